@@ -69,6 +69,11 @@ go test -v ./...
 
 cd v3
 
+go build -v ./...
+go test -v ./...
+
+cd integration/awskms/otelmetrics
+
 go build -v ./...
 go test -v ./...
 EOF
@@ -41,4 +41,9 @@ echo "Using go binary from $(which go): $(go version)"
 
   go build -v ./...
   go test -v ./...
+
+  cd integration/awskms/otelmetrics
+
+  go build -v ./...
+  go test -v ./...
 )
@@ -0,0 +1,150 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Outcomes reported in [AuditEvent].
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// AuditEvent describes a single cryptographic operation performed with AWS
+// KMS. It never contains plaintext, associated data or encryption context
+// values.
+type AuditEvent struct {
+	// Time is when the operation was started.
+	Time time.Time `json:"time"`
+	// Operation is the name of the AWS KMS API, for example "Encrypt".
+	Operation string `json:"operation"`
+	// KeyARN is the ARN of the key that performed the operation as reported
+	// by AWS KMS. If the operation failed, it is the key ID sent in the
+	// request.
+	KeyARN string `json:"keyArn,omitempty"`
+	// EncryptionContextKeys are the sorted keys of the encryption context
+	// sent in the request.
+	EncryptionContextKeys []string `json:"encryptionContextKeys,omitempty"`
+	// Principal is the value set with [ContextWithPrincipal], if any.
+	Principal string `json:"principal,omitempty"`
+	// Outcome is either [OutcomeSuccess] or [OutcomeFailure].
+	Outcome string `json:"outcome"`
+	// ErrorKind classifies the failure, see [CallRecord.ErrorKind].
+	ErrorKind string `json:"errorKind,omitempty"`
+	// RequestID is the AWS request ID, which can be used to find the
+	// corresponding CloudTrail entry.
+	RequestID string `json:"requestId,omitempty"`
+}
+
+// AuditSink receives an [AuditEvent] for every AWS KMS operation.
+//
+// Implementations must be safe for concurrent use.
+type AuditSink interface {
+	// Emit records event. If it returns an error, the operation which
+	// produced the event fails with that error, so that no cryptographic
+	// operation goes unaudited.
+	Emit(ctx context.Context, event AuditEvent) error
+}
+
+// WithAuditSink emits an [AuditEvent] to sink for every AWS KMS call made by
+// the client.
+//
+// Use [ContextWithPrincipal] to attribute operations to a caller.
+func WithAuditSink(sink AuditSink) ClientOption {
+	return option(func(ctx context.Context, a *awsClient) error {
+		if sink == nil {
+			return errors.New("audit sink must not be nil")
+		}
+		if a.audit != nil {
+			return errors.New("WithAuditSink option cannot be used, audit sink already set")
+		}
+		a.audit = sink
+		return nil
+	})
+}
+
+type principalKey struct{}
+
+// ContextWithPrincipal returns a copy of ctx that carries principal, an
+// application-defined identifier of the tenant or user on whose behalf
+// operations are performed. It is reported in [AuditEvent.Principal].
+func ContextWithPrincipal(ctx context.Context, principal string) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal set with [ContextWithPrincipal].
+func PrincipalFromContext(ctx context.Context) (string, bool) {
+	p, ok := ctx.Value(principalKey{}).(string)
+	return p, ok
+}
+
+// JSONLinesSink is an [AuditSink] that writes each event as a single line of
+// JSON.
+type JSONLinesSink struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+}
+
+var _ AuditSink = (*JSONLinesSink)(nil)
+
+// NewJSONLinesSink returns a sink writing to w. Writes are serialized, so w
+// does not need to be safe for concurrent use.
+func NewJSONLinesSink(w io.Writer) *JSONLinesSink {
+	return &JSONLinesSink{w: w}
+}
+
+// OpenJSONLinesFile returns a sink appending to the file at path, which is
+// created with permissions 0600 if it does not exist. The caller must call
+// [JSONLinesSink.Close] when done.
+func OpenJSONLinesFile(path string) (*JSONLinesSink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONLinesSink{w: f, closer: f}, nil
+}
+
+// Emit implements [AuditSink].
+func (s *JSONLinesSink) Emit(ctx context.Context, event AuditEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(line)
+	return err
+}
+
+// Close closes the underlying file if the sink was created with
+// [OpenJSONLinesFile]. Otherwise it does nothing.
+func (s *JSONLinesSink) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closer.Close()
+}
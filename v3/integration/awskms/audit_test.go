@@ -0,0 +1,173 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/tink-crypto/tink-go-awskms/v3/integration/awskms/internal/fakeawskms"
+	"github.com/tink-crypto/tink-go/v2/tink"
+)
+
+type failingSink struct{}
+
+func (failingSink) Emit(ctx context.Context, event AuditEvent) error {
+	return errors.New("sink unavailable")
+}
+
+func readAuditEvents(t *testing.T, b []byte) []AuditEvent {
+	t.Helper()
+	var events []AuditEvent
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		var e AuditEvent
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			t.Fatalf("json.Unmarshal(%q) err = %v, want nil", s.Bytes(), err)
+		}
+		events = append(events, e)
+	}
+	return events
+}
+
+func TestWithAuditSink_emitsEvents(t *testing.T) {
+	keyARN := "arn:aws:kms:us-east-2:235739564943:key/3ee50705-5a82-4f5b-9753-05c4f473922f"
+	keyURI := "aws-kms://arn:aws:kms:us-east-2:235739564943:key/3ee50705-5a82-4f5b-9753-05c4f473922f"
+	fakekms, err := fakeawskms.New([]string{keyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	out := &bytes.Buffer{}
+	client, err := NewClientWithOptions(t.Context(), "aws-kms://", WithKMS(fakekms), WithAuditSink(NewJSONLinesSink(out)))
+	if err != nil {
+		t.Fatalf("NewClientWithOptions() failed: %v", err)
+	}
+	a, err := client.GetAEAD(keyURI)
+	if err != nil {
+		t.Fatalf("client.GetAEAD(keyURI) err = %v, want nil", err)
+	}
+	ctx := ContextWithPrincipal(t.Context(), "tenant-42")
+	plaintext := []byte("plaintext")
+	associatedData := []byte("tenant secret")
+	ciphertext, err := a.(tink.AEADWithContext).EncryptWithContext(ctx, plaintext, associatedData)
+	if err != nil {
+		t.Fatalf("a.EncryptWithContext(ctx, plaintext, associatedData) err = %v, want nil", err)
+	}
+	if _, err := a.Decrypt(ciphertext, []byte("invalidAssociatedData")); err == nil {
+		t.Fatal("a.Decrypt(ciphertext, invalidAssociatedData) err = nil, want error")
+	}
+
+	hexAD := hex.EncodeToString(associatedData)
+	if strings.Contains(out.String(), string(plaintext)) || strings.Contains(out.String(), hexAD) {
+		t.Errorf("audit log contains plaintext or associated data: %s", out.String())
+	}
+	events := readAuditEvents(t, out.Bytes())
+	if len(events) != 2 {
+		t.Fatalf("len(events) = %d, want 2", len(events))
+	}
+	enc, dec := events[0], events[1]
+	if enc.Operation != "Encrypt" || enc.Outcome != OutcomeSuccess || enc.KeyARN != keyARN || enc.Principal != "tenant-42" {
+		t.Errorf("events[0] = %+v, want successful Encrypt by tenant-42 with key %q", enc, keyARN)
+	}
+	if !slices.Equal(enc.EncryptionContextKeys, []string{"associatedData"}) {
+		t.Errorf("events[0].EncryptionContextKeys = %q, want [associatedData]", enc.EncryptionContextKeys)
+	}
+	if enc.Time.IsZero() {
+		t.Error("events[0].Time is zero")
+	}
+	if dec.Operation != "Decrypt" || dec.Outcome != OutcomeFailure || dec.ErrorKind == "" || dec.Principal != "" {
+		t.Errorf("events[1] = %+v, want failed Decrypt without principal", dec)
+	}
+}
+
+func TestWithAuditSink_failingSinkFailsOperation(t *testing.T) {
+	keyARN := "arn:aws:kms:us-east-2:235739564943:key/3ee50705-5a82-4f5b-9753-05c4f473922f"
+	keyURI := "aws-kms://arn:aws:kms:us-east-2:235739564943:key/3ee50705-5a82-4f5b-9753-05c4f473922f"
+	fakekms, err := fakeawskms.New([]string{keyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	client, err := NewClientWithOptions(t.Context(), "aws-kms://", WithKMS(fakekms), WithAuditSink(failingSink{}))
+	if err != nil {
+		t.Fatalf("NewClientWithOptions() failed: %v", err)
+	}
+	a, err := client.GetAEAD(keyURI)
+	if err != nil {
+		t.Fatalf("client.GetAEAD(keyURI) err = %v, want nil", err)
+	}
+	if _, err := a.Encrypt([]byte("plaintext"), nil); err == nil {
+		t.Error("a.Encrypt() err = nil, want error")
+	}
+}
+
+func TestWithAuditSink_repeatedFails(t *testing.T) {
+	sink := NewJSONLinesSink(&bytes.Buffer{})
+	if _, err := NewClientWithOptions(t.Context(), "aws-kms://", WithAuditSink(sink), WithAuditSink(sink)); err == nil {
+		t.Fatal("NewClientWithOptions(t.Context(), _, WithAuditSink(_), WithAuditSink(_)) err = nil, want error")
+	}
+}
+
+func TestOpenJSONLinesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	for _, op := range []string{"Encrypt", "Decrypt"} {
+		sink, err := OpenJSONLinesFile(path)
+		if err != nil {
+			t.Fatalf("OpenJSONLinesFile(%q) err = %v, want nil", path, err)
+		}
+		if err := sink.Emit(t.Context(), AuditEvent{Operation: op, Outcome: OutcomeSuccess}); err != nil {
+			t.Fatalf("sink.Emit() err = %v, want nil", err)
+		}
+		if err := sink.Close(); err != nil {
+			t.Fatalf("sink.Close() err = %v, want nil", err)
+		}
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("os.ReadFile(%q) err = %v, want nil", path, err)
+	}
+	events := readAuditEvents(t, b)
+	if len(events) != 2 || events[0].Operation != "Encrypt" || events[1].Operation != "Decrypt" {
+		t.Errorf("events = %+v, want Encrypt followed by Decrypt", events)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	var metadata middleware.Metadata
+	awsmiddleware.SetRequestIDMetadata(&metadata, "request-1")
+	if got := requestID(metadata, nil); got != "request-1" {
+		t.Errorf("requestID(metadata, nil) = %q, want %q", got, "request-1")
+	}
+
+	err := &awshttp.ResponseError{RequestID: "request-2"}
+	if got := requestID(middleware.Metadata{}, err); got != "request-2" {
+		t.Errorf("requestID(_, err) = %q, want %q", got, "request-2")
+	}
+
+	if got := requestID(middleware.Metadata{}, errors.New("other")); got != "" {
+		t.Errorf("requestID(_, errors.New(\"other\")) = %q, want \"\"", got)
+	}
+}
@@ -19,10 +19,11 @@ package awskms
 
 import (
 	"context"
-	"encoding/hex"
+	"log/slog"
 
 	"github.com/aws/aws-sdk-go-v2/service/kms"
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
 	"github.com/tink-crypto/tink-go/v2/tink"
 )
 
@@ -30,11 +31,41 @@ import (
 // cryptographic operations remotely via the AWS KMS service using a specific
 // key ID.
 type awsAEAD struct {
-	keyID                 string
-	kms                   KMSAPI
-	encryptionContextName EncryptionContextName
+	keyID            string
+	kms              KMSAPI
+	encoder          EncryptionContextEncoder
+	decryptFallbacks []EncryptionContextEncoder
+	logger           *slog.Logger
+	verifyKeyIDs     bool
+	pinnedKeyARN     string
+	grantTokens      []string
+	dryRun           bool
+	keyMetadata      func(ctx context.Context, keyID string) (*types.KeyMetadata, error)
+	// encryptionAlgorithm is sent with requests if set. maxPlaintextSize is
+	// the plaintext size limit of the algorithm, or 0 if unknown.
+	encryptionAlgorithm types.EncryptionAlgorithmSpec
+	maxPlaintextSize    int
+	envelopeFallback    bool
+	ciphertextMetadata  bool
 }
 
+// EncryptionContextAEAD is implemented by the AEAD primitives returned by
+// this package. It allows using an arbitrary AWS KMS encryption context
+// instead of associated data, so that key policies can refer to individual
+// entries, for example with a kms:EncryptionContext:tenant condition.
+//
+// The encryption context is sent verbatim to AWS KMS.
+type EncryptionContextAEAD interface {
+	// EncryptWithEncryptionContext encrypts plaintext, binding it to
+	// encryptionContext.
+	EncryptWithEncryptionContext(ctx context.Context, plaintext []byte, encryptionContext map[string]string) ([]byte, error)
+	// DecryptWithEncryptionContext decrypts ciphertext, which must have been
+	// encrypted with the same encryptionContext.
+	DecryptWithEncryptionContext(ctx context.Context, ciphertext []byte, encryptionContext map[string]string) ([]byte, error)
+}
+
+var _ EncryptionContextAEAD = (*awsAEAD)(nil)
+
 // NewAEADWithContext returns a new AEADWithContext instance. The opts are the same as those
 // passed to NewClientWithOptions.
 func NewAEADWithContext(ctx context.Context, keyID string, opts ...ClientOption) (tink.AEADWithContext, error) {
@@ -43,7 +74,23 @@ func NewAEADWithContext(ctx context.Context, keyID string, opts ...ClientOption)
 	if err != nil {
 		return nil, err
 	}
-	return newAWSAEAD(keyID, awsClient.kms, awsClient.encryptionContextName), nil
+	return awsClient.newAEAD(ctx, keyURI, keyID)
+}
+
+// newAEAD returns the AEAD of keyID, after validating the key if required.
+func (c *awsClient) newAEAD(ctx context.Context, keyURI, keyID string) (*awsAEAD, error) {
+	a := newAWSAEAD(keyID, c)
+	if !c.validateKeys && c.encryptionAlgorithm == "" {
+		return a, nil
+	}
+	m, err := c.validateKey(ctx, keyURI, keyID)
+	if err != nil {
+		return nil, err
+	}
+	if c.encryptionAlgorithm != "" {
+		a.maxPlaintextSize = maxPlaintextSize(c.encryptionAlgorithm, m.KeySpec)
+	}
+	return a, nil
 }
 
 // newAWSAEAD returns a new awsAEAD instance.
@@ -53,28 +100,107 @@ func NewAEADWithContext(ctx context.Context, keyID string, opts ...ClientOption)
 //	arn:<partition>:kms:<region>:[<path>]
 //
 // See http://docs.aws.amazon.com/general/latest/gr/aws-arns-and-namespaces.html.
-func newAWSAEAD(keyID string, kms KMSAPI, name EncryptionContextName) *awsAEAD {
+func newAWSAEAD(keyID string, c *awsClient) *awsAEAD {
 	return &awsAEAD{
-		keyID:                 keyID,
-		kms:                   kms,
-		encryptionContextName: name,
+		keyID:               keyID,
+		kms:                 c.kms,
+		encoder:             c.encoder,
+		decryptFallbacks:    c.decryptFallbacks,
+		logger:              c.logger,
+		verifyKeyIDs:        !c.skipKeyIDVerification,
+		pinnedKeyARN:        c.pinnedKeyARNs[keyID],
+		grantTokens:         c.grantTokens,
+		dryRun:              c.dryRun,
+		encryptionAlgorithm: c.encryptionAlgorithm,
+		keyMetadata:         c.keyMetadata,
+		maxPlaintextSize:    maxPlaintextSize(types.EncryptionAlgorithmSpecSymmetricDefault, ""),
+		envelopeFallback:    c.envelopeFallback,
+		ciphertextMetadata:  c.ciphertextMetadata,
+	}
+}
+
+// encryptionContext returns the encryption context corresponding to
+// associatedData.
+func (a *awsAEAD) encryptionContext(associatedData []byte) (map[string]string, error) {
+	return encodeAssociatedData(a.encoder, associatedData)
+}
+
+// encodeAssociatedData returns the encryption context for associatedData
+// produced by encoder. Empty associated data results in no encryption context.
+func encodeAssociatedData(encoder EncryptionContextEncoder, associatedData []byte) (map[string]string, error) {
+	if len(associatedData) == 0 {
+		return nil, nil
 	}
+	return encoder.EncryptionContext(associatedData)
 }
 
 // EncryptWithContext encrypts the plaintext with associatedData.
 func (a *awsAEAD) EncryptWithContext(ctx context.Context, plaintext, associatedData []byte) ([]byte, error) {
+	if a.ciphertextMetadata {
+		return a.encryptWithMetadata(ctx, plaintext, associatedData)
+	}
+	return a.encrypt(ctx, plaintext, associatedData)
+}
+
+// encrypt encrypts the plaintext with associatedData, without metadata
+// header.
+func (a *awsAEAD) encrypt(ctx context.Context, plaintext, associatedData []byte) ([]byte, error) {
+	if a.envelopeFallback && a.maxPlaintextSize > 0 && len(plaintext) > a.maxPlaintextSize {
+		return a.encryptEnvelope(ctx, plaintext, associatedData)
+	}
+	return a.encryptKMS(ctx, plaintext, associatedData)
+}
+
+// encryptKMS encrypts the plaintext with associatedData using AWS KMS.
+func (a *awsAEAD) encryptKMS(ctx context.Context, plaintext, associatedData []byte) ([]byte, error) {
+	encryptionContext, err := a.encryptionContext(associatedData)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := a.EncryptWithEncryptionContext(ctx, plaintext, encryptionContext)
+	if err != nil {
+		return nil, err
+	}
+	if d := operationDetails(ctx); d != nil {
+		d.Encoder = a.encoder
+	}
+	return ciphertext, nil
+}
+
+// EncryptWithEncryptionContext encrypts the plaintext with encryptionContext.
+func (a *awsAEAD) EncryptWithEncryptionContext(ctx context.Context, plaintext []byte, encryptionContext map[string]string) ([]byte, error) {
+	if err := a.checkEncryptionAlgorithm(plaintext, encryptionContext); err != nil {
+		return nil, err
+	}
 	req := &kms.EncryptInput{
-		KeyId:     aws.String(a.keyID),
-		Plaintext: plaintext,
+		KeyId:       aws.String(a.keyID),
+		Plaintext:   plaintext,
+		GrantTokens: grantTokens(ctx, a.grantTokens),
+	}
+	if a.encryptionAlgorithm != "" {
+		req.EncryptionAlgorithm = a.encryptionAlgorithm
 	}
-	if len(associatedData) > 0 {
-		ad := hex.EncodeToString(associatedData)
-		req.EncryptionContext = map[string]string{a.encryptionContextName.String(): ad}
+	if len(encryptionContext) > 0 {
+		req.EncryptionContext = encryptionContext
+	}
+	dryRun := isDryRun(ctx, a.dryRun)
+	if dryRun {
+		req.DryRun = aws.Bool(true)
 	}
 	resp, err := a.kms.Encrypt(ctx, req)
+	if err != nil {
+		if dryRun {
+			err = dryRunError(ctx, err, "Encrypt", a.keyID)
+		}
+		return nil, err
+	}
+	keyARN, err := a.verifyKeyID(ctx, resp.KeyId)
 	if err != nil {
 		return nil, err
 	}
+	if d := operationDetails(ctx); d != nil {
+		d.KeyARN = keyARN
+	}
 	return resp.CiphertextBlob, nil
 }
 
@@ -84,18 +210,103 @@ func (a *awsAEAD) Encrypt(plaintext, associatedData []byte) ([]byte, error) {
 
 // DecryptWithContext decrypts the ciphertext and verifies the associated data.
 func (a *awsAEAD) DecryptWithContext(ctx context.Context, ciphertext, associatedData []byte) ([]byte, error) {
+	if hasCiphertextMetadata(ciphertext) {
+		return a.decryptWithMetadata(ctx, ciphertext, associatedData)
+	}
+	return a.decrypt(ctx, ciphertext, associatedData)
+}
+
+// decrypt decrypts a ciphertext without metadata header and verifies the
+// associated data.
+func (a *awsAEAD) decrypt(ctx context.Context, ciphertext, associatedData []byte) ([]byte, error) {
+	if isEnvelope(ciphertext) {
+		return a.decryptEnvelope(ctx, ciphertext, associatedData)
+	}
+	return a.decryptKMS(ctx, ciphertext, associatedData)
+}
+
+// decryptKMS decrypts the ciphertext of AWS KMS and verifies the associated
+// data.
+func (a *awsAEAD) decryptKMS(ctx context.Context, ciphertext, associatedData []byte) ([]byte, error) {
+	encryptionContext, err := a.encryptionContext(associatedData)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := a.DecryptWithEncryptionContext(ctx, ciphertext, encryptionContext)
+	if err == nil {
+		if d := operationDetails(ctx); d != nil {
+			d.Encoder = a.encoder
+		}
+		return plaintext, nil
+	}
+	if a.decryptFallbacks == nil || len(associatedData) == 0 || !isInvalidCiphertext(err) {
+		return nil, err
+	}
+	fallbacks := a.decryptFallbacks
+	if len(fallbacks) == 0 {
+		fallbacks = defaultDecryptFallbacks(a.encoder)
+	}
+	for _, fallback := range fallbacks {
+		fallbackContext, fallbackErr := fallback.EncryptionContext(associatedData)
+		if fallbackErr != nil {
+			continue
+		}
+		if a.logger != nil {
+			a.logger.DebugContext(ctx, "awskms: retrying decryption with fallback encryption context encoder",
+				slog.String("key_id", a.keyID),
+				slog.String("encryption_context_encoder", fallback.String()))
+		}
+		plaintext, fallbackErr := a.DecryptWithEncryptionContext(ctx, ciphertext, fallbackContext)
+		if fallbackErr == nil {
+			if d := operationDetails(ctx); d != nil {
+				d.Encoder = fallback
+			}
+			return plaintext, nil
+		}
+		if !isInvalidCiphertext(fallbackErr) {
+			return nil, fallbackErr
+		}
+	}
+	// Report the error of the configured encoder, which is the most relevant.
+	return nil, err
+}
+
+// DecryptWithEncryptionContext decrypts the ciphertext and verifies the
+// encryption context.
+func (a *awsAEAD) DecryptWithEncryptionContext(ctx context.Context, ciphertext []byte, encryptionContext map[string]string) ([]byte, error) {
+	if err := a.checkEncryptionAlgorithm(nil, encryptionContext); err != nil {
+		return nil, err
+	}
 	req := &kms.DecryptInput{
 		KeyId:          aws.String(a.keyID),
 		CiphertextBlob: ciphertext,
+		GrantTokens:    grantTokens(ctx, a.grantTokens),
+	}
+	if a.encryptionAlgorithm != "" {
+		req.EncryptionAlgorithm = a.encryptionAlgorithm
 	}
-	if len(associatedData) > 0 {
-		ad := hex.EncodeToString(associatedData)
-		req.EncryptionContext = map[string]string{a.encryptionContextName.String(): ad}
+	if len(encryptionContext) > 0 {
+		req.EncryptionContext = encryptionContext
+	}
+	dryRun := isDryRun(ctx, a.dryRun)
+	if dryRun {
+		req.DryRun = aws.Bool(true)
 	}
 	resp, err := a.kms.Decrypt(ctx, req)
 	if err != nil {
+		if dryRun {
+			err = dryRunError(ctx, err, "Decrypt", a.keyID)
+		}
 		return nil, err
 	}
+	keyARN, err := a.verifyKeyID(ctx, resp.KeyId)
+	if err != nil {
+		clear(resp.Plaintext)
+		return nil, err
+	}
+	if d := operationDetails(ctx); d != nil {
+		d.KeyARN = keyARN
+	}
 	return resp.Plaintext, nil
 }
 
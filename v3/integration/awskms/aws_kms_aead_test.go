@@ -0,0 +1,134 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"bytes"
+	"maps"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/tink-crypto/tink-go-awskms/v3/integration/awskms/internal/fakeawskms"
+)
+
+func TestEncryptWithEncryptionContext(t *testing.T) {
+	keyARN := "arn:aws:kms:us-east-2:235739564943:key/3ee50705-5a82-4f5b-9753-05c4f473922f"
+	keyURI := "aws-kms://arn:aws:kms:us-east-2:235739564943:key/3ee50705-5a82-4f5b-9753-05c4f473922f"
+	fakekms, err := fakeawskms.New([]string{keyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	client, err := NewClientWithOptions(t.Context(), "aws-kms://", WithKMS(fakekms))
+	if err != nil {
+		t.Fatalf("NewClientWithOptions() failed: %v", err)
+	}
+	p, err := client.GetAEAD(keyURI)
+	if err != nil {
+		t.Fatalf("client.GetAEAD(keyURI) err = %v, want nil", err)
+	}
+	a, ok := p.(EncryptionContextAEAD)
+	if !ok {
+		t.Fatalf("client.GetAEAD(keyURI) returned %T, which does not implement EncryptionContextAEAD", p)
+	}
+
+	plaintext := []byte("plaintext")
+	encryptionContext := map[string]string{"tenant": "acme", "purpose": "backup"}
+	ciphertext, err := a.EncryptWithEncryptionContext(t.Context(), plaintext, encryptionContext)
+	if err != nil {
+		t.Fatalf("a.EncryptWithEncryptionContext() err = %v, want nil", err)
+	}
+	got, err := a.DecryptWithEncryptionContext(t.Context(), ciphertext, maps.Clone(encryptionContext))
+	if err != nil {
+		t.Fatalf("a.DecryptWithEncryptionContext() err = %v, want nil", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("a.DecryptWithEncryptionContext() = %q, want %q", got, plaintext)
+	}
+
+	// The encryption context is sent to AWS KMS verbatim.
+	decResponse, err := fakekms.Decrypt(t.Context(), &kms.DecryptInput{
+		KeyId:             aws.String(keyARN),
+		CiphertextBlob:    ciphertext,
+		EncryptionContext: encryptionContext,
+	})
+	if err != nil {
+		t.Fatalf("fakekms.Decrypt() err = %v, want nil", err)
+	}
+	if !bytes.Equal(decResponse.Plaintext, plaintext) {
+		t.Errorf("decResponse.Plaintext = %q, want %q", decResponse.Plaintext, plaintext)
+	}
+
+	if _, err := a.DecryptWithEncryptionContext(t.Context(), ciphertext, map[string]string{"tenant": "acme"}); err == nil {
+		t.Error("a.DecryptWithEncryptionContext() with a partial encryption context err = nil, want error")
+	}
+}
+
+func TestWithSerializedEncryptionContext(t *testing.T) {
+	keyARN := "arn:aws:kms:us-east-2:235739564943:key/3ee50705-5a82-4f5b-9753-05c4f473922f"
+	keyURI := "aws-kms://arn:aws:kms:us-east-2:235739564943:key/3ee50705-5a82-4f5b-9753-05c4f473922f"
+	fakekms, err := fakeawskms.New([]string{keyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	client, err := NewClientWithOptions(t.Context(), "aws-kms://", WithKMS(fakekms), WithSerializedEncryptionContext())
+	if err != nil {
+		t.Fatalf("NewClientWithOptions() failed: %v", err)
+	}
+	a, err := client.GetAEAD(keyURI)
+	if err != nil {
+		t.Fatalf("client.GetAEAD(keyURI) err = %v, want nil", err)
+	}
+
+	plaintext := []byte("plaintext")
+	encryptionContext := map[string]string{"tenant": "acme", "purpose": "backup"}
+	associatedData, err := MarshalEncryptionContext(encryptionContext)
+	if err != nil {
+		t.Fatalf("MarshalEncryptionContext() err = %v, want nil", err)
+	}
+	ciphertext, err := a.Encrypt(plaintext, associatedData)
+	if err != nil {
+		t.Fatalf("a.Encrypt(plaintext, associatedData) err = %v, want nil", err)
+	}
+
+	// Ciphertexts are interchangeable with EncryptWithEncryptionContext.
+	got, err := a.(EncryptionContextAEAD).DecryptWithEncryptionContext(t.Context(), ciphertext, encryptionContext)
+	if err != nil {
+		t.Fatalf("a.DecryptWithEncryptionContext() err = %v, want nil", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("a.DecryptWithEncryptionContext() = %q, want %q", got, plaintext)
+	}
+	got, err = a.Decrypt(ciphertext, associatedData)
+	if err != nil {
+		t.Fatalf("a.Decrypt(ciphertext, associatedData) err = %v, want nil", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("a.Decrypt() = %q, want %q", got, plaintext)
+	}
+
+	if _, err := a.Encrypt(plaintext, []byte("not an encryption context")); err == nil {
+		t.Error("a.Encrypt(plaintext, invalidAssociatedData) err = nil, want error")
+	}
+}
+
+func TestWithSerializedEncryptionContext_withEncryptionContextNameFails(t *testing.T) {
+	if _, err := NewClientWithOptions(t.Context(), "aws-kms://", WithSerializedEncryptionContext(), WithEncryptionContextName(AssociatedData)); err == nil {
+		t.Error("NewClientWithOptions(t.Context(), _, WithSerializedEncryptionContext(), WithEncryptionContextName(_)) err = nil, want error")
+	}
+	if _, err := NewClientWithOptions(t.Context(), "aws-kms://", WithEncryptionContextName(AssociatedData), WithSerializedEncryptionContext()); err == nil {
+		t.Error("NewClientWithOptions(t.Context(), _, WithEncryptionContextName(_), WithSerializedEncryptionContext()) err = nil, want error")
+	}
+}
@@ -15,19 +15,24 @@
 package awskms
 
 import (
+	"container/list"
 	"context"
+	"crypto/sha256"
 	"encoding/csv"
 	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/kms"
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
 	"github.com/tink-crypto/tink-go/v2/core/registry"
 	"github.com/tink-crypto/tink-go/v2/tink"
 )
@@ -43,6 +48,9 @@ var (
 )
 
 // KMSAPI is a subset of the methods in the interface of *kms.Client that we use in this package.
+//
+// Some features use further methods of *kms.Client, such as ReEncrypt and
+// DescribeKey, if the client implements them.
 type KMSAPI interface {
 	Encrypt(ctx context.Context, params *kms.EncryptInput, optFns ...func(*kms.Options)) (*kms.EncryptOutput, error)
 	Decrypt(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error)
@@ -51,9 +59,38 @@ type KMSAPI interface {
 // awsClient is a wrapper around an AWS SDK provided KMS client that can
 // instantiate Tink primitives.
 type awsClient struct {
-	keyURIPrefix          string
-	kms                   KMSAPI
-	encryptionContextName EncryptionContextName
+	keyURIPrefix     string
+	kms              KMSAPI
+	encoder          EncryptionContextEncoder
+	decryptFallbacks []EncryptionContextEncoder
+	metrics          Metrics
+	audit            AuditSink
+	logger           *slog.Logger
+	credentialSource string
+
+	// config is the configuration kms was created from, if it was created by
+	// this package. It is used to create clients for other regions.
+	config *aws.Config
+
+	regionalMu  sync.Mutex
+	regionalKMS map[string]KMSAPI
+
+	validateKeys          bool
+	skipKeyIDVerification bool
+	pinnedKeyARNs         map[string]string
+	grantTokens           []string
+	dryRun                bool
+	encryptionAlgorithm   types.EncryptionAlgorithmSpec
+	envelopeFallback      bool
+	ciphertextMetadata    bool
+	keyMetadataMu         sync.Mutex
+	keyMetadataCache      map[string]*types.KeyMetadata
+
+	deterministicAEADCacheSize    int
+	deterministicAEADCacheSizeSet bool
+	deterministicAEADMu           sync.Mutex
+	deterministicAEADCache        map[[sha256.Size]byte]*list.Element
+	deterministicAEADLRU          *list.List
 }
 
 // ClientOption is an interface for defining options that are passed to
@@ -85,6 +122,8 @@ func WithCredentialPath(credentialPath string) ClientOption {
 			return err
 		}
 		a.kms = kms.NewFromConfig(cfg)
+		a.config = &cfg
+		a.credentialSource = credentialSourcePath
 		return nil
 	})
 }
@@ -101,6 +140,7 @@ func WithKMS(kms KMSAPI) ClientOption {
 			return errors.New("WithKMS option cannot be used, KMS client already set")
 		}
 		a.kms = kms
+		a.credentialSource = credentialSourceKMS
 		return nil
 	})
 }
@@ -134,6 +174,16 @@ func (n EncryptionContextName) String() string {
 	return encryptionContextNames[n]
 }
 
+// EncryptionContext implements [EncryptionContextEncoder]. It returns an
+// encryption context with the single entry named n, whose value is the hex
+// encoded associatedData.
+func (n EncryptionContextName) EncryptionContext(associatedData []byte) (map[string]string, error) {
+	if !n.valid() {
+		return nil, fmt.Errorf("invalid EncryptionContextName: %v", n)
+	}
+	return HexEncoder(n.String()).EncryptionContext(associatedData)
+}
+
 // WithEncryptionContextName sets the name which maps to the base64 encoded
 // associated data within the EncryptionContext field of EncrypInput and
 // DecryptInput requests.
@@ -143,15 +193,55 @@ func (n EncryptionContextName) String() string {
 // option was present, "additionalData" was hardcoded.
 //
 // This option is provided to facilitate compatibility with older ciphertexts.
+// It is equivalent to [WithEncryptionContextEncoder] with name as the encoder.
 func WithEncryptionContextName(name EncryptionContextName) ClientOption {
 	return option(func(ctx context.Context, a *awsClient) error {
 		if !name.valid() {
 			return fmt.Errorf("invalid EncryptionContextName: %v", name)
 		}
-		if a.encryptionContextName != 0 {
+		if a.encoder != nil {
 			return errors.New("encryptionContextName already set")
 		}
-		a.encryptionContextName = name
+		a.encoder = name
+		return nil
+	})
+}
+
+// WithEncryptionContextEncoder sets the encoder which converts associated data
+// into the EncryptionContext field of EncryptInput and DecryptInput requests.
+//
+// The default is [AssociatedData]. Use this option to decrypt ciphertexts
+// produced by tools that use a different convention, for example
+// [Base64Encoder] with a custom name. Only one of WithEncryptionContextEncoder,
+// [WithEncryptionContextName] and [WithSerializedEncryptionContext] can be used.
+func WithEncryptionContextEncoder(encoder EncryptionContextEncoder) ClientOption {
+	return option(func(ctx context.Context, a *awsClient) error {
+		if encoder == nil {
+			return errors.New("encryption context encoder must not be nil")
+		}
+		if a.encoder != nil {
+			return errors.New("WithEncryptionContextEncoder option cannot be used, encoder already set")
+		}
+		a.encoder = encoder
+		return nil
+	})
+}
+
+// WithSerializedEncryptionContext makes AEAD primitives interpret associated
+// data as an encryption context serialized with [MarshalEncryptionContext],
+// which is sent verbatim to AWS KMS.
+//
+// This allows using a multi-entry encryption context through the [tink.AEAD]
+// interface. Associated data which is not exactly the output of
+// MarshalEncryptionContext, such as other JSON encodings of the same context,
+// is rejected. It is equivalent to [WithEncryptionContextEncoder] with
+// [SerializedEncoder].
+func WithSerializedEncryptionContext() ClientOption {
+	return option(func(ctx context.Context, a *awsClient) error {
+		if a.encoder != nil {
+			return errors.New("WithSerializedEncryptionContext option cannot be used, encoder already set")
+		}
+		a.encoder = SerializedEncoder()
 		return nil
 	})
 }
@@ -174,21 +264,33 @@ func newAWSClient(ctx context.Context, uriPrefix string, opts ...ClientOption) (
 
 	// Populate values not defined via options.
 	if a.kms == nil {
-		k, err := getKMS(ctx, uriPrefix)
+		cfg, err := getDefaultConfig(ctx, uriPrefix)
 		if err != nil {
 			return nil, err
 		}
-		a.kms = k
+		a.kms = kms.NewFromConfig(cfg)
+		a.config = &cfg
+		a.credentialSource = credentialSourceDefault
 	}
-	if a.encryptionContextName == 0 {
-		a.encryptionContextName = AssociatedData
+	if a.encoder == nil {
+		a.encoder = AssociatedData
 	}
+	// Empty decrypt fallbacks select the default fallbacks.
+	if len(a.decryptFallbacks) > 0 {
+		a.decryptFallbacks = withoutEncoder(a.decryptFallbacks, a.encoder)
+	}
+	a.kms = instrument(a.kms, a)
+	for region, k := range a.regionalKMS {
+		a.regionalKMS[region] = instrument(k, a)
+	}
+	logClient(ctx, a)
 
 	return a, nil
 }
 
 // NewClientWithOptions returns a [registry.KMSClient] which wraps an AWS KMS
-// client and will handle keys whose URIs start with uriPrefix.
+// client and will handle keys whose URIs start with uriPrefix. It also
+// implements [Client].
 //
 // By default, the client will use default credentials.
 //
@@ -198,6 +300,57 @@ func NewClientWithOptions(ctx context.Context, uriPrefix string, opts ...ClientO
 	return newAWSClient(ctx, uriPrefix, opts...)
 }
 
+// Client is implemented by the [registry.KMSClient] returned by
+// [NewClientWithOptions]. It provides AWS KMS operations which do not fit the
+// [registry.KMSClient] interface.
+//
+// Use a type assertion to obtain it:
+//
+//	c, err := awskms.NewClientWithOptions(ctx, uriPrefix)
+//	...
+//	client := c.(awskms.Client)
+//
+// Client is not meant to be implemented outside this package; methods may be
+// added to it in future versions.
+type Client interface {
+	registry.KMSClient
+
+	// ReEncrypt re-encrypts ciphertext, produced by an AEAD of this client with
+	// fromAssociatedData, under toKeyURI with toAssociatedData. The result can
+	// be decrypted by the AEAD for toKeyURI.
+	//
+	// Within a region, this uses the AWS KMS ReEncrypt operation, so the
+	// plaintext never leaves AWS KMS. If toKeyURI is in a different region than
+	// the source key, AWS KMS cannot re-encrypt directly: the ciphertext is
+	// decrypted in the source region and encrypted in the destination region,
+	// and the plaintext is briefly held in memory. See [WithRegionalKMS].
+	//
+	// Ciphertexts with the metadata header of [WithCiphertextMetadata] are
+	// accepted, and the result has a header if this client uses that option.
+	// Like in decryption, the encoder named by the header is ignored.
+	//
+	// Envelope ciphertexts of [WithEnvelopeFallback] are only accepted if this
+	// client uses that option, and then only their encrypted data key is
+	// re-encrypted, so fromAssociatedData and toAssociatedData must be equal.
+	//
+	// The key IDs reported by AWS KMS are verified against toKeyURI and, if
+	// set, [WithSourceKeyURI], see [ErrKeyIDMismatch].
+	ReEncrypt(ctx context.Context, ciphertext, fromAssociatedData []byte, toKeyURI string, toAssociatedData []byte, opts ...ReEncryptOption) ([]byte, error)
+
+	// CheckKey checks that keyURI can be used by the AEAD primitives of this
+	// client, for example at startup. It calls DescribeKey and encrypts and
+	// decrypts a random canary.
+	//
+	// The returned report describes the key and which operations succeeded. The
+	// error is non-nil if any check failed, including if the key is not an
+	// enabled symmetric encryption key, or does not support the algorithm of
+	// [WithEncryptionAlgorithm] if it is used. DescribeKey requires the
+	// kms:DescribeKey permission; if only that check fails, the key is usable.
+	CheckKey(ctx context.Context, keyURI string) (*KeyReport, error)
+}
+
+var _ Client = (*awsClient)(nil)
+
 // NewClient returns a KMSClient backed by AWS KMS using default credentials to
 // handle keys whose URIs start with uriPrefix.
 //
@@ -278,27 +431,25 @@ func (c *awsClient) Supported(keyURI string) bool {
 //	aws-kms://arn:<partition>:kms:<region>:<path>
 //
 // See https://docs.aws.amazon.com/IAM/latest/UserGuide/reference-arns.html
+//
+// If the client was created with [WithKeyValidation], the key is checked to be
+// a symmetric encryption key.
 func (c *awsClient) GetAEAD(keyURI string) (tink.AEAD, error) {
 	if !c.Supported(keyURI) {
 		return nil, fmt.Errorf("keyURI must start with prefix %s, but got %s", c.keyURIPrefix, keyURI)
 	}
 
 	keyID := strings.TrimPrefix(keyURI, awsPrefix)
-	return newAWSAEAD(keyID, c.kms, c.encryptionContextName), nil
+	return c.newAEAD(context.Background(), keyURI, keyID)
 }
 
-func getKMS(ctx context.Context, uriPrefix string) (*kms.Client, error) {
+func getDefaultConfig(ctx context.Context, uriPrefix string) (aws.Config, error) {
 	r, err := getRegion(uriPrefix)
 	if err != nil {
-		return nil, err
-	}
-
-	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(r))
-	if err != nil {
-		return nil, err
+		return aws.Config{}, err
 	}
 
-	return kms.NewFromConfig(cfg), nil
+	return config.LoadDefaultConfig(ctx, config.WithRegion(r))
 }
 
 func getConfigFromCredentialPath(ctx context.Context, uriPrefix string, credentialPath string) (aws.Config, error) {
@@ -25,6 +25,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/kms"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/tink-crypto/tink-go/v2/core/registry"
+	"github.com/tink-crypto/tink-go/v2/tink"
 	"github.com/tink-crypto/tink-go-awskms/v3/integration/awskms/internal/fakeawskms"
 )
 
@@ -41,6 +42,35 @@ func testFilePath(t *testing.T, filename string) string {
 	return filepath.Join("../../..", filename)
 }
 
+const (
+	sourceKeyARN      = "arn:aws:kms:us-east-2:235739564943:key/3ee50705-5a82-4f5b-9753-05c4f473922f"
+	sourceKeyURI      = "aws-kms://" + sourceKeyARN
+	destinationKeyARN = "arn:aws:kms:us-east-2:235739564943:key/b3ca2efd-a8fb-47f2-b541-7e20f8c5cd11"
+	destinationKeyURI = "aws-kms://" + destinationKeyARN
+	westKeyARN        = "arn:aws:kms:eu-west-1:235739564943:key/e2d8a2be-c1ea-4a4f-a7ca-0e09e8b0b77d"
+	westKeyURI        = "aws-kms://" + westKeyARN
+)
+
+// newTestClient returns a client for all AWS KMS key URIs configured with opts,
+// which usually include WithKMS with a fake.
+func newTestClient(t *testing.T, opts ...ClientOption) Client {
+	t.Helper()
+	c, err := NewClientWithOptions(t.Context(), "aws-kms://", opts...)
+	if err != nil {
+		t.Fatalf("NewClientWithOptions() failed: %v", err)
+	}
+	return c.(Client)
+}
+
+func getAEAD(t *testing.T, client Client, keyURI string) tink.AEADWithContext {
+	t.Helper()
+	a, err := client.GetAEAD(keyURI)
+	if err != nil {
+		t.Fatalf("client.GetAEAD() err = %v, want nil", err)
+	}
+	return a.(tink.AEADWithContext)
+}
+
 func TestNewClientWithOptions_URIPrefix(t *testing.T) {
 	// Necessary for testing deprecated factory functions.
 	credFile := testFilePath(t, "testdata/aws/credentials.csv")
@@ -0,0 +1,148 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+// describeKeyAPI is implemented by KMS clients supporting DescribeKey, such as
+// *kms.Client.
+type describeKeyAPI interface {
+	DescribeKey(ctx context.Context, params *kms.DescribeKeyInput, optFns ...func(*kms.Options)) (*kms.DescribeKeyOutput, error)
+}
+
+var errDescribeKeyUnsupported = errors.New("KMS client does not support DescribeKey")
+
+// checkKeyAssociatedData is the associated data of the canary encrypted by
+// CheckKey, so that AWS KMS logs identify these requests.
+var checkKeyAssociatedData = []byte("tink-awskms CheckKey")
+
+// KeyReport is the result of [Client.CheckKey].
+type KeyReport struct {
+	// KeyURI is the checked key URI.
+	KeyURI string
+
+	// The following fields are set from the DescribeKey response, and are
+	// empty if DescribeKey failed.
+	KeyARN      string
+	KeyState    types.KeyState
+	KeySpec     types.KeySpec
+	KeyUsage    types.KeyUsageType
+	Origin      types.OriginType
+	MultiRegion bool
+
+	// CanDescribe, CanEncrypt and CanDecrypt report which AWS KMS operations
+	// succeeded. Decryption is only attempted if encryption succeeded.
+	CanDescribe bool
+	CanEncrypt  bool
+	CanDecrypt  bool
+
+	// Errors holds the errors of the failed operations.
+	Errors []error
+}
+
+// CheckKey implements [Client.CheckKey].
+func (c *awsClient) CheckKey(ctx context.Context, keyURI string) (*KeyReport, error) {
+	if !c.Supported(keyURI) {
+		return nil, fmt.Errorf("keyURI must start with prefix %s, but got %s", c.keyURIPrefix, keyURI)
+	}
+	keyID := strings.TrimPrefix(keyURI, awsPrefix)
+	report := &KeyReport{KeyURI: keyURI}
+
+	if err := c.describeKey(ctx, keyID, report); err != nil {
+		report.Errors = append(report.Errors, fmt.Errorf("DescribeKey failed: %w", err))
+	}
+
+	canary := make([]byte, 32)
+	if _, err := rand.Read(canary); err != nil {
+		return nil, err
+	}
+	a := newAWSAEAD(keyID, c)
+	associatedData := checkKeyAssociatedData
+	if !supportsEncryptionContext(c.encryptionAlgorithm) {
+		associatedData = nil
+	}
+	ciphertext, err := a.EncryptWithContext(ctx, canary, associatedData)
+	if err != nil {
+		report.Errors = append(report.Errors, fmt.Errorf("Encrypt failed: %w", err))
+		return report, errors.Join(report.Errors...)
+	}
+	report.CanEncrypt = true
+	plaintext, err := a.DecryptWithContext(ctx, ciphertext, associatedData)
+	switch {
+	case err != nil:
+		report.Errors = append(report.Errors, fmt.Errorf("Decrypt failed: %w", err))
+	case !bytes.Equal(plaintext, canary):
+		report.Errors = append(report.Errors, errors.New("Decrypt failed: decrypted canary does not match"))
+	default:
+		report.CanDecrypt = true
+	}
+	return report, errors.Join(report.Errors...)
+}
+
+// describeKey fills in the key metadata of report. Keys which are not enabled
+// encryption keys are reported as errors, as are keys whose spec is not
+// SYMMETRIC_DEFAULT or, if the client uses [WithEncryptionAlgorithm], which
+// do not support its algorithm.
+func (c *awsClient) describeKey(ctx context.Context, keyID string, report *KeyReport) error {
+	k, ok := c.kms.(describeKeyAPI)
+	if !ok {
+		return errDescribeKeyUnsupported
+	}
+	resp, err := k.DescribeKey(ctx, &kms.DescribeKeyInput{
+		KeyId:       aws.String(keyID),
+		GrantTokens: grantTokens(ctx, c.grantTokens),
+	})
+	if err != nil {
+		return err
+	}
+	report.CanDescribe = true
+	m := resp.KeyMetadata
+	if m == nil {
+		return errors.New("response contains no key metadata")
+	}
+	report.KeyARN = aws.ToString(m.Arn)
+	report.KeyState = m.KeyState
+	report.KeySpec = m.KeySpec
+	report.KeyUsage = m.KeyUsage
+	report.Origin = m.Origin
+	report.MultiRegion = aws.ToBool(m.MultiRegion)
+	if m.KeyState != types.KeyStateEnabled {
+		report.Errors = append(report.Errors, fmt.Errorf("key state is %s, want %s", m.KeyState, types.KeyStateEnabled))
+	}
+	if m.KeyUsage != types.KeyUsageTypeEncryptDecrypt {
+		report.Errors = append(report.Errors, fmt.Errorf("key usage is %s, want %s", m.KeyUsage, types.KeyUsageTypeEncryptDecrypt))
+	}
+	switch {
+	case c.encryptionAlgorithm != "":
+		if !slices.Contains(m.EncryptionAlgorithms, c.encryptionAlgorithm) {
+			report.Errors = append(report.Errors, fmt.Errorf("key spec %s does not support encryption algorithm %s", m.KeySpec, c.encryptionAlgorithm))
+		}
+	case m.KeySpec != types.KeySpecSymmetricDefault:
+		report.Errors = append(report.Errors, fmt.Errorf("key spec is %s, want %s", m.KeySpec, types.KeySpecSymmetricDefault))
+	}
+	return nil
+}
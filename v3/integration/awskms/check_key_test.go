@@ -0,0 +1,186 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/tink-crypto/tink-go-awskms/v3/integration/awskms/internal/fakeawskms"
+)
+
+func TestCheckKey(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	metrics := &fakeMetrics{}
+	client := newTestClient(t, WithKMS(fakekms), WithMetrics(metrics))
+	report, err := client.CheckKey(t.Context(), sourceKeyURI)
+	if err != nil {
+		t.Fatalf("client.CheckKey() err = %v, want nil", err)
+	}
+	want := KeyReport{
+		KeyURI:      sourceKeyURI,
+		KeyARN:      sourceKeyARN,
+		KeyState:    types.KeyStateEnabled,
+		KeySpec:     types.KeySpecSymmetricDefault,
+		KeyUsage:    types.KeyUsageTypeEncryptDecrypt,
+		Origin:      types.OriginTypeAwsKms,
+		CanDescribe: true,
+		CanEncrypt:  true,
+		CanDecrypt:  true,
+	}
+	if !reflect.DeepEqual(*report, want) {
+		t.Errorf("client.CheckKey() = %+v, want %+v", report, want)
+	}
+	var operations []string
+	for _, call := range metrics.calls {
+		operations = append(operations, call.Operation)
+	}
+	if got, want := strings.Join(operations, ","), "DescribeKey,Encrypt,Decrypt"; got != want {
+		t.Errorf("operations = %q, want %q", got, want)
+	}
+}
+
+// disabledKeyKMS reports all keys as disabled.
+type disabledKeyKMS struct {
+	*fakeawskms.FakeAWSKMS
+}
+
+func (k disabledKeyKMS) DescribeKey(ctx context.Context, params *kms.DescribeKeyInput, optFns ...func(*kms.Options)) (*kms.DescribeKeyOutput, error) {
+	resp, err := k.FakeAWSKMS.DescribeKey(ctx, params, optFns...)
+	if err != nil {
+		return nil, err
+	}
+	resp.KeyMetadata.Enabled = false
+	resp.KeyMetadata.KeyState = types.KeyStateDisabled
+	return resp, nil
+}
+
+func TestCheckKey_keyStates(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	client := newTestClient(t, WithKMS(fakekms))
+	ciphertext := mustEncrypt(t, client, sourceKeyURI, []byte("plaintext"), nil)
+
+	// Ciphertexts of previous key material can still be decrypted.
+	if _, err := fakekms.RotateKeyOnDemand(t.Context(), &kms.RotateKeyOnDemandInput{KeyId: aws.String(sourceKeyARN)}); err != nil {
+		t.Fatalf("fakekms.RotateKeyOnDemand() failed: %v", err)
+	}
+	if report, err := client.CheckKey(t.Context(), sourceKeyURI); err != nil {
+		t.Errorf("client.CheckKey() after rotation err = %v, want nil; report = %+v", err, report)
+	}
+	mustDecrypt(t, client, sourceKeyURI, ciphertext, nil)
+
+	for _, state := range []types.KeyState{types.KeyStateDisabled, types.KeyStatePendingDeletion, types.KeyStateUnavailable} {
+		if err := fakekms.SetKeyState(sourceKeyARN, state); err != nil {
+			t.Fatalf("fakekms.SetKeyState() failed: %v", err)
+		}
+		report, err := client.CheckKey(t.Context(), sourceKeyURI)
+		if err == nil {
+			t.Errorf("client.CheckKey() with state %s err = nil, want error", state)
+		}
+		if report.KeyState != state || !report.CanDescribe || report.CanEncrypt || report.CanDecrypt {
+			t.Errorf("client.CheckKey() = %+v, want key in state %s which cannot be used", report, state)
+		}
+	}
+}
+
+func TestCheckKey_fails(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+
+	t.Run("unknown key", func(t *testing.T) {
+		client := newTestClient(t, WithKMS(fakekms))
+		report, err := client.CheckKey(t.Context(), destinationKeyURI)
+		if err == nil {
+			t.Fatal("client.CheckKey() err = nil, want error")
+		}
+		if report.CanDescribe || report.CanEncrypt || report.CanDecrypt || len(report.Errors) != 2 {
+			t.Errorf("client.CheckKey() = %+v, want no successful operations and 2 errors", report)
+		}
+	})
+
+	t.Run("disabled key", func(t *testing.T) {
+		client := newTestClient(t, WithKMS(disabledKeyKMS{fakekms}))
+		report, err := client.CheckKey(t.Context(), sourceKeyURI)
+		if err == nil || !strings.Contains(err.Error(), "Disabled") {
+			t.Errorf("client.CheckKey() err = %v, want error about the key state", err)
+		}
+		if report.KeyState != types.KeyStateDisabled || !report.CanDescribe {
+			t.Errorf("client.CheckKey() = %+v, want disabled key", report)
+		}
+	})
+
+	t.Run("asymmetric key", func(t *testing.T) {
+		fakekms, err := fakeawskms.New(nil)
+		if err != nil {
+			t.Fatalf("fakeawskms.New() failed: %v", err)
+		}
+		if err := fakekms.AddKey(destinationKeyARN, types.KeySpecRsa2048, types.KeyUsageTypeEncryptDecrypt); err != nil {
+			t.Fatalf("fakekms.AddKey() failed: %v", err)
+		}
+		client := newTestClient(t, WithKMS(fakekms))
+		report, err := client.CheckKey(t.Context(), destinationKeyURI)
+		if err == nil || !strings.Contains(err.Error(), "key spec is RSA_2048") {
+			t.Errorf("client.CheckKey() err = %v, want error about the key spec", err)
+		}
+		if report.KeySpec != types.KeySpecRsa2048 || !report.CanDescribe || report.CanEncrypt {
+			t.Errorf("client.CheckKey() = %+v, want RSA key which cannot encrypt", report)
+		}
+
+		// The key can be used with an RSA encryption algorithm.
+		client = newTestClient(t, WithKMS(fakekms), WithEncryptionAlgorithm(types.EncryptionAlgorithmSpecRsaesOaepSha256))
+		if report, err := client.CheckKey(t.Context(), destinationKeyURI); err != nil || !report.CanDecrypt {
+			t.Errorf("client.CheckKey() with RSAES_OAEP_SHA_256 = %+v, %v, want usable key", report, err)
+		}
+		client = newTestClient(t, WithKMS(fakekms), WithEncryptionAlgorithm(types.EncryptionAlgorithmSpecSm2pke))
+		if _, err := client.CheckKey(t.Context(), destinationKeyURI); err == nil || !strings.Contains(err.Error(), "does not support encryption algorithm SM2PKE") {
+			t.Errorf("client.CheckKey() with SM2PKE err = %v, want error about the encryption algorithm", err)
+		}
+	})
+
+	t.Run("DescribeKey unsupported", func(t *testing.T) {
+		client := newTestClient(t, WithKMS(kmsAPIOnly{fakekms}), WithMetrics(&fakeMetrics{}))
+		report, err := client.CheckKey(t.Context(), sourceKeyURI)
+		if !errors.Is(err, errDescribeKeyUnsupported) {
+			t.Errorf("client.CheckKey() err = %v, want %v", err, errDescribeKeyUnsupported)
+		}
+		if report.CanDescribe || !report.CanEncrypt || !report.CanDecrypt {
+			t.Errorf("client.CheckKey() = %+v, want only encryption and decryption to succeed", report)
+		}
+	})
+
+	t.Run("unsupported key URI", func(t *testing.T) {
+		client, err := NewClientWithOptions(t.Context(), "aws-kms://arn:aws:kms:eu-west-1:", WithKMS(fakekms))
+		if err != nil {
+			t.Fatalf("NewClientWithOptions() failed: %v", err)
+		}
+		if _, err := client.(Client).CheckKey(t.Context(), sourceKeyURI); err == nil {
+			t.Error("client.CheckKey() err = nil, want error")
+		}
+	})
+}
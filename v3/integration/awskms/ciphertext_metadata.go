@@ -0,0 +1,187 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// metadataHeader starts the ciphertexts of [WithCiphertextMetadata]. AWS KMS
+// ciphertexts of symmetric keys start with a version byte of 1, so they
+// cannot be confused with ciphertexts with metadata.
+var metadataHeader = []byte{'T', 'K', 'C', 'M', 1}
+
+// WithCiphertextMetadata makes AEAD primitives prepend a metadata header to
+// their ciphertexts, recording the ARN of the key which encrypted them, as
+// reported by AWS KMS, and the [EncryptionContextEncoder] used. This allows
+// telling which key a stored ciphertext depends on, for example to check that
+// all ciphertexts have been re-encrypted after migrating to a new key, see
+// [InspectCiphertext]. The header has the format
+//
+//	"TKCM" || 0x01 || len(key ARN) (2 bytes, big endian) || key ARN || len(encoder name) (2 bytes, big endian) || encoder name || ciphertext
+//
+// The header is not encrypted, and adds about 100 bytes to each ciphertext.
+// AWS KMS does not reveal which version of the key material encrypted a
+// ciphertext, so automatic key rotation is not reflected in the header.
+//
+// Decryption recognizes ciphertexts with and without the header, whether this
+// option is used or not. Since the header is not authenticated, it is only
+// informative: ciphertexts are always decrypted with the encoder of the
+// client and its decrypt fallbacks, see [WithDecryptFallback], whatever
+// encoder the header names. Ciphertexts with the header fail with
+// [ErrKeyIDMismatch] if they were decrypted by another key than the recorded
+// one.
+func WithCiphertextMetadata() ClientOption {
+	return option(func(ctx context.Context, a *awsClient) error {
+		if a.ciphertextMetadata {
+			return errors.New("WithCiphertextMetadata option cannot be used, ciphertext metadata already enabled")
+		}
+		a.ciphertextMetadata = true
+		return nil
+	})
+}
+
+// CiphertextInfo describes a ciphertext of the AEAD primitives of this
+// package, see [InspectCiphertext].
+type CiphertextInfo struct {
+	// HasMetadata reports whether the ciphertext has the metadata header of
+	// [WithCiphertextMetadata]. If not, KeyARN and EncryptionContextName are
+	// empty.
+	HasMetadata bool
+	// KeyARN is the ARN of the key which encrypted the ciphertext.
+	KeyARN string
+	// EncryptionContextName is the String of the [EncryptionContextEncoder]
+	// used to encrypt the ciphertext, for example "associatedData".
+	EncryptionContextName string
+	// Envelope reports whether the plaintext was envelope encrypted with a
+	// data key, see [WithEnvelopeFallback].
+	Envelope bool
+}
+
+// InspectCiphertext returns what is known about a ciphertext of the AEAD
+// primitives of this package without decrypting it.
+//
+// The key ARN is only known for ciphertexts with the metadata header of
+// [WithCiphertextMetadata]. AWS KMS ciphertext blobs refer to their key in an
+// undocumented, opaque form, so for raw blobs the key ARN is only reported by
+// decryption, in [OperationDetails.KeyARN].
+func InspectCiphertext(ciphertext []byte) (*CiphertextInfo, error) {
+	if !hasCiphertextMetadata(ciphertext) {
+		return &CiphertextInfo{Envelope: isEnvelope(ciphertext)}, nil
+	}
+	info, inner, err := parseCiphertextMetadata(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	info.Envelope = isEnvelope(inner)
+	return info, nil
+}
+
+// hasCiphertextMetadata reports whether ciphertext starts with the metadata
+// header.
+func hasCiphertextMetadata(ciphertext []byte) bool {
+	return bytes.HasPrefix(ciphertext, metadataHeader)
+}
+
+// addCiphertextMetadata returns ciphertext with a metadata header.
+func addCiphertextMetadata(keyARN, encoderName string, ciphertext []byte) ([]byte, error) {
+	if len(keyARN) > math.MaxUint16 || len(encoderName) > math.MaxUint16 {
+		return nil, errors.New("ciphertext metadata too large")
+	}
+	out := make([]byte, 0, len(metadataHeader)+4+len(keyARN)+len(encoderName)+len(ciphertext))
+	out = append(out, metadataHeader...)
+	out = binary.BigEndian.AppendUint16(out, uint16(len(keyARN)))
+	out = append(out, keyARN...)
+	out = binary.BigEndian.AppendUint16(out, uint16(len(encoderName)))
+	out = append(out, encoderName...)
+	return append(out, ciphertext...), nil
+}
+
+// parseCiphertextMetadata returns the metadata of a ciphertext with a
+// metadata header and the ciphertext following it.
+func parseCiphertextMetadata(ciphertext []byte) (*CiphertextInfo, []byte, error) {
+	rest := ciphertext[len(metadataHeader):]
+	readField := func() (string, error) {
+		if len(rest) < 2 {
+			return "", errors.New("invalid ciphertext metadata: truncated header")
+		}
+		size := int(binary.BigEndian.Uint16(rest))
+		rest = rest[2:]
+		if size > len(rest) {
+			return "", errors.New("invalid ciphertext metadata: truncated header")
+		}
+		field := string(rest[:size])
+		rest = rest[size:]
+		return field, nil
+	}
+	keyARN, err := readField()
+	if err != nil {
+		return nil, nil, err
+	}
+	encoderName, err := readField()
+	if err != nil {
+		return nil, nil, err
+	}
+	if encoderName == "" {
+		return nil, nil, errors.New("invalid ciphertext metadata: missing encryption context name")
+	}
+	return &CiphertextInfo{
+		HasMetadata:           true,
+		KeyARN:                keyARN,
+		EncryptionContextName: encoderName,
+	}, rest, nil
+}
+
+// encryptWithMetadata encrypts plaintext and prepends the metadata header.
+func (a *awsAEAD) encryptWithMetadata(ctx context.Context, plaintext, associatedData []byte) ([]byte, error) {
+	d := operationDetails(ctx)
+	if d == nil {
+		d = &OperationDetails{}
+		ctx = ContextWithOperationDetails(ctx, d)
+	}
+	ciphertext, err := a.encrypt(ctx, plaintext, associatedData)
+	if err != nil {
+		return nil, err
+	}
+	return addCiphertextMetadata(d.KeyARN, a.encoder.String(), ciphertext)
+}
+
+// decryptWithMetadata decrypts a ciphertext with a metadata header. The
+// recorded encoder is ignored, as the header is not authenticated.
+func (a *awsAEAD) decryptWithMetadata(ctx context.Context, ciphertext, associatedData []byte) ([]byte, error) {
+	info, inner, err := parseCiphertextMetadata(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	d := operationDetails(ctx)
+	if d == nil {
+		d = &OperationDetails{}
+		ctx = ContextWithOperationDetails(ctx, d)
+	}
+	plaintext, err := a.decrypt(ctx, inner, associatedData)
+	if err != nil {
+		return nil, err
+	}
+	if info.KeyARN != "" && d.KeyARN != "" && d.KeyARN != info.KeyARN {
+		clear(plaintext)
+		return nil, fmt.Errorf("%w: ciphertext metadata records %s, decrypted by %s", ErrKeyIDMismatch, info.KeyARN, d.KeyARN)
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,258 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/tink-crypto/tink-go-awskms/v3/integration/awskms/internal/fakeawskms"
+	"github.com/tink-crypto/tink-go/v2/tink"
+)
+
+func TestWithCiphertextMetadata(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN, destinationKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	if err := fakekms.SetAlias(aliasARN, sourceKeyARN); err != nil {
+		t.Fatalf("fakekms.SetAlias() failed: %v", err)
+	}
+	plaintext := []byte("plaintext")
+	associatedData := []byte("associatedData")
+
+	for _, test := range []struct {
+		name         string
+		keyURI       string
+		opts         []ClientOption
+		size         int
+		wantEncoder  string
+		wantEnvelope bool
+	}{
+		{"key ARN", sourceKeyURI, nil, len(plaintext), "associatedData", false},
+		{"alias", "aws-kms://" + aliasARN, nil, len(plaintext), "associatedData", false},
+		{"encoder", sourceKeyURI, []ClientOption{WithEncryptionContextEncoder(HexEncoder("ad"))}, len(plaintext), "hex(ad)", false},
+		{"envelope", sourceKeyURI, []ClientOption{WithEnvelopeFallback()}, 5000, "associatedData", true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			opts := append([]ClientOption{WithKMS(fakekms), WithCiphertextMetadata()}, test.opts...)
+			a := getAEAD(t, newTestClient(t, opts...), test.keyURI)
+			plaintext := bytes.Repeat([]byte("a"), test.size)
+			ciphertext, err := a.EncryptWithContext(t.Context(), plaintext, associatedData)
+			if err != nil {
+				t.Fatalf("a.EncryptWithContext() err = %v, want nil", err)
+			}
+			if !bytes.HasPrefix(ciphertext, metadataHeader) {
+				t.Errorf("ciphertext = %x..., want prefix %x", ciphertext[:len(metadataHeader)], metadataHeader)
+			}
+			info, err := InspectCiphertext(ciphertext)
+			if err != nil {
+				t.Fatalf("InspectCiphertext() err = %v, want nil", err)
+			}
+			want := CiphertextInfo{
+				HasMetadata:           true,
+				KeyARN:                sourceKeyARN,
+				EncryptionContextName: test.wantEncoder,
+				Envelope:              test.wantEnvelope,
+			}
+			if *info != want {
+				t.Errorf("InspectCiphertext() = %+v, want %+v", *info, want)
+			}
+
+			// A client with the same encoder decrypts the ciphertext without
+			// the option.
+			withoutMetadata := getAEAD(t, newTestClient(t, append([]ClientOption{WithKMS(fakekms)}, test.opts...)...), sourceKeyURI)
+			for _, d := range []tink.AEADWithContext{a, withoutMetadata} {
+				details := &OperationDetails{}
+				got, err := d.DecryptWithContext(ContextWithOperationDetails(t.Context(), details), ciphertext, associatedData)
+				if err != nil {
+					t.Fatalf("DecryptWithContext() err = %v, want nil", err)
+				}
+				if !bytes.Equal(got, plaintext) {
+					t.Errorf("DecryptWithContext() = %d bytes, want %d bytes", len(got), len(plaintext))
+				}
+				if details.Encoder.String() != test.wantEncoder {
+					t.Errorf("details.Encoder = %s, want %s", details.Encoder, test.wantEncoder)
+				}
+				if _, err := d.DecryptWithContext(t.Context(), ciphertext, []byte("other")); err == nil {
+					t.Error("DecryptWithContext() with wrong associated data err = nil, want error")
+				}
+			}
+		})
+	}
+}
+
+func TestWithCiphertextMetadata_rawCiphertext(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN, destinationKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	plaintext := []byte("plaintext")
+	raw, err := getAEAD(t, newTestClient(t, WithKMS(fakekms)), sourceKeyURI).EncryptWithContext(t.Context(), plaintext, nil)
+	if err != nil {
+		t.Fatalf("EncryptWithContext() err = %v, want nil", err)
+	}
+	info, err := InspectCiphertext(raw)
+	if err != nil {
+		t.Fatalf("InspectCiphertext() err = %v, want nil", err)
+	}
+	if *info != (CiphertextInfo{}) {
+		t.Errorf("InspectCiphertext() = %+v, want zero value", *info)
+	}
+	a := getAEAD(t, newTestClient(t, WithKMS(fakekms), WithCiphertextMetadata()), sourceKeyURI)
+	if got, err := a.DecryptWithContext(t.Context(), raw, nil); err != nil || !bytes.Equal(got, plaintext) {
+		t.Errorf("a.DecryptWithContext() = %q, %v, want %q, nil", got, err, plaintext)
+	}
+}
+
+func TestWithCiphertextMetadata_reEncrypt(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN, destinationKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	client := newTestClient(t, WithKMS(fakekms), WithCiphertextMetadata(), WithEncryptionContextEncoder(UTF8Encoder("ad")))
+	plaintext := []byte("plaintext")
+	associatedData := []byte("associatedData")
+	ciphertext, err := getAEAD(t, client, sourceKeyURI).EncryptWithContext(t.Context(), plaintext, associatedData)
+	if err != nil {
+		t.Fatalf("EncryptWithContext() err = %v, want nil", err)
+	}
+
+	// A client with another encoder re-encrypts if the encoder of the
+	// ciphertext is one of its decrypt fallbacks.
+	rotator := newTestClient(t, WithKMS(fakekms), WithCiphertextMetadata(), WithDecryptFallback(UTF8Encoder("ad")))
+	newCiphertext, err := rotator.ReEncrypt(t.Context(), ciphertext, associatedData, destinationKeyURI, associatedData)
+	if err != nil {
+		t.Fatalf("rotator.ReEncrypt() err = %v, want nil", err)
+	}
+	info, err := InspectCiphertext(newCiphertext)
+	if err != nil {
+		t.Fatalf("InspectCiphertext() err = %v, want nil", err)
+	}
+	want := CiphertextInfo{HasMetadata: true, KeyARN: destinationKeyARN, EncryptionContextName: "associatedData"}
+	if *info != want {
+		t.Errorf("InspectCiphertext() = %+v, want %+v", *info, want)
+	}
+	if got, err := getAEAD(t, rotator, destinationKeyURI).DecryptWithContext(t.Context(), newCiphertext, associatedData); err != nil || !bytes.Equal(got, plaintext) {
+		t.Errorf("DecryptWithContext() = %q, %v, want %q, nil", got, err, plaintext)
+	}
+
+	// Without the option, the result is a raw ciphertext.
+	withoutMetadata := newTestClient(t, WithKMS(fakekms), WithEncryptionContextEncoder(UTF8Encoder("ad")))
+	raw, err := withoutMetadata.ReEncrypt(t.Context(), ciphertext, associatedData, destinationKeyURI, associatedData)
+	if err != nil {
+		t.Fatalf("withoutMetadata.ReEncrypt() err = %v, want nil", err)
+	}
+	if hasCiphertextMetadata(raw) {
+		t.Error("ReEncrypt() without WithCiphertextMetadata returned a metadata header")
+	}
+}
+
+func TestWithCiphertextMetadata_invalidCiphertextFails(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN, destinationKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	client := newTestClient(t, WithKMS(fakekms), WithCiphertextMetadata())
+	a := getAEAD(t, client, sourceKeyURI)
+	ciphertext, err := a.EncryptWithContext(t.Context(), []byte("plaintext"), nil)
+	if err != nil {
+		t.Fatalf("EncryptWithContext() err = %v, want nil", err)
+	}
+	info, inner, err := parseCiphertextMetadata(ciphertext)
+	if err != nil {
+		t.Fatalf("parseCiphertextMetadata() err = %v, want nil", err)
+	}
+	mustAdd := func(keyARN, encoderName string) []byte {
+		t.Helper()
+		c, err := addCiphertextMetadata(keyARN, encoderName, inner)
+		if err != nil {
+			t.Fatalf("addCiphertextMetadata() err = %v, want nil", err)
+		}
+		return c
+	}
+
+	for _, test := range []struct {
+		name       string
+		ciphertext []byte
+		wantErr    error
+	}{
+		{"truncated key ARN length", metadataHeader, nil},
+		{"truncated key ARN", append(bytes.Clone(metadataHeader), 0, 10, 'a'), nil},
+		{"truncated encoder name", ciphertext[:len(metadataHeader)+2+len(info.KeyARN)+3], nil},
+		{"empty encoder name", mustAdd(sourceKeyARN, ""), nil},
+		{"other key ARN", mustAdd(destinationKeyARN, info.EncryptionContextName), ErrKeyIDMismatch},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := a.DecryptWithContext(t.Context(), test.ciphertext, nil)
+			if err == nil {
+				t.Fatal("a.DecryptWithContext() err = nil, want error")
+			}
+			if test.wantErr != nil && !errors.Is(err, test.wantErr) {
+				t.Errorf("a.DecryptWithContext() err = %v, want %v", err, test.wantErr)
+			}
+		})
+	}
+	if _, err := InspectCiphertext(metadataHeader); err == nil {
+		t.Error("InspectCiphertext() of truncated header err = nil, want error")
+	}
+}
+
+func TestWithCiphertextMetadata_forgedEncoderFails(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN, destinationKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	plaintext := []byte("plaintext")
+	ciphertext, err := getAEAD(t, newTestClient(t, WithKMS(fakekms), WithCiphertextMetadata()), sourceKeyURI).EncryptWithContext(t.Context(), plaintext, []byte("ab"))
+	if err != nil {
+		t.Fatalf("EncryptWithContext() err = %v, want nil", err)
+	}
+	_, inner, err := parseCiphertextMetadata(ciphertext)
+	if err != nil {
+		t.Fatalf("parseCiphertextMetadata() err = %v, want nil", err)
+	}
+	// With the UTF-8 encoder, associated data "6162" would have the
+	// encryption context {"associatedData": "6162"} of "ab" with the default
+	// encoder.
+	for _, encoderName := range []string{"utf8(associatedData)", "custom"} {
+		forged, err := addCiphertextMetadata(sourceKeyARN, encoderName, inner)
+		if err != nil {
+			t.Fatalf("addCiphertextMetadata() err = %v, want nil", err)
+		}
+		for _, opts := range [][]ClientOption{nil, {WithCiphertextMetadata()}, {WithDecryptFallback()}} {
+			client := newTestClient(t, append([]ClientOption{WithKMS(fakekms)}, opts...)...)
+			a := getAEAD(t, client, sourceKeyURI)
+			if got, err := a.DecryptWithContext(t.Context(), forged, []byte("6162")); err == nil {
+				t.Errorf("DecryptWithContext() with header naming %q = %q, want error", encoderName, got)
+			}
+			if _, err := client.ReEncrypt(t.Context(), forged, []byte("6162"), destinationKeyURI, []byte("6162")); err == nil {
+				t.Errorf("ReEncrypt() with header naming %q err = nil, want error", encoderName)
+			}
+			// The header does not prevent decryption with the configured
+			// encoder.
+			if got, err := a.DecryptWithContext(t.Context(), forged, []byte("ab")); err != nil || !bytes.Equal(got, plaintext) {
+				t.Errorf("DecryptWithContext() with header naming %q = %q, %v, want %q, nil", encoderName, got, err, plaintext)
+			}
+		}
+	}
+}
+
+func TestWithCiphertextMetadata_twiceFails(t *testing.T) {
+	if _, err := NewClientWithOptions(t.Context(), "aws-kms://", WithCiphertextMetadata(), WithCiphertextMetadata()); err == nil {
+		t.Error("NewClientWithOptions() with WithCiphertextMetadata twice err = nil, want error")
+	}
+}
@@ -0,0 +1,353 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/tink-crypto/tink-go-awskms/v3/integration/awskms"
+	"github.com/tink-crypto/tink-go/v2/insecurecleartextkeyset"
+	"github.com/tink-crypto/tink-go/v2/keyset"
+	"github.com/tink-crypto/tink-go/v2/tink"
+)
+
+func runEncrypt(ctx context.Context, e *env, args []string) error {
+	return runAEAD(ctx, e, "encrypt", args, tink.AEADWithContext.EncryptWithContext)
+}
+
+func runDecrypt(ctx context.Context, e *env, args []string) error {
+	return runAEAD(ctx, e, "decrypt", args, tink.AEADWithContext.DecryptWithContext)
+}
+
+// runAEAD runs the encrypt or decrypt command, which apply op to the input.
+func runAEAD(ctx context.Context, e *env, name string, args []string, op func(a tink.AEADWithContext, ctx context.Context, data, associatedData []byte) ([]byte, error)) error {
+	var (
+		cf             clientFlags
+		iof            ioFlags
+		keyURI         string
+		associatedData string
+		dryRun         bool
+	)
+	fs := newFlagSet(e, name, "-key-uri URI [flags]")
+	cf.register(fs)
+	iof.register(fs)
+	fs.StringVar(&keyURI, "key-uri", "", "`URI` of the AWS KMS key, aws-kms://arn:...")
+	fs.StringVar(&associatedData, "associated-data", "", "associated `data`")
+	fs.BoolVar(&dryRun, "dry-run", false, "only check that AWS KMS would accept the request, and write no output")
+	if err := parse(fs, args, "key-uri"); err != nil {
+		return err
+	}
+
+	a, err := newAEAD(ctx, &cf, keyURI)
+	if err != nil {
+		return err
+	}
+	input, err := iof.read(e)
+	if err != nil {
+		return err
+	}
+	details := &awskms.OperationDetails{}
+	if dryRun {
+		ctx = awskms.ContextWithOperationDetails(awskms.ContextWithDryRun(ctx), details)
+	}
+	output, err := op(a, ctx, input, []byte(associatedData))
+	if errors.Is(err, awskms.ErrDryRunSucceeded) {
+		fmt.Fprintf(e.stderr, "dry run of %s with %s succeeded\n", details.DryRun.Operation, keyURI)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return iof.write(e, output)
+}
+
+func newAEAD(ctx context.Context, cf *clientFlags, keyURI string) (tink.AEADWithContext, error) {
+	c, err := cf.newClient(ctx, keyURI)
+	if err != nil {
+		return nil, err
+	}
+	a, err := c.GetAEAD(keyURI)
+	if err != nil {
+		return nil, err
+	}
+	return a.(tink.AEADWithContext), nil
+}
+
+// keysetFlags are the flags of commands processing keysets.
+type keysetFlags struct {
+	format         string
+	associatedData string
+}
+
+func (f *keysetFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.format, "format", "json", "keyset `format`: json or binary")
+	fs.StringVar(&f.associatedData, "associated-data", "", "associated `data` of the encrypted keyset")
+}
+
+func (f *keysetFlags) reader(data []byte) (keyset.Reader, error) {
+	switch f.format {
+	case "json":
+		return keyset.NewJSONReader(bytes.NewReader(data)), nil
+	case "binary":
+		return keyset.NewBinaryReader(bytes.NewReader(data)), nil
+	}
+	return nil, fmt.Errorf("invalid keyset format %q", f.format)
+}
+
+func (f *keysetFlags) writer(buf *bytes.Buffer) (keyset.Writer, error) {
+	switch f.format {
+	case "json":
+		return keyset.NewJSONWriter(buf), nil
+	case "binary":
+		return keyset.NewBinaryWriter(buf), nil
+	}
+	return nil, fmt.Errorf("invalid keyset format %q", f.format)
+}
+
+func runWrapKeyset(ctx context.Context, e *env, args []string) error {
+	var (
+		cf     clientFlags
+		iof    ioFlags
+		kf     keysetFlags
+		keyURI string
+	)
+	fs := newFlagSet(e, "wrap-keyset", "-key-uri URI [flags]")
+	cf.register(fs)
+	iof.register(fs)
+	kf.register(fs)
+	fs.StringVar(&keyURI, "key-uri", "", "`URI` of the AWS KMS key wrapping the keyset")
+	if err := parse(fs, args, "key-uri"); err != nil {
+		return err
+	}
+
+	a, err := newAEAD(ctx, &cf, keyURI)
+	if err != nil {
+		return err
+	}
+	input, err := iof.read(e)
+	if err != nil {
+		return err
+	}
+	r, err := kf.reader(input)
+	if err != nil {
+		return err
+	}
+	handle, err := insecurecleartextkeyset.Read(r)
+	if err != nil {
+		return err
+	}
+	buf := new(bytes.Buffer)
+	w, err := kf.writer(buf)
+	if err != nil {
+		return err
+	}
+	if err := handle.WriteWithContext(ctx, w, a, []byte(kf.associatedData)); err != nil {
+		return err
+	}
+	return iof.write(e, buf.Bytes())
+}
+
+func runUnwrapKeyset(ctx context.Context, e *env, args []string) error {
+	var (
+		cf     clientFlags
+		iof    ioFlags
+		kf     keysetFlags
+		keyURI string
+	)
+	fs := newFlagSet(e, "unwrap-keyset", "-key-uri URI [flags]")
+	cf.register(fs)
+	iof.register(fs)
+	kf.register(fs)
+	fs.StringVar(&keyURI, "key-uri", "", "`URI` of the AWS KMS key wrapping the keyset")
+	if err := parse(fs, args, "key-uri"); err != nil {
+		return err
+	}
+
+	a, err := newAEAD(ctx, &cf, keyURI)
+	if err != nil {
+		return err
+	}
+	input, err := iof.read(e)
+	if err != nil {
+		return err
+	}
+	r, err := kf.reader(input)
+	if err != nil {
+		return err
+	}
+	handle, err := keyset.ReadWithContext(ctx, r, a, []byte(kf.associatedData))
+	if err != nil {
+		return err
+	}
+	buf := new(bytes.Buffer)
+	w, err := kf.writer(buf)
+	if err != nil {
+		return err
+	}
+	if err := insecurecleartextkeyset.Write(handle, w); err != nil {
+		return err
+	}
+	return iof.write(e, buf.Bytes())
+}
+
+func runRewrap(ctx context.Context, e *env, args []string) error {
+	var (
+		cf                clientFlags
+		iof               ioFlags
+		kf                keysetFlags
+		keyURI            string
+		newKeyURI         string
+		newAssociatedData string
+	)
+	fs := newFlagSet(e, "rewrap", "-key-uri URI -new-key-uri URI [flags]")
+	cf.register(fs)
+	iof.register(fs)
+	kf.register(fs)
+	fs.StringVar(&keyURI, "key-uri", "", "`URI` of the AWS KMS key currently wrapping the keyset")
+	fs.StringVar(&newKeyURI, "new-key-uri", "", "`URI` of the AWS KMS key to wrap the keyset with")
+	fs.StringVar(&newAssociatedData, "new-associated-data", "", "associated `data` of the rewrapped keyset, by default the same as -associated-data")
+	if err := parse(fs, args, "key-uri", "new-key-uri"); err != nil {
+		return err
+	}
+	toAssociatedData := kf.associatedData
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "new-associated-data" {
+			toAssociatedData = newAssociatedData
+		}
+	})
+
+	c, err := cf.newClient(ctx, keyURI, newKeyURI)
+	if err != nil {
+		return err
+	}
+	input, err := iof.read(e)
+	if err != nil {
+		return err
+	}
+	r, err := kf.reader(input)
+	if err != nil {
+		return err
+	}
+	encryptedKeyset, err := r.ReadEncrypted()
+	if err != nil {
+		return err
+	}
+	// The keyset is re-encrypted by AWS KMS, without being decrypted locally.
+	encryptedKeyset.EncryptedKeyset, err = c.ReEncrypt(ctx, encryptedKeyset.GetEncryptedKeyset(), []byte(kf.associatedData), newKeyURI, []byte(toAssociatedData), awskms.WithSourceKeyURI(keyURI))
+	if err != nil {
+		return err
+	}
+	buf := new(bytes.Buffer)
+	w, err := kf.writer(buf)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteEncrypted(encryptedKeyset); err != nil {
+		return err
+	}
+	return iof.write(e, buf.Bytes())
+}
+
+// parsedKeyURI holds the components of an AWS KMS key URI.
+type parsedKeyURI struct {
+	partition string
+	region    string
+	account   string
+	// resourceType is "key" or "alias".
+	resourceType string
+	resourceID   string
+}
+
+var keyURIPattern = regexp.MustCompile(`^aws-kms://arn:(aws[a-zA-Z0-9-_]*):kms:([a-z0-9-]+):([0-9]+):(key|alias)/(.+)$`)
+
+func parseKeyURI(uri string) (*parsedKeyURI, error) {
+	m := keyURIPattern.FindStringSubmatch(uri)
+	if m == nil {
+		return nil, fmt.Errorf("invalid key URI %q, want aws-kms://arn:<partition>:kms:<region>:<account>:key/<id> or alias/<name>", uri)
+	}
+	return &parsedKeyURI{
+		partition:    m[1],
+		region:       m[2],
+		account:      m[3],
+		resourceType: m[4],
+		resourceID:   m[5],
+	}, nil
+}
+
+func runDescribeURI(ctx context.Context, e *env, args []string) error {
+	var uri string
+	fs := newFlagSet(e, "describe-uri", "-key-uri URI")
+	fs.StringVar(&uri, "key-uri", "", "`URI` of the AWS KMS key")
+	if err := parse(fs, args, "key-uri"); err != nil {
+		return err
+	}
+	u, err := parseKeyURI(uri)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(e.stdout, "arn: %s\n", strings.TrimPrefix(uri, "aws-kms://"))
+	fmt.Fprintf(e.stdout, "partition: %s\n", u.partition)
+	fmt.Fprintf(e.stdout, "region: %s\n", u.region)
+	fmt.Fprintf(e.stdout, "account: %s\n", u.account)
+	fmt.Fprintf(e.stdout, "%s: %s\n", u.resourceType, u.resourceID)
+	return nil
+}
+
+func runCheckAccess(ctx context.Context, e *env, args []string) error {
+	var (
+		cf     clientFlags
+		keyURI string
+	)
+	fs := newFlagSet(e, "check-access", "-key-uri URI [flags]")
+	cf.register(fs)
+	fs.StringVar(&keyURI, "key-uri", "", "`URI` of the AWS KMS key")
+	if err := parse(fs, args, "key-uri"); err != nil {
+		return err
+	}
+
+	c, err := cf.newClient(ctx, keyURI)
+	if err != nil {
+		return err
+	}
+	report, err := c.CheckKey(ctx, keyURI)
+	if report != nil {
+		fmt.Fprintf(e.stdout, "key: %s\n", report.KeyURI)
+		if report.CanDescribe {
+			fmt.Fprintf(e.stdout, "arn: %s\n", report.KeyARN)
+			fmt.Fprintf(e.stdout, "state: %s\n", report.KeyState)
+			fmt.Fprintf(e.stdout, "spec: %s\n", report.KeySpec)
+			fmt.Fprintf(e.stdout, "usage: %s\n", report.KeyUsage)
+			fmt.Fprintf(e.stdout, "origin: %s\n", report.Origin)
+			fmt.Fprintf(e.stdout, "multi-region: %t\n", report.MultiRegion)
+		}
+		fmt.Fprintf(e.stdout, "kms:DescribeKey: %s\n", result(report.CanDescribe))
+		fmt.Fprintf(e.stdout, "kms:Encrypt: %s\n", result(report.CanEncrypt))
+		fmt.Fprintf(e.stdout, "kms:Decrypt: %s\n", result(report.CanDecrypt))
+	}
+	return err
+}
+
+func result(ok bool) string {
+	if ok {
+		return "OK"
+	}
+	return "FAILED"
+}
@@ -0,0 +1,228 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/tink-crypto/tink-go-awskms/v3/integration/awskms"
+)
+
+// errUsage is returned when the flags of a command are invalid. The flag
+// package has already reported the problem.
+var errUsage = errors.New("invalid usage")
+
+func exitCode(err error) int {
+	if err == errUsage {
+		return 2
+	}
+	return 1
+}
+
+// clientFlags are the flags configuring the AWS KMS client.
+type clientFlags struct {
+	credentialPath        string
+	profile               string
+	endpoint              string
+	region                string
+	encryptionContextName string
+	decryptFallback       bool
+	validateKey           bool
+	grantTokens           stringsFlag
+	encryptionAlgorithm   string
+	envelopeFallback      bool
+}
+
+// stringsFlag is a flag which can be repeated.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string { return strings.Join(*f, ",") }
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+func (f *clientFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.credentialPath, "credentials", "", "path of a CSV or INI `file` with AWS credentials; only INI with -profile, -endpoint or -region")
+	fs.StringVar(&f.profile, "profile", "", "shared configuration `profile` to use")
+	fs.StringVar(&f.endpoint, "endpoint", "", "AWS KMS endpoint `URL`, for example of a local emulator")
+	fs.StringVar(&f.region, "region", "", "AWS `region`, by default the region of the key URI")
+	fs.StringVar(&f.encryptionContextName, "encryption-context-name", "associatedData", "encryption context `name` of the associated data: associatedData or additionalData")
+	fs.BoolVar(&f.decryptFallback, "decrypt-fallback", false, "retry decryption with the other encryption context name")
+	fs.BoolVar(&f.validateKey, "validate-key", false, "check that the key is a symmetric encryption key before using it")
+	fs.Var(&f.grantTokens, "grant-token", "grant `token` to send with AWS KMS requests; can be repeated")
+	fs.BoolVar(&f.envelopeFallback, "envelope-fallback", false, "envelope encrypt data exceeding the AWS KMS size limit, and accept envelope ciphertexts when decrypting")
+	fs.StringVar(&f.encryptionAlgorithm, "encryption-algorithm", "", "AWS KMS encryption `algorithm`, for example RSAES_OAEP_SHA_256; by default SYMMETRIC_DEFAULT")
+}
+
+// newFlagSet returns a flag set for the command name which reports errors to
+// e.stderr.
+func newFlagSet(e *env, name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(e.stderr, "Usage: tink-awskms %s %s\n", name, usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse parses args into fs and checks that all required flags are set.
+func parse(fs *flag.FlagSet, args []string, required ...string) error {
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(fs.Output(), "unexpected arguments: %q\n", fs.Args())
+		fs.Usage()
+		return errUsage
+	}
+	for _, name := range required {
+		if fs.Lookup(name).Value.String() == "" {
+			fmt.Fprintf(fs.Output(), "flag -%s is required\n", name)
+			fs.Usage()
+			return errUsage
+		}
+	}
+	return nil
+}
+
+// newClient returns a client handling keyURI configured by f. Keys in
+// the regions of extraKeyURIs can be used by [awskms.Client.ReEncrypt].
+func (f *clientFlags) newClient(ctx context.Context, keyURI string, extraKeyURIs ...string) (awskms.Client, error) {
+	var opts []awskms.ClientOption
+	switch f.encryptionContextName {
+	case awskms.AssociatedData.String():
+		opts = append(opts, awskms.WithEncryptionContextName(awskms.AssociatedData))
+	case awskms.LegacyAdditionalData.String():
+		opts = append(opts, awskms.WithEncryptionContextName(awskms.LegacyAdditionalData))
+	default:
+		return nil, fmt.Errorf("invalid encryption context name %q", f.encryptionContextName)
+	}
+	if f.decryptFallback {
+		opts = append(opts, awskms.WithDecryptFallback())
+	}
+	if f.validateKey {
+		opts = append(opts, awskms.WithKeyValidation())
+	}
+	if len(f.grantTokens) > 0 {
+		opts = append(opts, awskms.WithGrantTokens(f.grantTokens...))
+	}
+	if f.envelopeFallback {
+		opts = append(opts, awskms.WithEnvelopeFallback())
+	}
+	if f.encryptionAlgorithm != "" {
+		opts = append(opts, awskms.WithEncryptionAlgorithm(types.EncryptionAlgorithmSpec(f.encryptionAlgorithm)))
+	}
+
+	if f.profile == "" && f.endpoint == "" && f.region == "" {
+		if f.credentialPath != "" {
+			opts = append(opts, awskms.WithCredentialPath(f.credentialPath))
+		}
+	} else {
+		region := f.region
+		if region == "" {
+			u, err := parseKeyURI(keyURI)
+			if err != nil {
+				return nil, err
+			}
+			region = u.region
+		}
+		k, err := f.newKMS(ctx, region)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, awskms.WithKMS(k))
+		regions := map[string]bool{region: true}
+		for _, uri := range extraKeyURIs {
+			u, err := parseKeyURI(uri)
+			if err != nil || regions[u.region] {
+				continue
+			}
+			k, err := f.newKMS(ctx, u.region)
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, awskms.WithRegionalKMS(u.region, k))
+			regions[u.region] = true
+		}
+	}
+
+	c, err := awskms.NewClientWithOptions(ctx, keyURI, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return c.(awskms.Client), nil
+}
+
+// newKMS returns an AWS KMS client for region using the shared configuration,
+// and the shared credentials file of -credentials if set.
+func (f *clientFlags) newKMS(ctx context.Context, region string) (*kms.Client, error) {
+	loadOpts := []func(*config.LoadOptions) error{config.WithRegion(region)}
+	if f.profile != "" {
+		loadOpts = append(loadOpts, config.WithSharedConfigProfile(f.profile))
+	}
+	if f.credentialPath != "" {
+		loadOpts = append(loadOpts, config.WithSharedCredentialsFiles([]string{f.credentialPath}))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, loadOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return kms.NewFromConfig(cfg, func(o *kms.Options) {
+		if f.endpoint != "" {
+			o.BaseEndpoint = aws.String(f.endpoint)
+		}
+	}), nil
+}
+
+// ioFlags are the flags selecting the input and output of a command.
+type ioFlags struct {
+	in  string
+	out string
+}
+
+func (f *ioFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.in, "in", "-", "input `file`, - for standard input")
+	fs.StringVar(&f.out, "out", "-", "output `file`, - for standard output")
+}
+
+func (f *ioFlags) read(e *env) ([]byte, error) {
+	if f.in == "-" {
+		return io.ReadAll(e.stdin)
+	}
+	return os.ReadFile(f.in)
+}
+
+// write writes data to the output. Files are created readable only by the
+// current user, since they may contain plaintext or keys.
+func (f *ioFlags) write(e *env, data []byte) error {
+	if f.out == "-" {
+		_, err := e.stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(f.out, data, 0600)
+}
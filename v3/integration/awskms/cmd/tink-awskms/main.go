@@ -0,0 +1,106 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command tink-awskms encrypts data and wraps Tink keysets with AWS KMS keys.
+//
+// Usage:
+//
+//	tink-awskms <command> [flags]
+//
+// The commands are:
+//
+//	encrypt        encrypt data with a key
+//	decrypt        decrypt data encrypted with a key
+//	wrap-keyset    encrypt a cleartext keyset with a key
+//	unwrap-keyset  decrypt an encrypted keyset
+//	rewrap         re-encrypt an encrypted keyset with another key
+//	describe-uri   print the components of a key URI
+//	check-access   check that a key is enabled and can be used to encrypt and decrypt
+//
+// Input is read from -in and output written to -out, which default to
+// standard input and output. Run "tink-awskms <command> -h" for the flags of
+// a command.
+//
+// By default, credentials and configuration are loaded like in the AWS CLI.
+// Use -endpoint to target a local AWS KMS emulator, such as the fake AWS KMS
+// served by integration/awskms/internal/fakeawskms/cmd/fakeawskms.
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+)
+
+func main() {
+	os.Exit(run(context.Background(), os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// command is a subcommand of tink-awskms.
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, env *env, args []string) error
+}
+
+var commands = []*command{
+	{"encrypt", "encrypt data with a key", runEncrypt},
+	{"decrypt", "decrypt data encrypted with a key", runDecrypt},
+	{"wrap-keyset", "encrypt a cleartext keyset with a key", runWrapKeyset},
+	{"unwrap-keyset", "decrypt an encrypted keyset", runUnwrapKeyset},
+	{"rewrap", "re-encrypt an encrypted keyset with another key", runRewrap},
+	{"describe-uri", "print the components of a key URI", runDescribeURI},
+	{"check-access", "check that a key is enabled and can be used to encrypt and decrypt", runCheckAccess},
+}
+
+// env holds the standard streams of a command.
+type env struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// run runs the command described by args and returns the exit code.
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	e := &env{stdin: stdin, stdout: stdout, stderr: stderr}
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage(stderr)
+		return 2
+	}
+	for _, c := range commands {
+		if c.name != args[0] {
+			continue
+		}
+		if err := c.run(ctx, e, args[1:]); err != nil {
+			if err != errUsage {
+				fmt.Fprintf(stderr, "tink-awskms %s: %v\n", c.name, err)
+			}
+			return exitCode(err)
+		}
+		return 0
+	}
+	fmt.Fprintf(stderr, "tink-awskms: unknown command %q\n", args[0])
+	usage(stderr)
+	return 2
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: tink-awskms <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-14s %s\n", c.name, c.summary)
+	}
+}
@@ -0,0 +1,271 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/tink-crypto/tink-go-awskms/v3/integration/awskms/internal/fakeawskms"
+	"github.com/tink-crypto/tink-go/v2/aead"
+	"github.com/tink-crypto/tink-go/v2/insecurecleartextkeyset"
+	"github.com/tink-crypto/tink-go/v2/keyset"
+)
+
+const (
+	keyARN    = "arn:aws:kms:us-east-2:235739564943:key/3ee50705-5a82-4f5b-9753-05c4f473922f"
+	keyURI    = "aws-kms://" + keyARN
+	newKeyARN = "arn:aws:kms:us-east-2:235739564943:key/b3ca2efd-a8fb-47f2-b541-7e20f8c5cd11"
+	newKeyURI = "aws-kms://" + newKeyARN
+)
+
+// startEmulator serves a fake AWS KMS with the test keys and returns its
+// endpoint. The environment is set up so that no real credentials are used.
+func startEmulator(t *testing.T) string {
+	t.Helper()
+	endpoint, _ := startFakeEmulator(t)
+	return endpoint
+}
+
+// startFakeEmulator is like startEmulator, but also returns the fake backing
+// the emulator.
+func startFakeEmulator(t *testing.T) (string, *fakeawskms.FakeAWSKMS) {
+	t.Helper()
+	fakekms, err := fakeawskms.New([]string{keyARN, newKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	server := httptest.NewServer(fakeawskms.NewHandler(fakekms))
+	t.Cleanup(server.Close)
+
+	dir := t.TempDir()
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	return server.URL, fakekms
+}
+
+// runCommand runs tink-awskms with args and input on standard input. It fails
+// the test unless the exit code is wantCode, and returns standard output.
+func runCommand(t *testing.T, wantCode int, input []byte, args ...string) []byte {
+	t.Helper()
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	if code := run(t.Context(), args, bytes.NewReader(input), stdout, stderr); code != wantCode {
+		t.Fatalf("run(%q) = %d, want %d, stderr:\n%s", args, code, wantCode, stderr)
+	}
+	return stdout.Bytes()
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	endpoint := startEmulator(t)
+	plaintext := []byte("plaintext")
+	ciphertext := runCommand(t, 0, plaintext, "encrypt", "-endpoint", endpoint, "-key-uri", keyURI, "-associated-data", "ad", "-validate-key")
+	got := runCommand(t, 0, ciphertext, "decrypt", "-endpoint", endpoint, "-key-uri", keyURI, "-associated-data", "ad")
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("decrypt = %q, want %q", got, plaintext)
+	}
+	runCommand(t, 1, ciphertext, "decrypt", "-endpoint", endpoint, "-key-uri", keyURI, "-associated-data", "other")
+	runCommand(t, 1, ciphertext, "decrypt", "-endpoint", endpoint, "-key-uri", keyURI, "-associated-data", "ad", "-encryption-context-name", "additionalData")
+}
+
+func TestEncryptDecrypt_grantTokens(t *testing.T) {
+	endpoint, fakekms := startFakeEmulator(t)
+	token, err := fakekms.AddGrant(keyARN, types.GrantOperationEncrypt, types.GrantOperationDecrypt)
+	if err != nil {
+		t.Fatalf("fakekms.AddGrant() failed: %v", err)
+	}
+	plaintext := []byte("plaintext")
+	runCommand(t, 1, plaintext, "encrypt", "-endpoint", endpoint, "-key-uri", keyURI)
+	ciphertext := runCommand(t, 0, plaintext, "encrypt", "-endpoint", endpoint, "-key-uri", keyURI, "-grant-token", "other", "-grant-token", token)
+	got := runCommand(t, 0, ciphertext, "decrypt", "-endpoint", endpoint, "-key-uri", keyURI, "-grant-token", token)
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("decrypt = %q, want %q", got, plaintext)
+	}
+}
+
+func TestEncryptDecrypt_dryRun(t *testing.T) {
+	endpoint, fakekms := startFakeEmulator(t)
+	plaintext := []byte("plaintext")
+	ciphertext := runCommand(t, 0, plaintext, "encrypt", "-endpoint", endpoint, "-key-uri", keyURI)
+	if got := runCommand(t, 0, plaintext, "encrypt", "-endpoint", endpoint, "-key-uri", keyURI, "-dry-run"); len(got) != 0 {
+		t.Errorf("encrypt -dry-run output = %q, want empty", got)
+	}
+	if got := runCommand(t, 0, ciphertext, "decrypt", "-endpoint", endpoint, "-key-uri", keyURI, "-dry-run"); len(got) != 0 {
+		t.Errorf("decrypt -dry-run output = %q, want empty", got)
+	}
+	if _, err := fakekms.AddGrant(keyARN, types.GrantOperationDecrypt); err != nil {
+		t.Fatalf("fakekms.AddGrant() failed: %v", err)
+	}
+	runCommand(t, 1, plaintext, "encrypt", "-endpoint", endpoint, "-key-uri", keyURI, "-dry-run")
+}
+
+func TestEncryptDecrypt_encryptionAlgorithm(t *testing.T) {
+	endpoint, fakekms := startFakeEmulator(t)
+	const rsaKeyARN = "arn:aws:kms:us-east-2:235739564943:key/7a2d9c1e-3b4f-4e5a-8c6d-1f2e3d4c5b6a"
+	const rsaKeyURI = "aws-kms://" + rsaKeyARN
+	if err := fakekms.AddKey(rsaKeyARN, types.KeySpecRsa2048, types.KeyUsageTypeEncryptDecrypt); err != nil {
+		t.Fatalf("fakekms.AddKey() failed: %v", err)
+	}
+	plaintext := []byte("plaintext")
+	ciphertext := runCommand(t, 0, plaintext, "encrypt", "-endpoint", endpoint, "-key-uri", rsaKeyURI, "-encryption-algorithm", "RSAES_OAEP_SHA_256")
+	got := runCommand(t, 0, ciphertext, "decrypt", "-endpoint", endpoint, "-key-uri", rsaKeyURI, "-encryption-algorithm", "RSAES_OAEP_SHA_256")
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("decrypt = %q, want %q", got, plaintext)
+	}
+	runCommand(t, 1, plaintext, "encrypt", "-endpoint", endpoint, "-key-uri", keyURI, "-encryption-algorithm", "RSAES_OAEP_SHA_256")
+	runCommand(t, 1, plaintext, "encrypt", "-endpoint", endpoint, "-key-uri", rsaKeyURI, "-encryption-algorithm", "UNKNOWN")
+}
+
+func TestEncryptDecrypt_envelopeFallback(t *testing.T) {
+	endpoint := startEmulator(t)
+	plaintext := bytes.Repeat([]byte("a"), 10000)
+	runCommand(t, 1, plaintext, "encrypt", "-endpoint", endpoint, "-key-uri", keyURI)
+	ciphertext := runCommand(t, 0, plaintext, "encrypt", "-endpoint", endpoint, "-key-uri", keyURI, "-envelope-fallback")
+	got := runCommand(t, 0, ciphertext, "decrypt", "-endpoint", endpoint, "-key-uri", keyURI, "-envelope-fallback")
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("decrypt = %d bytes, want %d bytes", len(got), len(plaintext))
+	}
+	runCommand(t, 1, ciphertext, "decrypt", "-endpoint", endpoint, "-key-uri", keyURI)
+}
+
+func TestEncryptDecrypt_files(t *testing.T) {
+	endpoint := startEmulator(t)
+	dir := t.TempDir()
+	plaintextPath := filepath.Join(dir, "plaintext")
+	ciphertextPath := filepath.Join(dir, "ciphertext")
+	decryptedPath := filepath.Join(dir, "decrypted")
+	plaintext := []byte("plaintext")
+	if err := os.WriteFile(plaintextPath, plaintext, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	runCommand(t, 0, nil, "encrypt", "-endpoint", endpoint, "-key-uri", keyURI, "-in", plaintextPath, "-out", ciphertextPath)
+	runCommand(t, 0, nil, "decrypt", "-endpoint", endpoint, "-key-uri", keyURI, "-in", ciphertextPath, "-out", decryptedPath)
+	got, err := os.ReadFile(decryptedPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("decrypted file = %q, want %q", got, plaintext)
+	}
+}
+
+func TestDecryptFallback(t *testing.T) {
+	endpoint := startEmulator(t)
+	plaintext := []byte("plaintext")
+	ciphertext := runCommand(t, 0, plaintext, "encrypt", "-endpoint", endpoint, "-key-uri", keyURI, "-associated-data", "ad", "-encryption-context-name", "additionalData")
+	runCommand(t, 1, ciphertext, "decrypt", "-endpoint", endpoint, "-key-uri", keyURI, "-associated-data", "ad")
+	got := runCommand(t, 0, ciphertext, "decrypt", "-endpoint", endpoint, "-key-uri", keyURI, "-associated-data", "ad", "-decrypt-fallback")
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("decrypt = %q, want %q", got, plaintext)
+	}
+}
+
+func TestWrapUnwrapRewrapKeyset(t *testing.T) {
+	endpoint := startEmulator(t)
+	for _, format := range []string{"json", "binary"} {
+		t.Run(format, func(t *testing.T) {
+			handle, err := keyset.NewHandle(aead.AES128GCMKeyTemplate())
+			if err != nil {
+				t.Fatalf("keyset.NewHandle() err = %v, want nil", err)
+			}
+			buf := new(bytes.Buffer)
+			var w keyset.Writer = keyset.NewJSONWriter(buf)
+			if format == "binary" {
+				w = keyset.NewBinaryWriter(buf)
+			}
+			if err := insecurecleartextkeyset.Write(handle, w); err != nil {
+				t.Fatalf("insecurecleartextkeyset.Write() err = %v, want nil", err)
+			}
+			cleartext := buf.Bytes()
+
+			wrapped := runCommand(t, 0, cleartext, "wrap-keyset", "-endpoint", endpoint, "-key-uri", keyURI, "-format", format, "-associated-data", "keyset")
+			if bytes.Equal(wrapped, cleartext) {
+				t.Fatal("wrap-keyset returned the cleartext keyset")
+			}
+			rewrapped := runCommand(t, 0, wrapped, "rewrap", "-endpoint", endpoint, "-key-uri", keyURI, "-new-key-uri", newKeyURI, "-format", format, "-associated-data", "keyset", "-new-associated-data", "new keyset")
+
+			// The rewrapped keyset can only be unwrapped by the new key and associated data.
+			runCommand(t, 1, rewrapped, "unwrap-keyset", "-endpoint", endpoint, "-key-uri", keyURI, "-format", format, "-associated-data", "new keyset")
+			runCommand(t, 1, rewrapped, "unwrap-keyset", "-endpoint", endpoint, "-key-uri", newKeyURI, "-format", format, "-associated-data", "keyset")
+			got := runCommand(t, 0, rewrapped, "unwrap-keyset", "-endpoint", endpoint, "-key-uri", newKeyURI, "-format", format, "-associated-data", "new keyset")
+			if !bytes.Equal(got, cleartext) {
+				t.Errorf("unwrap-keyset = %q, want %q", got, cleartext)
+			}
+		})
+	}
+}
+
+func TestCheckAccess(t *testing.T) {
+	endpoint := startEmulator(t)
+	out := string(runCommand(t, 0, nil, "check-access", "-endpoint", endpoint, "-key-uri", keyURI))
+	for _, want := range []string{"arn: " + keyARN, "state: Enabled", "kms:DescribeKey: OK", "kms:Encrypt: OK", "kms:Decrypt: OK"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("check-access = %q, want it to contain %q", out, want)
+		}
+	}
+	out = string(runCommand(t, 1, nil, "check-access", "-endpoint", endpoint, "-key-uri", "aws-kms://arn:aws:kms:us-east-2:235739564943:key/unknown"))
+	if !strings.Contains(out, "kms:Encrypt: FAILED") {
+		t.Errorf("check-access = %q, want it to contain %q", out, "kms:Encrypt: FAILED")
+	}
+}
+
+func TestDescribeURI(t *testing.T) {
+	out := runCommand(t, 0, nil, "describe-uri", "-key-uri", "aws-kms://arn:aws-us-gov:kms:us-gov-east-1:235739564943:alias/backups")
+	want := "arn: arn:aws-us-gov:kms:us-gov-east-1:235739564943:alias/backups\npartition: aws-us-gov\nregion: us-gov-east-1\naccount: 235739564943\nalias: backups\n"
+	if string(out) != want {
+		t.Errorf("describe-uri = %q, want %q", out, want)
+	}
+	runCommand(t, 1, nil, "describe-uri", "-key-uri", "gcp-kms://projects/p/locations/l/keyRings/r/cryptoKeys/k")
+}
+
+func TestCredentialsWithEndpoint(t *testing.T) {
+	endpoint := startEmulator(t)
+	// Credentials of the environment take precedence over shared files.
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	credentialPath := filepath.Join(t.TempDir(), "credentials")
+	if err := os.WriteFile(credentialPath, []byte("[ci]\naws_access_key_id = AKIDEXAMPLE\naws_secret_access_key = secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	plaintext := []byte("plaintext")
+	ciphertext := runCommand(t, 0, plaintext, "encrypt", "-credentials", credentialPath, "-profile", "ci", "-endpoint", endpoint, "-region", "us-east-2", "-key-uri", keyURI)
+	got := runCommand(t, 0, ciphertext, "decrypt", "-credentials", credentialPath, "-profile", "ci", "-endpoint", endpoint, "-key-uri", keyURI)
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("decrypt = %q, want %q", got, plaintext)
+	}
+	runCommand(t, 1, plaintext, "encrypt", "-credentials", credentialPath, "-profile", "unknown", "-endpoint", endpoint, "-key-uri", keyURI)
+}
+
+func TestInvalidUsage(t *testing.T) {
+	for _, args := range [][]string{
+		nil,
+		{"unknown"},
+		{"encrypt"},
+		{"encrypt", "-key-uri", keyURI, "extra"},
+		{"encrypt", "-unknown-flag"},
+		{"rewrap", "-key-uri", keyURI},
+	} {
+		runCommand(t, 2, nil, args...)
+	}
+	runCommand(t, 1, nil, "encrypt", "-key-uri", keyURI, "-region", "us-east-2", "-encryption-context-name", "other")
+}
@@ -0,0 +1,459 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/tink-crypto/tink-go/v2/hybrid/ecies"
+	"github.com/tink-crypto/tink-go/v2/insecuresecretdataaccess"
+	"github.com/tink-crypto/tink-go/v2/key"
+	"github.com/tink-crypto/tink-go/v2/keyset"
+	"github.com/tink-crypto/tink-go/v2/secretdata"
+	tinkecdsa "github.com/tink-crypto/tink-go/v2/signature/ecdsa"
+	"github.com/tink-crypto/tink-go/v2/signature/rsassapkcs1"
+	"github.com/tink-crypto/tink-go/v2/signature/rsassapss"
+)
+
+// generateDataKeyPairAPI is implemented by KMS clients supporting
+// GenerateDataKeyPair and GenerateDataKeyPairWithoutPlaintext, such as
+// *kms.Client.
+type generateDataKeyPairAPI interface {
+	GenerateDataKeyPair(ctx context.Context, params *kms.GenerateDataKeyPairInput, optFns ...func(*kms.Options)) (*kms.GenerateDataKeyPairOutput, error)
+	GenerateDataKeyPairWithoutPlaintext(ctx context.Context, params *kms.GenerateDataKeyPairWithoutPlaintextInput, optFns ...func(*kms.Options)) (*kms.GenerateDataKeyPairWithoutPlaintextOutput, error)
+}
+
+var errGenerateDataKeyPairUnsupported = errors.New("KMS client does not support GenerateDataKeyPair")
+
+// rsaPublicExponent is the public exponent of the RSA data key pairs of AWS
+// KMS.
+const rsaPublicExponent = 65537
+
+// DataKeyPair is an asymmetric data key pair generated by AWS KMS, whose
+// private key is encrypted with an AWS KMS key.
+type DataKeyPair struct {
+	// Handle is the private keyset of the key pair. It is nil for key pairs
+	// generated by [GenerateDataKeyPairWithoutPlaintext].
+	Handle *keyset.Handle
+	// PublicHandle is the public keyset of the key pair.
+	PublicHandle *keyset.Handle
+	// EncryptedPrivateKey is the private key encrypted by AWS KMS, which
+	// [LoadDataKeyPair] decrypts.
+	EncryptedPrivateKey []byte
+	// PublicKey is the public key as DER-encoded X.509 SubjectPublicKeyInfo.
+	PublicKey []byte
+	// KeyPairSpec is the AWS KMS spec of the key pair, such as ECC_NIST_P256.
+	KeyPairSpec types.DataKeyPairSpec
+	// KeyARN is the ARN of the AWS KMS key which encrypted the private key.
+	KeyARN string
+}
+
+// GenerateDataKeyPair generates a key pair with AWS KMS GenerateDataKeyPair,
+// whose private key is encrypted with the AWS KMS key keyURI and
+// associatedData, and returns it as Tink keysets together with the encrypted
+// private key.
+//
+// parameters determines the type of the keys and the key pair spec. Supported
+// are the parameters of ECDSA, RSA-SSA-PKCS1 and RSA-SSA-PSS signature keys
+// and of ECIES hybrid encryption keys, on the NIST curves or with modulus
+// sizes of 2048, 3072 and 4096 bits and public exponent 65537. The parameters
+// must not require a key ID, that is, they must use the NO_PREFIX variant,
+// because the key ID would be lost when the key pair is loaded.
+func GenerateDataKeyPair(ctx context.Context, client Client, keyURI string, parameters key.Parameters, associatedData []byte) (*DataKeyPair, error) {
+	a, spec, err := newDataKeyPairAEAD(ctx, client, keyURI, parameters)
+	if err != nil {
+		return nil, err
+	}
+	g, ok := a.kms.(generateDataKeyPairAPI)
+	if !ok {
+		return nil, errGenerateDataKeyPairUnsupported
+	}
+	encryptionContext, err := a.encryptionContext(associatedData)
+	if err != nil {
+		return nil, err
+	}
+	dryRun := isDryRun(ctx, a.dryRun)
+	resp, err := g.GenerateDataKeyPair(ctx, &kms.GenerateDataKeyPairInput{
+		KeyId:             aws.String(a.keyID),
+		KeyPairSpec:       spec,
+		EncryptionContext: encryptionContext,
+		GrantTokens:       grantTokens(ctx, a.grantTokens),
+		DryRun:            aws.Bool(dryRun),
+	})
+	if err != nil {
+		if dryRun {
+			err = dryRunError(ctx, err, "GenerateDataKeyPair", a.keyID)
+		}
+		return nil, err
+	}
+	defer clear(resp.PrivateKeyPlaintext)
+	keyARN, err := a.verifyKeyID(ctx, resp.KeyId)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.PrivateKeyCiphertextBlob) == 0 {
+		return nil, errors.New("invalid GenerateDataKeyPair response: no encrypted private key")
+	}
+	handle, err := privateKeyset(parameters, resp.PrivateKeyPlaintext)
+	if err != nil {
+		return nil, err
+	}
+	publicHandle, err := handle.Public()
+	if err != nil {
+		return nil, err
+	}
+	return &DataKeyPair{
+		Handle:              handle,
+		PublicHandle:        publicHandle,
+		EncryptedPrivateKey: resp.PrivateKeyCiphertextBlob,
+		PublicKey:           resp.PublicKey,
+		KeyPairSpec:         spec,
+		KeyARN:              keyARN,
+	}, nil
+}
+
+// GenerateDataKeyPairWithoutPlaintext is like [GenerateDataKeyPair], but uses
+// AWS KMS GenerateDataKeyPairWithoutPlaintext, so that the private key is
+// never returned in plaintext. The returned [DataKeyPair] has no private
+// keyset. The private key can later be decrypted with [LoadDataKeyPair], for
+// example on the device which needs it.
+func GenerateDataKeyPairWithoutPlaintext(ctx context.Context, client Client, keyURI string, parameters key.Parameters, associatedData []byte) (*DataKeyPair, error) {
+	a, spec, err := newDataKeyPairAEAD(ctx, client, keyURI, parameters)
+	if err != nil {
+		return nil, err
+	}
+	g, ok := a.kms.(generateDataKeyPairAPI)
+	if !ok {
+		return nil, errGenerateDataKeyPairUnsupported
+	}
+	encryptionContext, err := a.encryptionContext(associatedData)
+	if err != nil {
+		return nil, err
+	}
+	dryRun := isDryRun(ctx, a.dryRun)
+	resp, err := g.GenerateDataKeyPairWithoutPlaintext(ctx, &kms.GenerateDataKeyPairWithoutPlaintextInput{
+		KeyId:             aws.String(a.keyID),
+		KeyPairSpec:       spec,
+		EncryptionContext: encryptionContext,
+		GrantTokens:       grantTokens(ctx, a.grantTokens),
+		DryRun:            aws.Bool(dryRun),
+	})
+	if err != nil {
+		if dryRun {
+			err = dryRunError(ctx, err, "GenerateDataKeyPairWithoutPlaintext", a.keyID)
+		}
+		return nil, err
+	}
+	keyARN, err := a.verifyKeyID(ctx, resp.KeyId)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.PrivateKeyCiphertextBlob) == 0 {
+		return nil, errors.New("invalid GenerateDataKeyPairWithoutPlaintext response: no encrypted private key")
+	}
+	publicHandle, err := publicKeyset(parameters, resp.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	return &DataKeyPair{
+		PublicHandle:        publicHandle,
+		EncryptedPrivateKey: resp.PrivateKeyCiphertextBlob,
+		PublicKey:           resp.PublicKey,
+		KeyPairSpec:         spec,
+		KeyARN:              keyARN,
+	}, nil
+}
+
+// LoadDataKeyPair decrypts the private key of a data key pair with AWS KMS
+// Decrypt and returns its private keyset. keyURI, parameters and
+// associatedData must be the same as when the key pair was generated.
+func LoadDataKeyPair(ctx context.Context, client Client, keyURI string, parameters key.Parameters, encryptedPrivateKey, associatedData []byte) (*keyset.Handle, error) {
+	a, _, err := newDataKeyPairAEAD(ctx, client, keyURI, parameters)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, err := a.decryptKMS(ctx, encryptedPrivateKey, associatedData)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(privateKey)
+	return privateKeyset(parameters, privateKey)
+}
+
+// newDataKeyPairAEAD returns the AEAD of keyURI, used to encrypt and decrypt
+// private keys, and the key pair spec of parameters.
+func newDataKeyPairAEAD(ctx context.Context, client Client, keyURI string, parameters key.Parameters) (*awsAEAD, types.DataKeyPairSpec, error) {
+	c, ok := client.(*awsClient)
+	if !ok {
+		return nil, "", fmt.Errorf("unsupported client type %T", client)
+	}
+	if !c.Supported(keyURI) {
+		return nil, "", fmt.Errorf("keyURI must start with prefix %s, but got %s", c.keyURIPrefix, keyURI)
+	}
+	spec, err := dataKeyPairSpec(parameters)
+	if err != nil {
+		return nil, "", err
+	}
+	a, err := c.newAEAD(ctx, keyURI, strings.TrimPrefix(keyURI, awsPrefix))
+	if err != nil {
+		return nil, "", err
+	}
+	return a, spec, nil
+}
+
+// dataKeyPairSpec returns the AWS KMS key pair spec of keys with parameters.
+func dataKeyPairSpec(parameters key.Parameters) (types.DataKeyPairSpec, error) {
+	if parameters == nil {
+		return "", errors.New("parameters must not be nil")
+	}
+	if parameters.HasIDRequirement() {
+		return "", errors.New("parameters of data key pairs must not require a key ID, use the NO_PREFIX variant")
+	}
+	switch p := parameters.(type) {
+	case *tinkecdsa.Parameters:
+		switch p.CurveType() {
+		case tinkecdsa.NistP256:
+			return types.DataKeyPairSpecEccNistP256, nil
+		case tinkecdsa.NistP384:
+			return types.DataKeyPairSpecEccNistP384, nil
+		case tinkecdsa.NistP521:
+			return types.DataKeyPairSpecEccNistP521, nil
+		}
+		return "", fmt.Errorf("unsupported ECDSA curve %s", p.CurveType())
+	case *ecies.Parameters:
+		switch p.CurveType() {
+		case ecies.NISTP256:
+			return types.DataKeyPairSpecEccNistP256, nil
+		case ecies.NISTP384:
+			return types.DataKeyPairSpecEccNistP384, nil
+		case ecies.NISTP521:
+			return types.DataKeyPairSpecEccNistP521, nil
+		}
+		return "", fmt.Errorf("unsupported ECIES curve %s", p.CurveType())
+	case *rsassapkcs1.Parameters:
+		return rsaKeyPairSpec(p.ModulusSizeBits(), p.PublicExponent())
+	case *rsassapss.Parameters:
+		return rsaKeyPairSpec(p.ModulusSizeBits(), p.PublicExponent())
+	}
+	return "", fmt.Errorf("unsupported parameters type %T", parameters)
+}
+
+func rsaKeyPairSpec(modulusSizeBits, publicExponent int) (types.DataKeyPairSpec, error) {
+	if publicExponent != rsaPublicExponent {
+		return "", fmt.Errorf("unsupported RSA public exponent %d, want %d", publicExponent, rsaPublicExponent)
+	}
+	switch modulusSizeBits {
+	case 2048:
+		return types.DataKeyPairSpecRsa2048, nil
+	case 3072:
+		return types.DataKeyPairSpecRsa3072, nil
+	case 4096:
+		return types.DataKeyPairSpecRsa4096, nil
+	}
+	return "", fmt.Errorf("unsupported RSA modulus size %d", modulusSizeBits)
+}
+
+// privateKeyset returns a keyset holding the PKCS #8 encoded private key as a
+// key with parameters.
+func privateKeyset(parameters key.Parameters, privateKeyDER []byte) (*keyset.Handle, error) {
+	privateKey, err := x509.ParsePKCS8PrivateKey(privateKeyDER)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data key pair private key: %v", err)
+	}
+	var k key.Key
+	switch p := parameters.(type) {
+	case *tinkecdsa.Parameters:
+		ecdsaKey, err := ecdsaPrivateKeyBytes(privateKey)
+		if err != nil {
+			return nil, err
+		}
+		k, err = tinkecdsa.NewPrivateKey(secret(ecdsaKey), 0, p)
+		if err != nil {
+			return nil, err
+		}
+	case *ecies.Parameters:
+		ecdsaKey, err := ecdsaPrivateKeyBytes(privateKey)
+		if err != nil {
+			return nil, err
+		}
+		k, err = ecies.NewPrivateKey(secret(ecdsaKey), 0, p)
+		if err != nil {
+			return nil, err
+		}
+	case *rsassapkcs1.Parameters:
+		rsaKey, err := twoPrimeRSAKey(privateKey)
+		if err != nil {
+			return nil, err
+		}
+		publicKey, err := rsassapkcs1.NewPublicKey(rsaKey.N.Bytes(), 0, p)
+		if err != nil {
+			return nil, err
+		}
+		k, err = rsassapkcs1.NewPrivateKey(publicKey, rsassapkcs1.PrivateKeyValues{
+			P: secret(rsaKey.Primes[0].Bytes()),
+			Q: secret(rsaKey.Primes[1].Bytes()),
+			D: secret(rsaKey.D.Bytes()),
+		})
+		if err != nil {
+			return nil, err
+		}
+	case *rsassapss.Parameters:
+		rsaKey, err := twoPrimeRSAKey(privateKey)
+		if err != nil {
+			return nil, err
+		}
+		publicKey, err := rsassapss.NewPublicKey(rsaKey.N.Bytes(), 0, p)
+		if err != nil {
+			return nil, err
+		}
+		k, err = rsassapss.NewPrivateKey(publicKey, rsassapss.PrivateKeyValues{
+			P: secret(rsaKey.Primes[0].Bytes()),
+			Q: secret(rsaKey.Primes[1].Bytes()),
+			D: secret(rsaKey.D.Bytes()),
+		})
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported parameters type %T", parameters)
+	}
+	return keysetOf(k)
+}
+
+// publicKeyset returns a keyset holding the X.509 SubjectPublicKeyInfo encoded
+// public key as a key with parameters.
+func publicKeyset(parameters key.Parameters, publicKeyDER []byte) (*keyset.Handle, error) {
+	publicKey, err := x509.ParsePKIXPublicKey(publicKeyDER)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data key pair public key: %v", err)
+	}
+	var k key.Key
+	switch p := parameters.(type) {
+	case *tinkecdsa.Parameters:
+		point, err := ecdsaPublicPoint(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		k, err = tinkecdsa.NewPublicKey(point, 0, p)
+		if err != nil {
+			return nil, err
+		}
+	case *ecies.Parameters:
+		point, err := ecdsaPublicPoint(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		k, err = ecies.NewPublicKey(point, 0, p)
+		if err != nil {
+			return nil, err
+		}
+	case *rsassapkcs1.Parameters:
+		modulus, err := rsaModulus(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		k, err = rsassapkcs1.NewPublicKey(modulus, 0, p)
+		if err != nil {
+			return nil, err
+		}
+	case *rsassapss.Parameters:
+		modulus, err := rsaModulus(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		k, err = rsassapss.NewPublicKey(modulus, 0, p)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported parameters type %T", parameters)
+	}
+	return keysetOf(k)
+}
+
+// keysetOf returns a keyset with k as its only and primary key.
+func keysetOf(k key.Key) (*keyset.Handle, error) {
+	manager := keyset.NewManager()
+	keyID, err := manager.AddKey(k)
+	if err != nil {
+		return nil, err
+	}
+	if err := manager.SetPrimary(keyID); err != nil {
+		return nil, err
+	}
+	return manager.Handle()
+}
+
+// secret returns b as secret data.
+func secret(b []byte) secretdata.Bytes {
+	return secretdata.NewBytesFromData(b, insecuresecretdataaccess.Token{})
+}
+
+// twoPrimeRSAKey returns privateKey if it is an RSA key with two primes, as
+// generated by AWS KMS.
+func twoPrimeRSAKey(privateKey any) (*rsa.PrivateKey, error) {
+	rsaKey, ok := privateKey.(*rsa.PrivateKey)
+	if !ok || len(rsaKey.Primes) != 2 {
+		return nil, fmt.Errorf("data key pair private key is %T, want a two-prime RSA key", privateKey)
+	}
+	return rsaKey, nil
+}
+
+// ecdsaPrivateKeyBytes returns the fixed-size private scalar of an ECDSA
+// private key.
+func ecdsaPrivateKeyBytes(privateKey any) ([]byte, error) {
+	ecdsaKey, ok := privateKey.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("data key pair private key is %T, want an ECDSA key", privateKey)
+	}
+	ecdhKey, err := ecdsaKey.ECDH()
+	if err != nil {
+		return nil, err
+	}
+	return ecdhKey.Bytes(), nil
+}
+
+// ecdsaPublicPoint returns the uncompressed point of an ECDSA public key.
+func ecdsaPublicPoint(publicKey any) ([]byte, error) {
+	ecdsaKey, ok := publicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("data key pair public key is %T, want an ECDSA key", publicKey)
+	}
+	ecdhKey, err := ecdsaKey.ECDH()
+	if err != nil {
+		return nil, err
+	}
+	return ecdhKey.Bytes(), nil
+}
+
+func rsaModulus(publicKey any) ([]byte, error) {
+	rsaKey, ok := publicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("data key pair public key is %T, want an RSA key", publicKey)
+	}
+	if rsaKey.E != rsaPublicExponent {
+		return nil, fmt.Errorf("unsupported RSA public exponent %d, want %d", rsaKey.E, rsaPublicExponent)
+	}
+	return rsaKey.N.Bytes(), nil
+}
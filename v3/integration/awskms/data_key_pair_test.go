@@ -0,0 +1,280 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/tink-crypto/tink-go-awskms/v3/integration/awskms/internal/fakeawskms"
+	"github.com/tink-crypto/tink-go/v2/aead/aesgcm"
+	"github.com/tink-crypto/tink-go/v2/hybrid"
+	"github.com/tink-crypto/tink-go/v2/hybrid/ecies"
+	"github.com/tink-crypto/tink-go/v2/key"
+	"github.com/tink-crypto/tink-go/v2/keyset"
+	"github.com/tink-crypto/tink-go/v2/signature"
+	tinkecdsa "github.com/tink-crypto/tink-go/v2/signature/ecdsa"
+	"github.com/tink-crypto/tink-go/v2/signature/rsassapkcs1"
+	"github.com/tink-crypto/tink-go/v2/signature/rsassapss"
+)
+
+func mustParameters[P key.Parameters](p P, err error) P {
+	if err != nil {
+		panic(fmt.Sprintf("creating parameters failed: %v", err))
+	}
+	return p
+}
+
+func eciesParameters(t *testing.T, pointFormat ecies.PointFormat, variant ecies.Variant) *ecies.Parameters {
+	t.Helper()
+	dem := mustParameters(aesgcm.NewParameters(aesgcm.ParametersOpts{
+		KeySizeInBytes: 16,
+		IVSizeInBytes:  12,
+		TagSizeInBytes: 16,
+		Variant:        aesgcm.VariantNoPrefix,
+	}))
+	return mustParameters(ecies.NewParameters(ecies.ParametersOpts{
+		CurveType:            ecies.NISTP256,
+		HashType:             ecies.SHA256,
+		NISTCurvePointFormat: pointFormat,
+		DEMParameters:        dem,
+		Variant:              variant,
+	}))
+}
+
+// checkKeyPair checks that the private keyset handle matches the public
+// keyset publicHandle.
+func checkKeyPair(t *testing.T, handle, publicHandle *keyset.Handle) {
+	t.Helper()
+	message := []byte("message")
+	if signer, err := signature.NewSigner(handle); err == nil {
+		sig, err := signer.Sign(message)
+		if err != nil {
+			t.Fatalf("signer.Sign() err = %v, want nil", err)
+		}
+		verifier, err := signature.NewVerifier(publicHandle)
+		if err != nil {
+			t.Fatalf("signature.NewVerifier() err = %v, want nil", err)
+		}
+		if err := verifier.Verify(sig, message); err != nil {
+			t.Errorf("verifier.Verify() err = %v, want nil", err)
+		}
+		return
+	}
+	encrypter, err := hybrid.NewHybridEncrypt(publicHandle)
+	if err != nil {
+		t.Fatalf("hybrid.NewHybridEncrypt() err = %v, want nil", err)
+	}
+	ciphertext, err := encrypter.Encrypt(message, []byte("context"))
+	if err != nil {
+		t.Fatalf("encrypter.Encrypt() err = %v, want nil", err)
+	}
+	decrypter, err := hybrid.NewHybridDecrypt(handle)
+	if err != nil {
+		t.Fatalf("hybrid.NewHybridDecrypt() err = %v, want nil", err)
+	}
+	if got, err := decrypter.Decrypt(ciphertext, []byte("context")); err != nil || !bytes.Equal(got, message) {
+		t.Errorf("decrypter.Decrypt() = %q, %v, want %q, nil", got, err, message)
+	}
+}
+
+func TestGenerateDataKeyPair(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	client := newTestClient(t, WithKMS(fakekms))
+	associatedData := []byte("device-1")
+
+	for _, test := range []struct {
+		name       string
+		parameters key.Parameters
+		wantSpec   types.DataKeyPairSpec
+	}{
+		{
+			name:       "ECDSA P-256",
+			parameters: mustParameters(tinkecdsa.NewParameters(tinkecdsa.NistP256, tinkecdsa.SHA256, tinkecdsa.DER, tinkecdsa.VariantNoPrefix)),
+			wantSpec:   types.DataKeyPairSpecEccNistP256,
+		},
+		{
+			name:       "ECDSA P-384",
+			parameters: mustParameters(tinkecdsa.NewParameters(tinkecdsa.NistP384, tinkecdsa.SHA384, tinkecdsa.IEEEP1363, tinkecdsa.VariantNoPrefix)),
+			wantSpec:   types.DataKeyPairSpecEccNistP384,
+		},
+		{
+			name:       "RSA-SSA-PKCS1",
+			parameters: mustParameters(rsassapkcs1.NewParameters(2048, rsassapkcs1.SHA256, 65537, rsassapkcs1.VariantNoPrefix)),
+			wantSpec:   types.DataKeyPairSpecRsa2048,
+		},
+		{
+			name: "RSA-SSA-PSS",
+			parameters: mustParameters(rsassapss.NewParameters(rsassapss.ParametersValues{
+				ModulusSizeBits: 2048,
+				SigHashType:     rsassapss.SHA256,
+				MGF1HashType:    rsassapss.SHA256,
+				PublicExponent:  65537,
+				SaltLengthBytes: 32,
+			}, rsassapss.VariantNoPrefix)),
+			wantSpec: types.DataKeyPairSpecRsa2048,
+		},
+		{
+			name:       "ECIES uncompressed",
+			parameters: eciesParameters(t, ecies.UncompressedPointFormat, ecies.VariantNoPrefix),
+			wantSpec:   types.DataKeyPairSpecEccNistP256,
+		},
+		{
+			name:       "ECIES compressed",
+			parameters: eciesParameters(t, ecies.CompressedPointFormat, ecies.VariantNoPrefix),
+			wantSpec:   types.DataKeyPairSpecEccNistP256,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			pair, err := GenerateDataKeyPair(t.Context(), client, sourceKeyURI, test.parameters, associatedData)
+			if err != nil {
+				t.Fatalf("GenerateDataKeyPair() err = %v, want nil", err)
+			}
+			if pair.KeyPairSpec != test.wantSpec || pair.KeyARN != sourceKeyARN {
+				t.Errorf("pair.KeyPairSpec, pair.KeyARN = %s, %q, want %s, %q", pair.KeyPairSpec, pair.KeyARN, test.wantSpec, sourceKeyARN)
+			}
+			checkKeyPair(t, pair.Handle, pair.PublicHandle)
+
+			loaded, err := LoadDataKeyPair(t.Context(), client, sourceKeyURI, test.parameters, pair.EncryptedPrivateKey, associatedData)
+			if err != nil {
+				t.Fatalf("LoadDataKeyPair() err = %v, want nil", err)
+			}
+			checkKeyPair(t, loaded, pair.PublicHandle)
+
+			withoutPlaintext, err := GenerateDataKeyPairWithoutPlaintext(t.Context(), client, sourceKeyURI, test.parameters, associatedData)
+			if err != nil {
+				t.Fatalf("GenerateDataKeyPairWithoutPlaintext() err = %v, want nil", err)
+			}
+			if withoutPlaintext.Handle != nil {
+				t.Error("withoutPlaintext.Handle != nil, want nil")
+			}
+			loaded, err = LoadDataKeyPair(t.Context(), client, sourceKeyURI, test.parameters, withoutPlaintext.EncryptedPrivateKey, associatedData)
+			if err != nil {
+				t.Fatalf("LoadDataKeyPair() err = %v, want nil", err)
+			}
+			checkKeyPair(t, loaded, withoutPlaintext.PublicHandle)
+
+			if _, err := LoadDataKeyPair(t.Context(), client, sourceKeyURI, test.parameters, pair.EncryptedPrivateKey, []byte("device-2")); err == nil {
+				t.Error("LoadDataKeyPair() with wrong associated data err = nil, want error")
+			}
+		})
+	}
+}
+
+func TestGenerateDataKeyPair_dryRun(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	client := newTestClient(t, WithKMS(fakekms))
+	p256 := mustParameters(tinkecdsa.NewParameters(tinkecdsa.NistP256, tinkecdsa.SHA256, tinkecdsa.DER, tinkecdsa.VariantNoPrefix))
+	pair, err := GenerateDataKeyPair(t.Context(), client, sourceKeyURI, p256, nil)
+	if err != nil {
+		t.Fatalf("GenerateDataKeyPair() err = %v, want nil", err)
+	}
+
+	for _, test := range []struct {
+		operation string
+		run       func(ctx context.Context, c Client) error
+	}{
+		{"GenerateDataKeyPair", func(ctx context.Context, c Client) error {
+			_, err := GenerateDataKeyPair(ctx, c, sourceKeyURI, p256, nil)
+			return err
+		}},
+		{"GenerateDataKeyPairWithoutPlaintext", func(ctx context.Context, c Client) error {
+			_, err := GenerateDataKeyPairWithoutPlaintext(ctx, c, sourceKeyURI, p256, nil)
+			return err
+		}},
+		{"Decrypt", func(ctx context.Context, c Client) error {
+			_, err := LoadDataKeyPair(ctx, c, sourceKeyURI, p256, pair.EncryptedPrivateKey, nil)
+			return err
+		}},
+	} {
+		t.Run(test.operation, func(t *testing.T) {
+			details := &OperationDetails{}
+			ctx := ContextWithOperationDetails(ContextWithDryRun(t.Context()), details)
+			if err := test.run(ctx, client); !errors.Is(err, ErrDryRunSucceeded) {
+				t.Errorf("err = %v, want %v", err, ErrDryRunSucceeded)
+			}
+			if want := (DryRunResult{Operation: test.operation, KeyID: sourceKeyARN}); details.DryRun == nil || *details.DryRun != want {
+				t.Errorf("details.DryRun = %+v, want %+v", details.DryRun, want)
+			}
+			withDryRun := newTestClient(t, WithKMS(fakekms), WithDryRun())
+			if err := test.run(t.Context(), withDryRun); !errors.Is(err, ErrDryRunSucceeded) {
+				t.Errorf("with WithDryRun err = %v, want %v", err, ErrDryRunSucceeded)
+			}
+		})
+	}
+}
+
+func TestGenerateDataKeyPair_fails(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	client := newTestClient(t, WithKMS(fakekms))
+	p256 := mustParameters(tinkecdsa.NewParameters(tinkecdsa.NistP256, tinkecdsa.SHA256, tinkecdsa.DER, tinkecdsa.VariantNoPrefix))
+
+	for _, test := range []struct {
+		name       string
+		parameters key.Parameters
+	}{
+		{"nil", nil},
+		{"key ID requirement", mustParameters(tinkecdsa.NewParameters(tinkecdsa.NistP256, tinkecdsa.SHA256, tinkecdsa.DER, tinkecdsa.VariantTink))},
+		{"X25519", mustParameters(ecies.NewParameters(ecies.ParametersOpts{
+			CurveType:     ecies.X25519,
+			HashType:      ecies.SHA256,
+			DEMParameters: eciesParameters(t, ecies.UncompressedPointFormat, ecies.VariantNoPrefix).DEMParameters(),
+			Variant:       ecies.VariantNoPrefix,
+		}))},
+		{"RSA exponent", mustParameters(rsassapkcs1.NewParameters(2048, rsassapkcs1.SHA256, 65539, rsassapkcs1.VariantNoPrefix))},
+		{"RSA modulus size", mustParameters(rsassapkcs1.NewParameters(2560, rsassapkcs1.SHA256, 65537, rsassapkcs1.VariantNoPrefix))},
+		{"AEAD", mustParameters(aesgcm.NewParameters(aesgcm.ParametersOpts{KeySizeInBytes: 16, IVSizeInBytes: 12, TagSizeInBytes: 16, Variant: aesgcm.VariantNoPrefix}))},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := GenerateDataKeyPair(t.Context(), client, sourceKeyURI, test.parameters, nil); err == nil {
+				t.Error("GenerateDataKeyPair() err = nil, want error")
+			}
+		})
+	}
+
+	if _, err := GenerateDataKeyPair(t.Context(), client, "gcp-kms://key", p256, nil); err == nil {
+		t.Error("GenerateDataKeyPair() with unsupported key URI err = nil, want error")
+	}
+	unsupported := newTestClient(t, WithKMS(kmsAPIOnly{fakekms}))
+	if _, err := GenerateDataKeyPair(t.Context(), unsupported, sourceKeyURI, p256, nil); !errors.Is(err, errGenerateDataKeyPairUnsupported) {
+		t.Errorf("GenerateDataKeyPair() err = %v, want %v", err, errGenerateDataKeyPairUnsupported)
+	}
+	if _, err := GenerateDataKeyPairWithoutPlaintext(t.Context(), unsupported, sourceKeyURI, p256, nil); !errors.Is(err, errGenerateDataKeyPairUnsupported) {
+		t.Errorf("GenerateDataKeyPairWithoutPlaintext() err = %v, want %v", err, errGenerateDataKeyPairUnsupported)
+	}
+
+	// A key pair cannot be loaded with parameters of another type.
+	pair, err := GenerateDataKeyPair(t.Context(), client, sourceKeyURI, p256, nil)
+	if err != nil {
+		t.Fatalf("GenerateDataKeyPair() err = %v, want nil", err)
+	}
+	rsaParameters := mustParameters(rsassapkcs1.NewParameters(2048, rsassapkcs1.SHA256, 65537, rsassapkcs1.VariantNoPrefix))
+	if _, err := LoadDataKeyPair(t.Context(), client, sourceKeyURI, rsaParameters, pair.EncryptedPrivateKey, nil); err == nil {
+		t.Error("LoadDataKeyPair() with RSA parameters of ECDSA key pair err = nil, want error")
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+// WithDecryptFallback makes AEAD primitives retry decryption with other
+// encryption context conventions if AWS KMS rejects a ciphertext with an
+// InvalidCiphertextException.
+//
+// Decryption is first attempted with the configured encoder, see
+// [WithEncryptionContextEncoder], and then with each of fallbacks in order. If
+// no fallbacks are given, the other [EncryptionContextName] values are tried.
+// Encryption always uses the configured encoder. Use
+// [ContextWithOperationDetails] to learn which encoder succeeded.
+//
+// Fallbacks only apply to non-empty associated data, since empty associated
+// data is never sent as an encryption context. Each fallback costs an
+// additional AWS KMS request, so fallbacks equivalent to the configured
+// encoder or to an earlier fallback are skipped.
+func WithDecryptFallback(fallbacks ...EncryptionContextEncoder) ClientOption {
+	return option(func(ctx context.Context, a *awsClient) error {
+		if a.decryptFallbacks != nil {
+			return errors.New("WithDecryptFallback option cannot be used, decrypt fallbacks already set")
+		}
+		for _, f := range fallbacks {
+			if f == nil {
+				return errors.New("decrypt fallback must not be nil")
+			}
+		}
+		a.decryptFallbacks = append([]EncryptionContextEncoder{}, dedupeEncoders(fallbacks)...)
+		return nil
+	})
+}
+
+// WithEncryptionContextMigration facilitates migrating from
+// [LegacyAdditionalData] to [AssociatedData].
+//
+// Encryption uses [AssociatedData], while decryption accepts ciphertexts
+// written with either name. It is equivalent to using
+// [WithEncryptionContextName] with [AssociatedData] and [WithDecryptFallback]
+// with [LegacyAdditionalData].
+func WithEncryptionContextMigration() ClientOption {
+	return option(func(ctx context.Context, a *awsClient) error {
+		if err := WithEncryptionContextName(AssociatedData).set(ctx, a); err != nil {
+			return err
+		}
+		return WithDecryptFallback(LegacyAdditionalData).set(ctx, a)
+	})
+}
+
+// defaultDecryptFallbacks returns the EncryptionContextName values other than
+// encoder.
+func defaultDecryptFallbacks(encoder EncryptionContextEncoder) []EncryptionContextEncoder {
+	var fallbacks []EncryptionContextEncoder
+	for _, n := range []EncryptionContextName{AssociatedData, LegacyAdditionalData} {
+		if encoderKey(n) != encoderKey(encoder) {
+			fallbacks = append(fallbacks, n)
+		}
+	}
+	return fallbacks
+}
+
+// encoderKey returns a key identifying the encryption contexts produced by e,
+// so that equivalent encoders, such as [AssociatedData] and
+// HexEncoder("associatedData"), have the same key.
+func encoderKey(e EncryptionContextEncoder) string {
+	if n, ok := e.(EncryptionContextName); ok && n.valid() {
+		return HexEncoder(n.String()).String()
+	}
+	return e.String()
+}
+
+// dedupeEncoders returns encoders without the encoders equivalent to an
+// earlier one.
+func dedupeEncoders(encoders []EncryptionContextEncoder) []EncryptionContextEncoder {
+	seen := make(map[string]bool)
+	var deduped []EncryptionContextEncoder
+	for _, e := range encoders {
+		if k := encoderKey(e); !seen[k] {
+			seen[k] = true
+			deduped = append(deduped, e)
+		}
+	}
+	return deduped
+}
+
+// withoutEncoder returns fallbacks without the ones equivalent to encoder. It
+// returns nil, disabling fallbacks, if none remain.
+func withoutEncoder(fallbacks []EncryptionContextEncoder, encoder EncryptionContextEncoder) []EncryptionContextEncoder {
+	var remaining []EncryptionContextEncoder
+	for _, f := range fallbacks {
+		if encoderKey(f) != encoderKey(encoder) {
+			remaining = append(remaining, f)
+		}
+	}
+	return remaining
+}
+
+// decryptEncoders returns the encoders decryption is attempted with: encoder,
+// followed by fallbacks if decrypt fallbacks are enabled.
+func decryptEncoders(encoder EncryptionContextEncoder, fallbacks []EncryptionContextEncoder) []EncryptionContextEncoder {
+	encoders := []EncryptionContextEncoder{encoder}
+	if fallbacks == nil {
+		return encoders
+	}
+	if len(fallbacks) == 0 {
+		fallbacks = defaultDecryptFallbacks(encoder)
+	}
+	return append(encoders, fallbacks...)
+}
+
+func isInvalidCiphertext(err error) bool {
+	var e *types.InvalidCiphertextException
+	return errors.As(err, &e)
+}
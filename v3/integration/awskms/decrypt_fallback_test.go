@@ -0,0 +1,178 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/tink-crypto/tink-go-awskms/v3/integration/awskms/internal/fakeawskms"
+)
+
+func TestWithDecryptFallback(t *testing.T) {
+	keyARN := "arn:aws:kms:us-east-2:235739564943:key/3ee50705-5a82-4f5b-9753-05c4f473922f"
+	fakekms, err := fakeawskms.New([]string{keyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	plaintext := []byte("plaintext")
+	associatedData := []byte("associatedData")
+
+	legacy := getAEAD(t, newTestClient(t, WithKMS(fakekms), WithEncryptionContextName(LegacyAdditionalData)), sourceKeyURI)
+	legacyCiphertext, err := legacy.EncryptWithContext(t.Context(), plaintext, associatedData)
+	if err != nil {
+		t.Fatalf("legacy.EncryptWithContext() err = %v, want nil", err)
+	}
+	base64AEAD := getAEAD(t, newTestClient(t, WithKMS(fakekms), WithEncryptionContextEncoder(Base64Encoder("aad"))), sourceKeyURI)
+	base64Ciphertext, err := base64AEAD.EncryptWithContext(t.Context(), plaintext, associatedData)
+	if err != nil {
+		t.Fatalf("base64AEAD.EncryptWithContext() err = %v, want nil", err)
+	}
+
+	tests := []struct {
+		name        string
+		opts        []ClientOption
+		ciphertext  []byte
+		wantEncoder string
+	}{
+		{
+			name:        "default fallbacks",
+			opts:        []ClientOption{WithDecryptFallback()},
+			ciphertext:  legacyCiphertext,
+			wantEncoder: LegacyAdditionalData.String(),
+		},
+		{
+			name:        "migration",
+			opts:        []ClientOption{WithEncryptionContextMigration()},
+			ciphertext:  legacyCiphertext,
+			wantEncoder: LegacyAdditionalData.String(),
+		},
+		{
+			name:        "custom fallback",
+			opts:        []ClientOption{WithDecryptFallback(LegacyAdditionalData, Base64Encoder("aad"))},
+			ciphertext:  base64Ciphertext,
+			wantEncoder: "base64(aad)",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a := getAEAD(t, newTestClient(t, append([]ClientOption{WithKMS(fakekms)}, test.opts...)...), sourceKeyURI)
+			details := &OperationDetails{}
+			got, err := a.DecryptWithContext(ContextWithOperationDetails(t.Context(), details), test.ciphertext, associatedData)
+			if err != nil {
+				t.Fatalf("a.DecryptWithContext() err = %v, want nil", err)
+			}
+			if !bytes.Equal(got, plaintext) {
+				t.Errorf("a.DecryptWithContext() = %q, want %q", got, plaintext)
+			}
+			if details.Encoder == nil || details.Encoder.String() != test.wantEncoder {
+				t.Errorf("details.Encoder = %v, want %v", details.Encoder, test.wantEncoder)
+			}
+
+			// Encryption always uses the configured encoder.
+			newDetails := &OperationDetails{}
+			newCiphertext, err := a.EncryptWithContext(ContextWithOperationDetails(t.Context(), newDetails), plaintext, associatedData)
+			if err != nil {
+				t.Fatalf("a.EncryptWithContext() err = %v, want nil", err)
+			}
+			if newDetails.Encoder != AssociatedData {
+				t.Errorf("newDetails.Encoder = %v, want %v", newDetails.Encoder, AssociatedData)
+			}
+			if _, err := legacy.DecryptWithContext(t.Context(), newCiphertext, associatedData); err == nil {
+				t.Error("legacy.DecryptWithContext(newCiphertext) err = nil, want error")
+			}
+		})
+	}
+}
+
+func TestWithDecryptFallback_invalidAssociatedDataFails(t *testing.T) {
+	keyARN := "arn:aws:kms:us-east-2:235739564943:key/3ee50705-5a82-4f5b-9753-05c4f473922f"
+	fakekms, err := fakeawskms.New([]string{keyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	a := getAEAD(t, newTestClient(t, WithKMS(fakekms), WithDecryptFallback()), sourceKeyURI)
+	ciphertext, err := a.EncryptWithContext(t.Context(), []byte("plaintext"), []byte("associatedData"))
+	if err != nil {
+		t.Fatalf("a.EncryptWithContext() err = %v, want nil", err)
+	}
+	if _, err := a.DecryptWithContext(t.Context(), ciphertext, []byte("invalidAssociatedData")); !isInvalidCiphertext(err) {
+		t.Errorf("a.DecryptWithContext() err = %v, want InvalidCiphertextException", err)
+	}
+}
+
+func TestWithoutDecryptFallback_doesNotRetry(t *testing.T) {
+	keyARN := "arn:aws:kms:us-east-2:235739564943:key/3ee50705-5a82-4f5b-9753-05c4f473922f"
+	fakekms, err := fakeawskms.New([]string{keyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	legacy := getAEAD(t, newTestClient(t, WithKMS(fakekms), WithEncryptionContextName(LegacyAdditionalData)), sourceKeyURI)
+	ciphertext, err := legacy.EncryptWithContext(t.Context(), []byte("plaintext"), []byte("associatedData"))
+	if err != nil {
+		t.Fatalf("legacy.EncryptWithContext() err = %v, want nil", err)
+	}
+	metrics := &fakeMetrics{}
+	a := getAEAD(t, newTestClient(t, WithKMS(fakekms), WithMetrics(metrics)), sourceKeyURI)
+	if _, err := a.DecryptWithContext(t.Context(), ciphertext, []byte("associatedData")); err == nil {
+		t.Error("a.DecryptWithContext() err = nil, want error")
+	}
+	if len(metrics.calls) != 1 {
+		t.Errorf("len(metrics.calls) = %d, want 1", len(metrics.calls))
+	}
+}
+
+func TestWithDecryptFallback_skipsEquivalentEncoders(t *testing.T) {
+	keyARN := "arn:aws:kms:us-east-2:235739564943:key/3ee50705-5a82-4f5b-9753-05c4f473922f"
+	fakekms, err := fakeawskms.New([]string{keyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	other := getAEAD(t, newTestClient(t, WithKMS(fakekms), WithEncryptionContextEncoder(Base64Encoder("aad"))), sourceKeyURI)
+	ciphertext, err := other.EncryptWithContext(t.Context(), []byte("plaintext"), []byte("associatedData"))
+	if err != nil {
+		t.Fatalf("other.EncryptWithContext() err = %v, want nil", err)
+	}
+	for _, test := range []struct {
+		name      string
+		opts      []ClientOption
+		wantCalls int
+	}{
+		{"fallback equivalent to encoder", []ClientOption{WithDecryptFallback(HexEncoder("associatedData"))}, 1},
+		{"encoder equivalent to fallback", []ClientOption{WithEncryptionContextEncoder(HexEncoder("additionalData")), WithDecryptFallback(LegacyAdditionalData)}, 1},
+		{"default fallbacks", []ClientOption{WithEncryptionContextEncoder(HexEncoder("associatedData")), WithDecryptFallback()}, 2},
+		{"repeated fallbacks", []ClientOption{WithDecryptFallback(LegacyAdditionalData, HexEncoder("additionalData"), LegacyAdditionalData)}, 2},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			metrics := &fakeMetrics{}
+			a := getAEAD(t, newTestClient(t, append([]ClientOption{WithKMS(fakekms), WithMetrics(metrics)}, test.opts...)...), sourceKeyURI)
+			if _, err := a.DecryptWithContext(t.Context(), ciphertext, []byte("associatedData")); err == nil {
+				t.Error("a.DecryptWithContext() err = nil, want error")
+			}
+			if got := countCalls(metrics, "Decrypt"); got != test.wantCalls {
+				t.Errorf("Decrypt calls = %d, want %d", got, test.wantCalls)
+			}
+		})
+	}
+}
+
+func TestWithDecryptFallback_repeatedFails(t *testing.T) {
+	if _, err := NewClientWithOptions(t.Context(), "aws-kms://", WithDecryptFallback(), WithDecryptFallback()); err == nil {
+		t.Error("NewClientWithOptions(t.Context(), _, WithDecryptFallback(), WithDecryptFallback()) err = nil, want error")
+	}
+	if _, err := NewClientWithOptions(t.Context(), "aws-kms://", WithEncryptionContextName(LegacyAdditionalData), WithEncryptionContextMigration()); err == nil {
+		t.Error("NewClientWithOptions(t.Context(), _, WithEncryptionContextName(_), WithEncryptionContextMigration()) err = nil, want error")
+	}
+}
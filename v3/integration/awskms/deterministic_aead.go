@@ -0,0 +1,319 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/tink-crypto/tink-go/v2/daead"
+	"github.com/tink-crypto/tink-go/v2/keyset"
+	"github.com/tink-crypto/tink-go/v2/tink"
+)
+
+// deterministicAEADCacheName is the name of the deterministic AEAD cache
+// reported to [Metrics.RecordCacheLookup].
+const deterministicAEADCacheName = "deterministic_aead"
+
+// defaultDeterministicAEADCacheSize is the number of decrypted keysets a
+// client caches unless [WithDeterministicAEADCacheSize] is used.
+const defaultDeterministicAEADCacheSize = 1024
+
+// WithDeterministicAEADCacheSize sets the maximum number of decrypted keysets
+// the client caches for [LoadDeterministicAEAD]. When the cache is full, the
+// least recently used keyset is evicted. A size of 0 disables the cache, so
+// that every load calls AWS KMS. The default is 1024.
+func WithDeterministicAEADCacheSize(size int) ClientOption {
+	return option(func(ctx context.Context, a *awsClient) error {
+		if size < 0 {
+			return fmt.Errorf("deterministic AEAD cache size must not be negative, got %d", size)
+		}
+		if a.deterministicAEADCacheSizeSet {
+			return errors.New("WithDeterministicAEADCacheSize option cannot be used, cache size already set")
+		}
+		a.deterministicAEADCacheSize = size
+		a.deterministicAEADCacheSizeSet = true
+		return nil
+	})
+}
+
+// DeterministicAEADOption is an interface for defining options that are
+// passed to [LoadDeterministicAEAD].
+type DeterministicAEADOption interface {
+	set(o *deterministicAEADOptions) error
+}
+
+type deterministicAEADOptions struct {
+	rewrapKeyURI string
+}
+
+type deterministicAEADOption func(o *deterministicAEADOptions) error
+
+func (f deterministicAEADOption) set(o *deterministicAEADOptions) error { return f(o) }
+
+// WithRewrapKeyURI makes [LoadDeterministicAEAD] rewrap the keyset with
+// keyURI if it is encrypted with another key, or with another encryption
+// context encoder than the one of the client. This migrates keysets to a new
+// AWS KMS key as they are loaded, see [DeterministicAEAD.Rewrapped].
+func WithRewrapKeyURI(keyURI string) DeterministicAEADOption {
+	return deterministicAEADOption(func(o *deterministicAEADOptions) error {
+		if keyURI == "" {
+			return errors.New("rewrap key URI must not be empty")
+		}
+		if o.rewrapKeyURI != "" {
+			return errors.New("WithRewrapKeyURI option cannot be used, rewrap key URI already set")
+		}
+		o.rewrapKeyURI = keyURI
+		return nil
+	})
+}
+
+// DeterministicAEAD is a tink.DeterministicAEAD using an AES-SIV keyset which
+// is encrypted with an AWS KMS key, for example to support equality search
+// over encrypted columns. It is safe for concurrent use.
+//
+// The keyset is stored as an encrypted keyset document, see
+// [MarshalEncryptedKeyset], and only decrypted by AWS KMS when it is loaded.
+type DeterministicAEAD struct {
+	tink.DeterministicAEAD
+
+	client         *awsClient
+	handle         *keyset.Handle
+	associatedData []byte
+
+	mu        sync.Mutex
+	keyURI    string
+	data      []byte
+	rewrapped bool
+}
+
+// deterministicAEADEntry is a keyset cached by the client.
+type deterministicAEADEntry struct {
+	handle    *keyset.Handle
+	primitive tink.DeterministicAEAD
+}
+
+// deterministicAEADCacheElement is an element of the LRU list of the cache.
+type deterministicAEADCacheElement struct {
+	key   [sha256.Size]byte
+	entry *deterministicAEADEntry
+}
+
+// NewDeterministicAEAD generates an AES-256-SIV keyset, encrypts it with the
+// AWS KMS key keyURI and associatedData, and returns its primitive together
+// with the encrypted keyset document, which is to be stored and later passed
+// to [LoadDeterministicAEAD].
+func NewDeterministicAEAD(ctx context.Context, client Client, keyURI string, associatedData []byte) (*DeterministicAEAD, []byte, error) {
+	c, ok := client.(*awsClient)
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported client type %T", client)
+	}
+	handle, data, err := NewEncryptedKeyset(ctx, client, keyURI, daead.AESSIVKeyTemplate(), associatedData)
+	if err != nil {
+		return nil, nil, err
+	}
+	primitive, err := daead.New(handle)
+	if err != nil {
+		return nil, nil, err
+	}
+	d := &DeterministicAEAD{
+		DeterministicAEAD: primitive,
+		client:            c,
+		handle:            handle,
+		associatedData:    bytes.Clone(associatedData),
+		keyURI:            keyURI,
+		data:              data,
+	}
+	c.cacheDeterministicAEAD(data, associatedData, &deterministicAEADEntry{handle: handle, primitive: primitive})
+	return d, data, nil
+}
+
+// LoadDeterministicAEAD returns the primitive of an encrypted keyset document
+// written by [NewDeterministicAEAD] or [DeterministicAEAD.Rewrap].
+//
+// Like [UnmarshalEncryptedKeyset], the document must be encrypted with the AWS
+// KMS key keyURI, or with the key given by [WithRewrapKeyURI], which allows
+// loading documents which have already been migrated.
+//
+// The decrypted keyset is cached by client, so that loading the same document
+// with the same associated data again does not call AWS KMS, see
+// [WithDeterministicAEADCacheSize]. The keyset must be a deterministic AEAD
+// keyset.
+func LoadDeterministicAEAD(ctx context.Context, client Client, keyURI string, data, associatedData []byte, opts ...DeterministicAEADOption) (*DeterministicAEAD, error) {
+	c, ok := client.(*awsClient)
+	if !ok {
+		return nil, fmt.Errorf("unsupported client type %T", client)
+	}
+	o := &deterministicAEADOptions{}
+	for _, opt := range opts {
+		if err := opt.set(o); err != nil {
+			return nil, err
+		}
+	}
+	doc, err := parseEncryptedKeysetDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	if doc.KeyURI != keyURI && (o.rewrapKeyURI == "" || doc.KeyURI != o.rewrapKeyURI) {
+		return nil, fmt.Errorf("encrypted keyset is encrypted with key URI %q, want %q", doc.KeyURI, keyURI)
+	}
+	entry, err := c.loadDeterministicAEAD(ctx, doc, data, associatedData)
+	if err != nil {
+		return nil, err
+	}
+	d := &DeterministicAEAD{
+		DeterministicAEAD: entry.primitive,
+		client:            c,
+		handle:            entry.handle,
+		associatedData:    bytes.Clone(associatedData),
+		keyURI:            doc.KeyURI,
+		data:              bytes.Clone(data),
+	}
+	if o.rewrapKeyURI != "" && (doc.KeyURI != o.rewrapKeyURI || doc.EncryptionContextName != c.encoder.String()) {
+		if _, err := d.Rewrap(ctx, o.rewrapKeyURI); err != nil {
+			return nil, fmt.Errorf("rewrapping keyset with %s failed: %w", o.rewrapKeyURI, err)
+		}
+	}
+	return d, nil
+}
+
+// Rewrap encrypts the keyset with the AWS KMS key keyURI, using the
+// encryption context encoder of the client and the associated data the keyset
+// was loaded with, and returns the new encrypted keyset document. The keys
+// are unchanged, so existing ciphertexts remain valid.
+func (d *DeterministicAEAD) Rewrap(ctx context.Context, keyURI string) ([]byte, error) {
+	data, err := MarshalEncryptedKeyset(ctx, d.client, keyURI, d.handle, d.associatedData)
+	if err != nil {
+		return nil, err
+	}
+	d.client.cacheDeterministicAEAD(data, d.associatedData, &deterministicAEADEntry{handle: d.handle, primitive: d.DeterministicAEAD})
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.keyURI = keyURI
+	d.data = data
+	d.rewrapped = true
+	return bytes.Clone(data), nil
+}
+
+// EncryptedKeyset returns the current encrypted keyset document.
+func (d *DeterministicAEAD) EncryptedKeyset() []byte {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return bytes.Clone(d.data)
+}
+
+// KeyURI returns the URI of the AWS KMS key which encrypts the current
+// keyset document.
+func (d *DeterministicAEAD) KeyURI() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.keyURI
+}
+
+// Rewrapped reports whether the keyset was rewrapped since it was loaded, in
+// which case [DeterministicAEAD.EncryptedKeyset] should replace the stored
+// document.
+func (d *DeterministicAEAD) Rewrapped() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.rewrapped
+}
+
+// deterministicAEADCacheKey returns the cache key of an encrypted keyset
+// document and associated data. Both are included, so that a keyset is only
+// served from the cache for the associated data AWS KMS verified.
+func deterministicAEADCacheKey(data, associatedData []byte) [sha256.Size]byte {
+	h := sha256.New()
+	h.Write(binary.BigEndian.AppendUint64(nil, uint64(len(data))))
+	h.Write(data)
+	h.Write(associatedData)
+	var key [sha256.Size]byte
+	h.Sum(key[:0])
+	return key
+}
+
+// loadDeterministicAEAD returns the deterministic AEAD of the encrypted keyset
+// document doc parsed from data, from the cache if possible.
+func (c *awsClient) loadDeterministicAEAD(ctx context.Context, doc *encryptedKeysetDocument, data, associatedData []byte) (*deterministicAEADEntry, error) {
+	entry, ok := c.cachedDeterministicAEAD(deterministicAEADCacheKey(data, associatedData))
+	if c.metrics != nil {
+		c.metrics.RecordCacheLookup(ctx, deterministicAEADCacheName, strings.TrimPrefix(doc.KeyURI, awsPrefix), ok)
+	}
+	if ok {
+		return entry, nil
+	}
+
+	handle, err := c.decryptEncryptedKeyset(ctx, doc, associatedData)
+	if err != nil {
+		return nil, err
+	}
+	primitive, err := daead.New(handle)
+	if err != nil {
+		return nil, err
+	}
+	entry = &deterministicAEADEntry{handle: handle, primitive: primitive}
+	c.cacheDeterministicAEAD(data, associatedData, entry)
+	return entry, nil
+}
+
+// cachedDeterministicAEAD returns the cached entry of key, and marks it as
+// recently used.
+func (c *awsClient) cachedDeterministicAEAD(key [sha256.Size]byte) (*deterministicAEADEntry, bool) {
+	c.deterministicAEADMu.Lock()
+	defer c.deterministicAEADMu.Unlock()
+	e, ok := c.deterministicAEADCache[key]
+	if !ok {
+		return nil, false
+	}
+	c.deterministicAEADLRU.MoveToFront(e)
+	return e.Value.(*deterministicAEADCacheElement).entry, true
+}
+
+// cacheDeterministicAEAD caches entry, evicting the least recently used
+// entries if the cache is full.
+func (c *awsClient) cacheDeterministicAEAD(data, associatedData []byte, entry *deterministicAEADEntry) {
+	size := defaultDeterministicAEADCacheSize
+	if c.deterministicAEADCacheSizeSet {
+		size = c.deterministicAEADCacheSize
+	}
+	if size == 0 {
+		return
+	}
+	key := deterministicAEADCacheKey(data, associatedData)
+	c.deterministicAEADMu.Lock()
+	defer c.deterministicAEADMu.Unlock()
+	if c.deterministicAEADCache == nil {
+		c.deterministicAEADCache = make(map[[sha256.Size]byte]*list.Element)
+		c.deterministicAEADLRU = list.New()
+	}
+	if e, ok := c.deterministicAEADCache[key]; ok {
+		e.Value.(*deterministicAEADCacheElement).entry = entry
+		c.deterministicAEADLRU.MoveToFront(e)
+		return
+	}
+	c.deterministicAEADCache[key] = c.deterministicAEADLRU.PushFront(&deterministicAEADCacheElement{key: key, entry: entry})
+	for c.deterministicAEADLRU.Len() > size {
+		oldest := c.deterministicAEADLRU.Back()
+		c.deterministicAEADLRU.Remove(oldest)
+		delete(c.deterministicAEADCache, oldest.Value.(*deterministicAEADCacheElement).key)
+	}
+}
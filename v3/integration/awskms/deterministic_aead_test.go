@@ -0,0 +1,263 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/tink-crypto/tink-go-awskms/v3/integration/awskms/internal/fakeawskms"
+	"github.com/tink-crypto/tink-go/v2/aead"
+)
+
+func countCalls(metrics *fakeMetrics, operation string) int {
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	var n int
+	for _, call := range metrics.calls {
+		if call.Operation == operation {
+			n++
+		}
+	}
+	return n
+}
+
+func TestDeterministicAEAD(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	metrics := &fakeMetrics{}
+	client := newTestClient(t, WithKMS(fakekms), WithMetrics(metrics))
+	associatedData := []byte("column")
+	plaintext := []byte("alice@example.com")
+
+	d, data, err := NewDeterministicAEAD(t.Context(), client, sourceKeyURI, associatedData)
+	if err != nil {
+		t.Fatalf("NewDeterministicAEAD() err = %v, want nil", err)
+	}
+	if got := d.KeyURI(); got != sourceKeyURI {
+		t.Errorf("d.KeyURI() = %q, want %q", got, sourceKeyURI)
+	}
+	if !bytes.Equal(d.EncryptedKeyset(), data) {
+		t.Error("d.EncryptedKeyset() != data")
+	}
+	ciphertext, err := d.EncryptDeterministically(plaintext, nil)
+	if err != nil {
+		t.Fatalf("d.EncryptDeterministically() err = %v, want nil", err)
+	}
+	again, err := d.EncryptDeterministically(plaintext, nil)
+	if err != nil {
+		t.Fatalf("d.EncryptDeterministically() err = %v, want nil", err)
+	}
+	if !bytes.Equal(ciphertext, again) {
+		t.Error("d.EncryptDeterministically() is not deterministic")
+	}
+
+	// A new client has to decrypt the keyset with AWS KMS, once.
+	metrics = &fakeMetrics{}
+	client = newTestClient(t, WithKMS(fakekms), WithMetrics(metrics))
+	for range 3 {
+		loaded, err := LoadDeterministicAEAD(t.Context(), client, sourceKeyURI, data, associatedData)
+		if err != nil {
+			t.Fatalf("LoadDeterministicAEAD() err = %v, want nil", err)
+		}
+		got, err := loaded.DecryptDeterministically(ciphertext, nil)
+		if err != nil {
+			t.Fatalf("loaded.DecryptDeterministically() err = %v, want nil", err)
+		}
+		if !bytes.Equal(got, plaintext) {
+			t.Errorf("loaded.DecryptDeterministically() = %q, want %q", got, plaintext)
+		}
+		if loaded.Rewrapped() {
+			t.Error("loaded.Rewrapped() = true, want false")
+		}
+	}
+	if got := countCalls(metrics, "Decrypt"); got != 1 {
+		t.Errorf("Decrypt calls = %d, want 1", got)
+	}
+	var hits int
+	for _, hit := range metrics.cacheLookups {
+		if hit {
+			hits++
+		}
+	}
+	if hits != 2 {
+		t.Errorf("cache hits = %d, want 2", hits)
+	}
+
+	// The cache does not bypass the verification of the associated data.
+	if _, err := LoadDeterministicAEAD(t.Context(), client, sourceKeyURI, data, []byte("other")); err == nil {
+		t.Error("LoadDeterministicAEAD() with wrong associated data err = nil, want error")
+	}
+}
+
+func TestWithDeterministicAEADCacheSize(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	writer := newTestClient(t, WithKMS(fakekms))
+	var documents [][]byte
+	for range 2 {
+		_, data, err := NewDeterministicAEAD(t.Context(), writer, sourceKeyURI, nil)
+		if err != nil {
+			t.Fatalf("NewDeterministicAEAD() err = %v, want nil", err)
+		}
+		documents = append(documents, data)
+	}
+
+	for _, test := range []struct {
+		name      string
+		size      int
+		loads     []int
+		wantCalls int
+	}{
+		{"evicts least recently used", 1, []int{0, 1, 1, 0}, 3},
+		{"keeps recently used", 2, []int{0, 1, 0, 1}, 2},
+		{"disabled", 0, []int{0, 0}, 2},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			metrics := &fakeMetrics{}
+			client := newTestClient(t, WithKMS(fakekms), WithMetrics(metrics), WithDeterministicAEADCacheSize(test.size))
+			for _, i := range test.loads {
+				if _, err := LoadDeterministicAEAD(t.Context(), client, sourceKeyURI, documents[i], nil); err != nil {
+					t.Fatalf("LoadDeterministicAEAD() err = %v, want nil", err)
+				}
+			}
+			if got := countCalls(metrics, "Decrypt"); got != test.wantCalls {
+				t.Errorf("Decrypt calls = %d, want %d", got, test.wantCalls)
+			}
+			if got := len(client.(*awsClient).deterministicAEADCache); got > test.size {
+				t.Errorf("len(deterministicAEADCache) = %d, want at most %d", got, test.size)
+			}
+		})
+	}
+}
+
+func TestWithDeterministicAEADCacheSize_fails(t *testing.T) {
+	for _, opts := range [][]ClientOption{
+		{WithDeterministicAEADCacheSize(-1)},
+		{WithDeterministicAEADCacheSize(1), WithDeterministicAEADCacheSize(2)},
+	} {
+		if _, err := NewClientWithOptions(t.Context(), "aws-kms://", opts...); err == nil {
+			t.Errorf("NewClientWithOptions() with %d options err = nil, want error", len(opts))
+		}
+	}
+}
+
+func TestDeterministicAEAD_rewrap(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN, destinationKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	client := newTestClient(t, WithKMS(fakekms))
+	associatedData := []byte("column")
+	plaintext := []byte("plaintext")
+	d, data, err := NewDeterministicAEAD(t.Context(), client, sourceKeyURI, associatedData)
+	if err != nil {
+		t.Fatalf("NewDeterministicAEAD() err = %v, want nil", err)
+	}
+	ciphertext, err := d.EncryptDeterministically(plaintext, nil)
+	if err != nil {
+		t.Fatalf("d.EncryptDeterministically() err = %v, want nil", err)
+	}
+
+	rewrapped, err := d.Rewrap(t.Context(), destinationKeyURI)
+	if err != nil {
+		t.Fatalf("d.Rewrap() err = %v, want nil", err)
+	}
+	if !d.Rewrapped() || d.KeyURI() != destinationKeyURI || !bytes.Equal(d.EncryptedKeyset(), rewrapped) {
+		t.Errorf("after d.Rewrap(): Rewrapped() = %v, KeyURI() = %q, want true, %q", d.Rewrapped(), d.KeyURI(), destinationKeyURI)
+	}
+	metadata, err := ReadEncryptedKeysetMetadata(rewrapped)
+	if err != nil {
+		t.Fatalf("ReadEncryptedKeysetMetadata() err = %v, want nil", err)
+	}
+	if metadata.KeyURI != destinationKeyURI {
+		t.Errorf("metadata.KeyURI = %q, want %q", metadata.KeyURI, destinationKeyURI)
+	}
+
+	// Automatic rewrap when loading with a new client.
+	for _, test := range []struct {
+		name       string
+		data       []byte
+		wantRewrap bool
+	}{
+		{"source key", data, true},
+		{"destination key", rewrapped, false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			client := newTestClient(t, WithKMS(fakekms))
+			loaded, err := LoadDeterministicAEAD(t.Context(), client, sourceKeyURI, test.data, associatedData, WithRewrapKeyURI(destinationKeyURI))
+			if err != nil {
+				t.Fatalf("LoadDeterministicAEAD() err = %v, want nil", err)
+			}
+			if got := loaded.Rewrapped(); got != test.wantRewrap {
+				t.Errorf("loaded.Rewrapped() = %v, want %v", got, test.wantRewrap)
+			}
+			if got := loaded.KeyURI(); got != destinationKeyURI {
+				t.Errorf("loaded.KeyURI() = %q, want %q", got, destinationKeyURI)
+			}
+			got, err := loaded.DecryptDeterministically(ciphertext, nil)
+			if err != nil || !bytes.Equal(got, plaintext) {
+				t.Errorf("loaded.DecryptDeterministically() = %q, %v, want %q, nil", got, err, plaintext)
+			}
+			// The rewrapped keyset is readable on its own.
+			reloaded, err := LoadDeterministicAEAD(t.Context(), newTestClient(t, WithKMS(fakekms)), destinationKeyURI, loaded.EncryptedKeyset(), associatedData)
+			if err != nil {
+				t.Fatalf("LoadDeterministicAEAD() of rewrapped keyset err = %v, want nil", err)
+			}
+			if got, err := reloaded.DecryptDeterministically(ciphertext, nil); err != nil || !bytes.Equal(got, plaintext) {
+				t.Errorf("reloaded.DecryptDeterministically() = %q, %v, want %q, nil", got, err, plaintext)
+			}
+		})
+	}
+}
+
+func TestLoadDeterministicAEAD_fails(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	client := newTestClient(t, WithKMS(fakekms))
+	_, aeadKeyset, err := NewEncryptedKeyset(t.Context(), client, sourceKeyURI, aead.AES256GCMKeyTemplate(), nil)
+	if err != nil {
+		t.Fatalf("NewEncryptedKeyset() err = %v, want nil", err)
+	}
+	if _, err := LoadDeterministicAEAD(t.Context(), client, sourceKeyURI, aeadKeyset, nil); err == nil {
+		t.Error("LoadDeterministicAEAD() of AEAD keyset err = nil, want error")
+	}
+	if _, err := LoadDeterministicAEAD(t.Context(), client, sourceKeyURI, []byte("{}"), nil); err == nil {
+		t.Error("LoadDeterministicAEAD() of invalid document err = nil, want error")
+	}
+
+	_, data, err := NewDeterministicAEAD(t.Context(), client, sourceKeyURI, nil)
+	if err != nil {
+		t.Fatalf("NewDeterministicAEAD() err = %v, want nil", err)
+	}
+	for _, opts := range [][]DeterministicAEADOption{
+		{WithRewrapKeyURI("")},
+		{WithRewrapKeyURI(sourceKeyURI), WithRewrapKeyURI(sourceKeyURI)},
+		{WithRewrapKeyURI("gcp-kms://key")},
+	} {
+		if _, err := LoadDeterministicAEAD(t.Context(), client, sourceKeyURI, data, nil, opts...); err == nil {
+			t.Errorf("LoadDeterministicAEAD() with %d options err = nil, want error", len(opts))
+		}
+	}
+	if _, err := LoadDeterministicAEAD(t.Context(), client, destinationKeyURI, data, nil); err == nil {
+		t.Error("LoadDeterministicAEAD() with other key URI err = nil, want error")
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+// ErrDryRunSucceeded is returned by operations whose dry run AWS KMS
+// reported would have succeeded, see [WithDryRun]. It wraps the
+// *types.DryRunOperationException of AWS KMS.
+var ErrDryRunSucceeded = errors.New("AWS KMS dry run succeeded")
+
+// DryRunResult describes a dry run which AWS KMS reported would have
+// succeeded. It is set in [OperationDetails.DryRun].
+type DryRunResult struct {
+	// Operation is the AWS KMS operation, such as "Encrypt" or "Decrypt".
+	Operation string
+	// KeyID is the key ID of the request, as configured.
+	KeyID string
+}
+
+// WithDryRun makes AEAD primitives perform dry runs of Encrypt and Decrypt,
+// in which AWS KMS only checks whether the request would succeed, for example
+// whether the caller has the required permissions. It also applies to the
+// GenerateDataKey requests of [NewStreamingAEAD], the GenerateDataKeyPair
+// requests of [GenerateDataKeyPair] and [GenerateDataKeyPairWithoutPlaintext],
+// and the Decrypt requests of [LoadDataKeyPair].
+//
+// A successful dry run returns an error wrapping [ErrDryRunSucceeded] and no
+// ciphertext or plaintext, so that callers which are unaware of dry runs
+// never mistake it for a real operation. Failed dry runs return the error AWS
+// KMS reported. [OperationDetails.DryRun] describes successful dry runs.
+//
+// To perform a dry run of a single call, use [ContextWithDryRun].
+func WithDryRun() ClientOption {
+	return option(func(ctx context.Context, a *awsClient) error {
+		if a.dryRun {
+			return errors.New("WithDryRun option cannot be used, dry run already enabled")
+		}
+		a.dryRun = true
+		return nil
+	})
+}
+
+type dryRunKey struct{}
+
+// ContextWithDryRun returns a copy of ctx which makes AEAD operations using
+// it perform dry runs, like with [WithDryRun].
+func ContextWithDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, true)
+}
+
+// isDryRun reports whether operations with ctx are dry runs.
+func isDryRun(ctx context.Context, configured bool) bool {
+	dryRun, _ := ctx.Value(dryRunKey{}).(bool)
+	return configured || dryRun
+}
+
+// dryRunError returns err, wrapped in [ErrDryRunSucceeded] if it is the
+// DryRunOperationException with which AWS KMS signals that a dry run would
+// have succeeded, which is then recorded in the operation details of ctx.
+func dryRunError(ctx context.Context, err error, operation, keyID string) error {
+	var dryRunErr *types.DryRunOperationException
+	if !errors.As(err, &dryRunErr) {
+		return err
+	}
+	if d := operationDetails(ctx); d != nil {
+		d.DryRun = &DryRunResult{Operation: operation, KeyID: keyID}
+	}
+	return fmt.Errorf("%w: %w", ErrDryRunSucceeded, err)
+}
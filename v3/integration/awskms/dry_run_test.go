@@ -0,0 +1,109 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/tink-crypto/tink-go-awskms/v3/integration/awskms/internal/fakeawskms"
+	"github.com/tink-crypto/tink-go/v2/tink"
+)
+
+func TestWithDryRun(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	plaintext := []byte("plaintext")
+	associatedData := []byte("associatedData")
+	ciphertext, err := getAEAD(t, newTestClient(t, WithKMS(fakekms)), sourceKeyURI).EncryptWithContext(t.Context(), plaintext, associatedData)
+	if err != nil {
+		t.Fatalf("a.EncryptWithContext() err = %v, want nil", err)
+	}
+
+	a := getAEAD(t, newTestClient(t, WithKMS(fakekms), WithDryRun()), sourceKeyURI)
+	details := &OperationDetails{}
+	got, err := a.EncryptWithContext(ContextWithOperationDetails(t.Context(), details), plaintext, associatedData)
+	if !errors.Is(err, ErrDryRunSucceeded) || got != nil {
+		t.Errorf("a.EncryptWithContext() = %q, %v, want nil, ErrDryRunSucceeded", got, err)
+	}
+	var dryRunErr *types.DryRunOperationException
+	if !errors.As(err, &dryRunErr) {
+		t.Errorf("a.EncryptWithContext() err = %v, want DryRunOperationException", err)
+	}
+	if want := (DryRunResult{Operation: "Encrypt", KeyID: sourceKeyARN}); details.DryRun == nil || *details.DryRun != want {
+		t.Errorf("details.DryRun = %+v, want %+v", details.DryRun, want)
+	}
+	details = &OperationDetails{}
+	got, err = a.DecryptWithContext(ContextWithOperationDetails(t.Context(), details), ciphertext, associatedData)
+	if !errors.Is(err, ErrDryRunSucceeded) || got != nil {
+		t.Errorf("a.DecryptWithContext() = %q, %v, want nil, ErrDryRunSucceeded", got, err)
+	}
+	// The generic tink.AEAD interface reports it as an error as well.
+	if got, err := a.(tink.AEAD).Decrypt(ciphertext, associatedData); !errors.Is(err, ErrDryRunSucceeded) || got != nil {
+		t.Errorf("a.Decrypt() = %q, %v, want nil, ErrDryRunSucceeded", got, err)
+	}
+	if want := (DryRunResult{Operation: "Decrypt", KeyID: sourceKeyARN}); details.DryRun == nil || *details.DryRun != want {
+		t.Errorf("details.DryRun = %+v, want %+v", details.DryRun, want)
+	}
+
+	// Dry runs which would fail return the error.
+	if _, err := a.DecryptWithContext(t.Context(), ciphertext, []byte("other")); !isInvalidCiphertext(err) {
+		t.Errorf("a.DecryptWithContext() with wrong associated data err = %v, want InvalidCiphertextException", err)
+	}
+	if _, err := fakekms.AddGrant(sourceKeyARN, types.GrantOperationDecrypt); err != nil {
+		t.Fatalf("fakekms.AddGrant() failed: %v", err)
+	}
+	if _, err := a.EncryptWithContext(t.Context(), plaintext, associatedData); !isAccessDenied(err) {
+		t.Errorf("a.EncryptWithContext() without grant err = %v, want AccessDeniedException", err)
+	}
+}
+
+func TestContextWithDryRun(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	a := getAEAD(t, newTestClient(t, WithKMS(fakekms)), sourceKeyURI)
+	details := &OperationDetails{}
+	ctx := ContextWithOperationDetails(ContextWithDryRun(t.Context()), details)
+	if got, err := a.EncryptWithContext(ctx, []byte("plaintext"), nil); !errors.Is(err, ErrDryRunSucceeded) || got != nil {
+		t.Errorf("a.EncryptWithContext() = %q, %v, want nil, ErrDryRunSucceeded", got, err)
+	}
+	if details.DryRun == nil {
+		t.Error("details.DryRun = nil, want dry run result")
+	}
+
+	details = &OperationDetails{}
+	ctx = ContextWithOperationDetails(t.Context(), details)
+	if got, err := a.EncryptWithContext(ctx, []byte("plaintext"), nil); err != nil || got == nil {
+		t.Errorf("a.EncryptWithContext() = %q, %v, want ciphertext", got, err)
+	}
+	if details.DryRun != nil {
+		t.Errorf("details.DryRun = %+v, want nil", details.DryRun)
+	}
+}
+
+func TestWithDryRun_twiceFails(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	if _, err := NewClientWithOptions(t.Context(), "aws-kms://", WithKMS(fakekms), WithDryRun(), WithDryRun()); err == nil {
+		t.Error("NewClientWithOptions() err = nil, want error")
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tink-crypto/tink-go/v2/keyset"
+	tinkpb "github.com/tink-crypto/tink-go/v2/proto/tink_go_proto"
+)
+
+// encryptedKeysetVersion is the version of the encrypted keyset documents
+// written by this package.
+const encryptedKeysetVersion = 1
+
+// encryptedKeysetDocument is the JSON document written by
+// [MarshalEncryptedKeyset].
+type encryptedKeysetDocument struct {
+	Version               int    `json:"version"`
+	KeyURI                string `json:"keyUri"`
+	EncryptionContextName string `json:"encryptionContextName"`
+	// Keyset is the encrypted keyset in the Tink JSON format, which includes
+	// the keyset info.
+	Keyset json.RawMessage `json:"keyset"`
+}
+
+// EncryptedKeysetMetadata describes an encrypted keyset document, without
+// decrypting it.
+type EncryptedKeysetMetadata struct {
+	// Version is the version of the document format.
+	Version int
+	// KeyURI is the URI of the AWS KMS key which encrypted the keyset.
+	KeyURI string
+	// EncryptionContextName is the String of the [EncryptionContextEncoder]
+	// used to encrypt the keyset, for example "associatedData".
+	EncryptionContextName string
+	// KeysetInfo describes the keys of the keyset, without key material.
+	KeysetInfo *tinkpb.KeysetInfo
+}
+
+// NewEncryptedKeyset generates a keyset from template and encrypts it with the
+// AWS KMS key keyURI. It returns the keyset and the encrypted keyset document,
+// see [MarshalEncryptedKeyset].
+func NewEncryptedKeyset(ctx context.Context, client Client, keyURI string, template *tinkpb.KeyTemplate, associatedData []byte) (*keyset.Handle, []byte, error) {
+	handle, err := keyset.NewHandle(template)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := MarshalEncryptedKeyset(ctx, client, keyURI, handle, associatedData)
+	if err != nil {
+		return nil, nil, err
+	}
+	return handle, data, nil
+}
+
+// MarshalEncryptedKeyset encrypts handle with the AWS KMS key keyURI and
+// associatedData, and returns a versioned JSON document containing the
+// encrypted keyset.
+//
+// The document also records keyURI and the encryption context encoder of
+// client, see [ReadEncryptedKeysetMetadata]. The associated data is not
+// recorded.
+func MarshalEncryptedKeyset(ctx context.Context, client Client, keyURI string, handle *keyset.Handle, associatedData []byte) ([]byte, error) {
+	c, ok := client.(*awsClient)
+	if !ok {
+		return nil, fmt.Errorf("unsupported client type %T", client)
+	}
+	if !c.Supported(keyURI) {
+		return nil, fmt.Errorf("keyURI must start with prefix %s, but got %s", c.keyURIPrefix, keyURI)
+	}
+	a := newAWSAEAD(strings.TrimPrefix(keyURI, awsPrefix), c)
+	buf := new(bytes.Buffer)
+	if err := handle.WriteWithContext(ctx, keyset.NewJSONWriter(buf), a, associatedData); err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(&encryptedKeysetDocument{
+		Version:               encryptedKeysetVersion,
+		KeyURI:                keyURI,
+		EncryptionContextName: c.encoder.String(),
+		Keyset:                buf.Bytes(),
+	}, "", "  ")
+}
+
+// UnmarshalEncryptedKeyset decrypts an encrypted keyset document written by
+// [MarshalEncryptedKeyset] with the AWS KMS key keyURI.
+//
+// Like [keyset.Read] with a known key encryption key, the document is
+// rejected unless it was encrypted with keyURI, which must be supported by
+// client, so that whoever can write the document cannot substitute a keyset
+// encrypted with another key. The encryption context encoder recorded in the
+// document must be the encoder of client or one of its decrypt fallbacks, see
+// [WithDecryptFallback].
+func UnmarshalEncryptedKeyset(ctx context.Context, client Client, keyURI string, data, associatedData []byte) (*keyset.Handle, error) {
+	c, ok := client.(*awsClient)
+	if !ok {
+		return nil, fmt.Errorf("unsupported client type %T", client)
+	}
+	doc, err := parseEncryptedKeysetDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	if doc.KeyURI != keyURI {
+		return nil, fmt.Errorf("encrypted keyset is encrypted with key URI %q, want %q", doc.KeyURI, keyURI)
+	}
+	return c.decryptEncryptedKeyset(ctx, doc, associatedData)
+}
+
+// decryptEncryptedKeyset decrypts the keyset of doc, whose key URI has been
+// checked by the caller.
+func (c *awsClient) decryptEncryptedKeyset(ctx context.Context, doc *encryptedKeysetDocument, associatedData []byte) (*keyset.Handle, error) {
+	if !c.Supported(doc.KeyURI) {
+		return nil, fmt.Errorf("key URI %q of the encrypted keyset is not supported by the client", doc.KeyURI)
+	}
+	encoder, err := encoderByName(doc.EncryptionContextName, decryptEncoders(c.encoder, c.decryptFallbacks))
+	if err != nil {
+		return nil, err
+	}
+	a := newAWSAEAD(strings.TrimPrefix(doc.KeyURI, awsPrefix), c)
+	a.encoder = encoder
+	a.decryptFallbacks = nil
+	return keyset.ReadWithContext(ctx, keyset.NewJSONReader(bytes.NewReader(doc.Keyset)), a, associatedData)
+}
+
+// ReadEncryptedKeysetMetadata returns the metadata of an encrypted keyset
+// document written by [MarshalEncryptedKeyset], without decrypting it.
+func ReadEncryptedKeysetMetadata(data []byte) (*EncryptedKeysetMetadata, error) {
+	doc, err := parseEncryptedKeysetDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	encryptedKeyset, err := keyset.NewJSONReader(bytes.NewReader(doc.Keyset)).ReadEncrypted()
+	if err != nil {
+		return nil, err
+	}
+	return &EncryptedKeysetMetadata{
+		Version:               doc.Version,
+		KeyURI:                doc.KeyURI,
+		EncryptionContextName: doc.EncryptionContextName,
+		KeysetInfo:            encryptedKeyset.GetKeysetInfo(),
+	}, nil
+}
+
+func parseEncryptedKeysetDocument(data []byte) (*encryptedKeysetDocument, error) {
+	doc := &encryptedKeysetDocument{}
+	if err := json.Unmarshal(data, doc); err != nil {
+		return nil, fmt.Errorf("invalid encrypted keyset document: %v", err)
+	}
+	if doc.Version != encryptedKeysetVersion {
+		return nil, fmt.Errorf("unsupported encrypted keyset document version %d", doc.Version)
+	}
+	if doc.KeyURI == "" || doc.EncryptionContextName == "" || len(doc.Keyset) == 0 {
+		return nil, errors.New("invalid encrypted keyset document: missing fields")
+	}
+	return doc, nil
+}
+
+// encoderByName returns the encoder of encoders whose String is name. Only
+// the encoders a client is configured with are accepted, so that a name read
+// from untrusted data cannot select another interpretation of the associated
+// data.
+func encoderByName(name string, encoders []EncryptionContextEncoder) (EncryptionContextEncoder, error) {
+	for _, encoder := range encoders {
+		if encoder.String() == name {
+			return encoder, nil
+		}
+	}
+	return nil, fmt.Errorf("encryption context encoder %q is not configured for the client", name)
+}
@@ -0,0 +1,152 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/tink-crypto/tink-go-awskms/v3/integration/awskms/internal/fakeawskms"
+	"github.com/tink-crypto/tink-go/v2/aead"
+)
+
+func TestEncryptedKeyset(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	writer := newTestClient(t, WithKMS(fakekms), WithEncryptionContextEncoder(Base64Encoder("keyset")))
+	associatedData := []byte("associatedData")
+	handle, data, err := NewEncryptedKeyset(t.Context(), writer, sourceKeyURI, aead.AES256GCMKeyTemplate(), associatedData)
+	if err != nil {
+		t.Fatalf("NewEncryptedKeyset() err = %v, want nil", err)
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("json.Unmarshal() err = %v, want nil", err)
+	}
+	if doc["version"] != 1.0 || doc["keyUri"] != sourceKeyURI || doc["encryptionContextName"] != "base64(keyset)" {
+		t.Errorf("document = %v, want version 1, keyUri %q and encryptionContextName base64(keyset)", doc, sourceKeyURI)
+	}
+	metadata, err := ReadEncryptedKeysetMetadata(data)
+	if err != nil {
+		t.Fatalf("ReadEncryptedKeysetMetadata() err = %v, want nil", err)
+	}
+	if got, want := metadata.KeysetInfo.GetPrimaryKeyId(), handle.KeysetInfo().GetPrimaryKeyId(); got != want {
+		t.Errorf("metadata.KeysetInfo.GetPrimaryKeyId() = %d, want %d", got, want)
+	}
+
+	// The encoder of the writer may be a decrypt fallback of the reader.
+	reader := newTestClient(t, WithKMS(fakekms), WithDecryptFallback(Base64Encoder("keyset")))
+	got, err := UnmarshalEncryptedKeyset(t.Context(), reader, sourceKeyURI, data, associatedData)
+	if err != nil {
+		t.Fatalf("UnmarshalEncryptedKeyset() err = %v, want nil", err)
+	}
+	primitive, err := aead.New(handle)
+	if err != nil {
+		t.Fatalf("aead.New(handle) err = %v, want nil", err)
+	}
+	gotPrimitive, err := aead.New(got)
+	if err != nil {
+		t.Fatalf("aead.New(got) err = %v, want nil", err)
+	}
+	ciphertext, err := primitive.Encrypt([]byte("plaintext"), nil)
+	if err != nil {
+		t.Fatalf("primitive.Encrypt() err = %v, want nil", err)
+	}
+	if plaintext, err := gotPrimitive.Decrypt(ciphertext, nil); err != nil || !bytes.Equal(plaintext, []byte("plaintext")) {
+		t.Errorf("gotPrimitive.Decrypt() = %q, %v, want %q, nil", plaintext, err, "plaintext")
+	}
+
+	if _, err := UnmarshalEncryptedKeyset(t.Context(), reader, sourceKeyURI, data, []byte("other")); err == nil {
+		t.Error("UnmarshalEncryptedKeyset() with wrong associated data err = nil, want error")
+	}
+	unsupported, err := NewClientWithOptions(t.Context(), "aws-kms://arn:aws:kms:eu-west-1:", WithKMS(fakekms), WithDecryptFallback(Base64Encoder("keyset")))
+	if err != nil {
+		t.Fatalf("NewClientWithOptions() failed: %v", err)
+	}
+	if _, err := UnmarshalEncryptedKeyset(t.Context(), unsupported.(Client), sourceKeyURI, data, associatedData); err == nil {
+		t.Error("UnmarshalEncryptedKeyset() with unsupported key URI err = nil, want error")
+	}
+	otherEncoder := newTestClient(t, WithKMS(fakekms))
+	if _, err := UnmarshalEncryptedKeyset(t.Context(), otherEncoder, sourceKeyURI, data, associatedData); err == nil {
+		t.Error("UnmarshalEncryptedKeyset() with client without the recorded encoder err = nil, want error")
+	}
+}
+
+func TestUnmarshalEncryptedKeyset_otherKeyFails(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN, destinationKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	client := newTestClient(t, WithKMS(fakekms))
+	_, data, err := NewEncryptedKeyset(t.Context(), client, destinationKeyURI, aead.AES256GCMKeyTemplate(), nil)
+	if err != nil {
+		t.Fatalf("NewEncryptedKeyset() err = %v, want nil", err)
+	}
+	// A keyset encrypted with another key which the client can use is
+	// rejected, even if the document names the expected key.
+	if _, err := UnmarshalEncryptedKeyset(t.Context(), client, sourceKeyURI, data, nil); err == nil {
+		t.Error("UnmarshalEncryptedKeyset() of keyset of other key err = nil, want error")
+	}
+	forged := bytes.Replace(data, []byte(destinationKeyURI), []byte(sourceKeyURI), 1)
+	if _, err := UnmarshalEncryptedKeyset(t.Context(), client, sourceKeyURI, forged, nil); err == nil {
+		t.Error("UnmarshalEncryptedKeyset() of document with forged key URI err = nil, want error")
+	}
+}
+
+func TestUnmarshalEncryptedKeyset_invalidDocumentFails(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	client := newTestClient(t, WithKMS(fakekms))
+	for _, data := range []string{
+		``,
+		`[]`,
+		`{"version":2,"keyUri":"` + sourceKeyURI + `","encryptionContextName":"associatedData","keyset":{}}`,
+		`{"version":1,"encryptionContextName":"associatedData","keyset":{}}`,
+		`{"version":1,"keyUri":"` + sourceKeyURI + `","encryptionContextName":"custom","keyset":{"encryptedKeyset":""}}`,
+	} {
+		if _, err := UnmarshalEncryptedKeyset(t.Context(), client, sourceKeyURI, []byte(data), nil); err == nil {
+			t.Errorf("UnmarshalEncryptedKeyset(%q) err = nil, want error", data)
+		}
+	}
+}
+
+func TestEncoderByName(t *testing.T) {
+	encoders := []EncryptionContextEncoder{
+		AssociatedData,
+		SerializedEncoder(),
+		HexEncoder("name"),
+		UTF8Encoder("(name)"),
+	}
+	for _, encoder := range encoders {
+		got, err := encoderByName(encoder.String(), encoders)
+		if err != nil {
+			t.Fatalf("encoderByName(%q) err = %v, want nil", encoder, err)
+		}
+		if got.String() != encoder.String() {
+			t.Errorf("encoderByName(%q) = %q, want %q", encoder, got, encoder)
+		}
+	}
+	for _, encoder := range []EncryptionContextEncoder{LegacyAdditionalData, Base64Encoder("name"), UTF8Encoder("other")} {
+		if _, err := encoderByName(encoder.String(), encoders); err == nil {
+			t.Errorf("encoderByName(%q) of encoder which is not configured err = nil, want error", encoder)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+// ErrUnsupportedEncryptionAlgorithm is wrapped by a [KeyValidationError] if
+// the key does not support the algorithm selected with
+// [WithEncryptionAlgorithm].
+var ErrUnsupportedEncryptionAlgorithm = errors.New("encryption algorithm is not supported by the key")
+
+// WithEncryptionAlgorithm sets the encryption algorithm sent with Encrypt and
+// Decrypt requests. By default, no algorithm is sent and AWS KMS uses
+// SYMMETRIC_DEFAULT.
+//
+// The algorithm is checked against the key metadata when the AEAD is created,
+// using DescribeKey, which requires the kms:DescribeKey permission. This
+// allows using asymmetric keys, such as RSA keys with RSAES_OAEP_SHA_256 or
+// SM2 keys with SM2PKE in China regions. AWS KMS does not support encryption
+// contexts with these algorithms, so their AEADs require empty associated
+// data. The plaintext size limit of the algorithm is enforced before calling
+// AWS KMS.
+func WithEncryptionAlgorithm(algorithm types.EncryptionAlgorithmSpec) ClientOption {
+	return option(func(ctx context.Context, a *awsClient) error {
+		if !slices.Contains(algorithm.Values(), algorithm) {
+			return fmt.Errorf("unknown encryption algorithm %q", algorithm)
+		}
+		if a.encryptionAlgorithm != "" {
+			return errors.New("WithEncryptionAlgorithm option cannot be used, encryption algorithm already set")
+		}
+		a.encryptionAlgorithm = algorithm
+		return nil
+	})
+}
+
+// algorithm returns the encryption algorithm used by a.
+func (a *awsAEAD) algorithm() types.EncryptionAlgorithmSpec {
+	if a.encryptionAlgorithm == "" {
+		return types.EncryptionAlgorithmSpecSymmetricDefault
+	}
+	return a.encryptionAlgorithm
+}
+
+// supportsEncryptionContext reports whether AWS KMS accepts an encryption
+// context with algorithm, where the empty algorithm means SYMMETRIC_DEFAULT.
+func supportsEncryptionContext(algorithm types.EncryptionAlgorithmSpec) bool {
+	return algorithm == "" || algorithm == types.EncryptionAlgorithmSpecSymmetricDefault
+}
+
+// maxPlaintextSize returns the size of the largest plaintext AWS KMS encrypts
+// with algorithm and a key with the given spec, or 0 if it is unknown.
+func maxPlaintextSize(algorithm types.EncryptionAlgorithmSpec, spec types.KeySpec) int {
+	var hashSize int
+	switch algorithm {
+	case types.EncryptionAlgorithmSpecSymmetricDefault:
+		return 4096
+	case types.EncryptionAlgorithmSpecSm2pke:
+		return 1024
+	case types.EncryptionAlgorithmSpecRsaesOaepSha1:
+		hashSize = sha1.Size
+	case types.EncryptionAlgorithmSpecRsaesOaepSha256:
+		hashSize = sha256.Size
+	default:
+		return 0
+	}
+	var modulusSize int
+	switch spec {
+	case types.KeySpecRsa2048:
+		modulusSize = 2048 / 8
+	case types.KeySpecRsa3072:
+		modulusSize = 3072 / 8
+	case types.KeySpecRsa4096:
+		modulusSize = 4096 / 8
+	default:
+		return 0
+	}
+	// See RFC 8017, section 7.1.1.
+	return modulusSize - 2*hashSize - 2
+}
+
+// checkEncryptionAlgorithm checks that a request with plaintext, which is nil
+// for decryption, and encryptionContext can be sent with the encryption
+// algorithm of a.
+func (a *awsAEAD) checkEncryptionAlgorithm(plaintext []byte, encryptionContext map[string]string) error {
+	if len(encryptionContext) > 0 && !supportsEncryptionContext(a.encryptionAlgorithm) {
+		return fmt.Errorf("encryption algorithm %s does not support an encryption context, associated data must be empty", a.encryptionAlgorithm)
+	}
+	if a.maxPlaintextSize > 0 && len(plaintext) > a.maxPlaintextSize {
+		return fmt.Errorf("%w: %d bytes exceed the maximum of %d bytes for encryption algorithm %s", ErrPlaintextTooLarge, len(plaintext), a.maxPlaintextSize, a.algorithm())
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/tink-crypto/tink-go-awskms/v3/integration/awskms/internal/fakeawskms"
+)
+
+const sm2KeyARN = "arn:aws-cn:kms:cn-north-1:235739564943:key/5c3e8d2f-1a4b-4c6d-9e7f-2a3b4c5d6e7f"
+
+func TestWithEncryptionAlgorithm_rsa(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	if err := fakekms.AddKey(rsaKeyARN, types.KeySpecRsa2048, types.KeyUsageTypeEncryptDecrypt); err != nil {
+		t.Fatalf("fakekms.AddKey() failed: %v", err)
+	}
+	metrics := &fakeMetrics{}
+	client := newTestClient(t, WithKMS(fakekms),
+		WithEncryptionAlgorithm(types.EncryptionAlgorithmSpecRsaesOaepSha256), WithMetrics(metrics))
+	a, err := client.GetAEAD("aws-kms://" + rsaKeyARN)
+	if err != nil {
+		t.Fatalf("client.GetAEAD() err = %v, want nil", err)
+	}
+	// The limit of RSAES_OAEP_SHA_256 with a 2048-bit key.
+	plaintext := bytes.Repeat([]byte("a"), 190)
+	ciphertext, err := a.Encrypt(plaintext, nil)
+	if err != nil {
+		t.Fatalf("a.Encrypt() err = %v, want nil", err)
+	}
+	got, err := a.Decrypt(ciphertext, nil)
+	if err != nil {
+		t.Fatalf("a.Decrypt() err = %v, want nil", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("a.Decrypt() = %q, want %q", got, plaintext)
+	}
+
+	calls := len(metrics.calls)
+	if _, err := a.Encrypt(append(plaintext, 'a'), nil); err == nil {
+		t.Error("a.Encrypt() with too large plaintext err = nil, want error")
+	}
+	if _, err := a.Encrypt(plaintext, []byte("associatedData")); err == nil {
+		t.Error("a.Encrypt() with associated data err = nil, want error")
+	}
+	if _, err := a.Decrypt(ciphertext, []byte("associatedData")); err == nil {
+		t.Error("a.Decrypt() with associated data err = nil, want error")
+	}
+	if len(metrics.calls) != calls {
+		t.Errorf("AWS KMS calls of invalid requests = %d, want 0", len(metrics.calls)-calls)
+	}
+}
+
+func TestWithEncryptionAlgorithm_sizeLimits(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	if err := fakekms.AddKey(rsaKeyARN, types.KeySpecRsa2048, types.KeyUsageTypeEncryptDecrypt); err != nil {
+		t.Fatalf("fakekms.AddKey() failed: %v", err)
+	}
+	if err := fakekms.AddKey(sm2KeyARN, types.KeySpecSm2, types.KeyUsageTypeEncryptDecrypt); err != nil {
+		t.Fatalf("fakekms.AddKey() failed: %v", err)
+	}
+	for _, test := range []struct {
+		algorithm types.EncryptionAlgorithmSpec
+		keyARN    string
+		maxSize   int
+	}{
+		{types.EncryptionAlgorithmSpecSymmetricDefault, sourceKeyARN, 4096},
+		{types.EncryptionAlgorithmSpecRsaesOaepSha1, rsaKeyARN, 214},
+		{types.EncryptionAlgorithmSpecRsaesOaepSha256, rsaKeyARN, 190},
+		{types.EncryptionAlgorithmSpecSm2pke, sm2KeyARN, 1024},
+	} {
+		t.Run(string(test.algorithm), func(t *testing.T) {
+			a, err := NewAEADWithContext(t.Context(), test.keyARN, WithKMS(fakekms), WithEncryptionAlgorithm(test.algorithm))
+			if err != nil {
+				t.Fatalf("NewAEADWithContext() err = %v, want nil", err)
+			}
+			if _, err := a.EncryptWithContext(t.Context(), make([]byte, test.maxSize+1), nil); err == nil {
+				t.Errorf("a.EncryptWithContext() with %d bytes err = nil, want error", test.maxSize+1)
+			}
+		})
+	}
+}
+
+func TestWithEncryptionAlgorithm_symmetricDefault(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	a := getAEAD(t, newTestClient(t, WithKMS(fakekms), WithEncryptionAlgorithm(types.EncryptionAlgorithmSpecSymmetricDefault)), sourceKeyURI)
+	plaintext := []byte("plaintext")
+	associatedData := []byte("associatedData")
+	ciphertext, err := a.EncryptWithContext(t.Context(), plaintext, associatedData)
+	if err != nil {
+		t.Fatalf("a.EncryptWithContext() err = %v, want nil", err)
+	}
+	got, err := a.DecryptWithContext(t.Context(), ciphertext, associatedData)
+	if err != nil {
+		t.Fatalf("a.DecryptWithContext() err = %v, want nil", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("a.DecryptWithContext() = %q, want %q", got, plaintext)
+	}
+}
+
+func TestWithEncryptionAlgorithm_unsupportedByKeyFails(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	if err := fakekms.AddKey(hmacKeyARN, types.KeySpecHmac256, types.KeyUsageTypeGenerateVerifyMac); err != nil {
+		t.Fatalf("fakekms.AddKey() failed: %v", err)
+	}
+	client := newTestClient(t, WithKMS(fakekms),
+		WithEncryptionAlgorithm(types.EncryptionAlgorithmSpecRsaesOaepSha256))
+	for _, test := range []struct {
+		keyURI  string
+		wantErr error
+	}{
+		{sourceKeyURI, ErrUnsupportedEncryptionAlgorithm},
+		{"aws-kms://" + hmacKeyARN, ErrUnsupportedKeyUsage},
+	} {
+		_, err := client.GetAEAD(test.keyURI)
+		var validationErr *KeyValidationError
+		if !errors.As(err, &validationErr) || !errors.Is(err, test.wantErr) {
+			t.Errorf("client.GetAEAD(%q) err = %v, want KeyValidationError wrapping %v", test.keyURI, err, test.wantErr)
+			continue
+		}
+		if validationErr.EncryptionAlgorithm != types.EncryptionAlgorithmSpecRsaesOaepSha256 {
+			t.Errorf("validationErr.EncryptionAlgorithm = %q, want %q", validationErr.EncryptionAlgorithm, types.EncryptionAlgorithmSpecRsaesOaepSha256)
+		}
+	}
+}
+
+func TestWithEncryptionAlgorithm_invalidOptionFails(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	for _, opts := range [][]ClientOption{
+		{WithEncryptionAlgorithm("UNKNOWN")},
+		{WithEncryptionAlgorithm(types.EncryptionAlgorithmSpecSymmetricDefault), WithEncryptionAlgorithm(types.EncryptionAlgorithmSpecSymmetricDefault)},
+	} {
+		if _, err := NewClientWithOptions(t.Context(), "aws-kms://", append([]ClientOption{WithKMS(fakekms)}, opts...)...); err == nil {
+			t.Error("NewClientWithOptions() err = nil, want error")
+		}
+	}
+}
+
+func TestMaxPlaintextSize(t *testing.T) {
+	for _, test := range []struct {
+		algorithm types.EncryptionAlgorithmSpec
+		spec      types.KeySpec
+		want      int
+	}{
+		{types.EncryptionAlgorithmSpecSymmetricDefault, types.KeySpecSymmetricDefault, 4096},
+		{types.EncryptionAlgorithmSpecRsaesOaepSha1, types.KeySpecRsa2048, 214},
+		{types.EncryptionAlgorithmSpecRsaesOaepSha256, types.KeySpecRsa2048, 190},
+		{types.EncryptionAlgorithmSpecRsaesOaepSha1, types.KeySpecRsa3072, 342},
+		{types.EncryptionAlgorithmSpecRsaesOaepSha256, types.KeySpecRsa3072, 318},
+		{types.EncryptionAlgorithmSpecRsaesOaepSha1, types.KeySpecRsa4096, 470},
+		{types.EncryptionAlgorithmSpecRsaesOaepSha256, types.KeySpecRsa4096, 446},
+		{types.EncryptionAlgorithmSpecSm2pke, types.KeySpecSm2, 1024},
+		{types.EncryptionAlgorithmSpecRsaesOaepSha256, types.KeySpecEccNistP256, 0},
+	} {
+		if got := maxPlaintextSize(test.algorithm, test.spec); got != test.want {
+			t.Errorf("maxPlaintextSize(%s, %s) = %d, want %d", test.algorithm, test.spec, got, test.want)
+		}
+	}
+}
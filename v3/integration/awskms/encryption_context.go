@@ -0,0 +1,161 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// EncryptionContextEncoder converts the associated data passed to AEAD
+// primitives into the EncryptionContext field of AWS KMS requests.
+//
+// [AssociatedData] and [LegacyAdditionalData] are the encoders used by Tink.
+// [HexEncoder], [Base64Encoder] and [UTF8Encoder] allow custom names and
+// encodings, for compatibility with ciphertexts produced by other tools.
+type EncryptionContextEncoder interface {
+	// EncryptionContext returns the encryption context for associatedData,
+	// which is never empty. Requests with empty associated data are sent
+	// without an encryption context.
+	EncryptionContext(associatedData []byte) (map[string]string, error)
+	// String returns a short description of the encoder, for example
+	// "base64(aad)". It is used in logs and stored metadata.
+	String() string
+}
+
+// namedEncoder is an EncryptionContextEncoder producing a single entry.
+type namedEncoder struct {
+	kind   string
+	name   string
+	encode func([]byte) (string, error)
+}
+
+func (e *namedEncoder) EncryptionContext(associatedData []byte) (map[string]string, error) {
+	value, err := e.encode(associatedData)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{e.name: value}, nil
+}
+
+func (e *namedEncoder) String() string {
+	return e.kind + "(" + e.name + ")"
+}
+
+// HexEncoder returns an encoder producing a single encryption context entry
+// named name whose value is the lowercase hex encoded associated data.
+//
+// HexEncoder("associatedData") produces the same encryption context as
+// [AssociatedData].
+func HexEncoder(name string) EncryptionContextEncoder {
+	return &namedEncoder{
+		kind: "hex",
+		name: name,
+		encode: func(b []byte) (string, error) {
+			return hex.EncodeToString(b), nil
+		},
+	}
+}
+
+// Base64Encoder returns an encoder producing a single encryption context entry
+// named name whose value is the standard, padded base64 encoding of the
+// associated data.
+func Base64Encoder(name string) EncryptionContextEncoder {
+	return &namedEncoder{
+		kind: "base64",
+		name: name,
+		encode: func(b []byte) (string, error) {
+			return base64.StdEncoding.EncodeToString(b), nil
+		},
+	}
+}
+
+// UTF8Encoder returns an encoder producing a single encryption context entry
+// named name whose value is the associated data itself. Associated data which
+// is not valid UTF-8 is rejected.
+func UTF8Encoder(name string) EncryptionContextEncoder {
+	return &namedEncoder{
+		kind: "utf8",
+		name: name,
+		encode: func(b []byte) (string, error) {
+			if !utf8.Valid(b) {
+				return "", errors.New("associated data is not valid UTF-8")
+			}
+			return string(b), nil
+		},
+	}
+}
+
+type serializedEncoder struct{}
+
+func (serializedEncoder) EncryptionContext(associatedData []byte) (map[string]string, error) {
+	return UnmarshalEncryptionContext(associatedData)
+}
+
+func (serializedEncoder) String() string { return "serialized" }
+
+// SerializedEncoder returns an encoder which interprets associated data as an
+// encryption context serialized with [MarshalEncryptionContext].
+func SerializedEncoder() EncryptionContextEncoder {
+	return serializedEncoder{}
+}
+
+// MarshalEncryptionContext serializes encryptionContext deterministically, so
+// that it can be passed as associated data to primitives of a client created
+// with [WithSerializedEncryptionContext].
+//
+// The serialization is a JSON object with keys in sorted order.
+func MarshalEncryptionContext(encryptionContext map[string]string) ([]byte, error) {
+	if len(encryptionContext) == 0 {
+		return nil, nil
+	}
+	// encoding/json sorts map keys, which makes the output deterministic.
+	return json.Marshal(encryptionContext)
+}
+
+// UnmarshalEncryptionContext parses associated data produced by
+// [MarshalEncryptionContext]. Empty data results in an empty encryption
+// context.
+//
+// Only the exact output of MarshalEncryptionContext is accepted, so that each
+// encryption context has a single serialization and ciphertexts remain bound
+// to the bytes of their associated data. Other JSON encodings of the same
+// object, for example with whitespace, other key order or duplicate keys, and
+// the empty object "{}" are rejected.
+func UnmarshalEncryptionContext(data []byte) (map[string]string, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var encryptionContext map[string]string
+	if err := json.Unmarshal(data, &encryptionContext); err != nil {
+		return nil, fmt.Errorf("associated data is not a serialized encryption context: %v", err)
+	}
+	if encryptionContext == nil {
+		return nil, errors.New("associated data is not a serialized encryption context: null")
+	}
+	canonical, err := MarshalEncryptionContext(encryptionContext)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(canonical, data) {
+		return nil, errors.New("associated data is not a serialized encryption context: not in canonical form")
+	}
+	return encryptionContext, nil
+}
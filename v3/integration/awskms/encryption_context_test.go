@@ -0,0 +1,231 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"bytes"
+	"maps"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/tink-crypto/tink-go-awskms/v3/integration/awskms/internal/fakeawskms"
+)
+
+func TestEncryptionContextEncoders(t *testing.T) {
+	associatedData := []byte("héllo")
+	tests := []struct {
+		encoder    EncryptionContextEncoder
+		wantString string
+		want       map[string]string
+	}{
+		{
+			encoder:    AssociatedData,
+			wantString: "associatedData",
+			want:       map[string]string{"associatedData": "68c3a96c6c6f"},
+		},
+		{
+			encoder:    LegacyAdditionalData,
+			wantString: "additionalData",
+			want:       map[string]string{"additionalData": "68c3a96c6c6f"},
+		},
+		{
+			encoder:    HexEncoder("aad"),
+			wantString: "hex(aad)",
+			want:       map[string]string{"aad": "68c3a96c6c6f"},
+		},
+		{
+			encoder:    Base64Encoder("aad"),
+			wantString: "base64(aad)",
+			want:       map[string]string{"aad": "aMOpbGxv"},
+		},
+		{
+			encoder:    UTF8Encoder("aad"),
+			wantString: "utf8(aad)",
+			want:       map[string]string{"aad": "héllo"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.wantString, func(t *testing.T) {
+			if got := test.encoder.String(); got != test.wantString {
+				t.Errorf("encoder.String() = %q, want %q", got, test.wantString)
+			}
+			got, err := test.encoder.EncryptionContext(associatedData)
+			if err != nil {
+				t.Fatalf("encoder.EncryptionContext() err = %v, want nil", err)
+			}
+			if !maps.Equal(got, test.want) {
+				t.Errorf("encoder.EncryptionContext() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestUTF8Encoder_invalidUTF8Fails(t *testing.T) {
+	if _, err := UTF8Encoder("aad").EncryptionContext([]byte{0xff, 0xfe}); err == nil {
+		t.Error("UTF8Encoder(\"aad\").EncryptionContext(invalid) err = nil, want error")
+	}
+}
+
+func TestInvalidEncryptionContextName_fails(t *testing.T) {
+	if _, err := EncryptionContextName(0).EncryptionContext([]byte("ad")); err == nil {
+		t.Error("EncryptionContextName(0).EncryptionContext() err = nil, want error")
+	}
+}
+
+func TestWithEncryptionContextEncoder(t *testing.T) {
+	keyARN := "arn:aws:kms:us-east-2:235739564943:key/3ee50705-5a82-4f5b-9753-05c4f473922f"
+	keyURI := "aws-kms://arn:aws:kms:us-east-2:235739564943:key/3ee50705-5a82-4f5b-9753-05c4f473922f"
+	fakekms, err := fakeawskms.New([]string{keyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	client, err := NewClientWithOptions(t.Context(), "aws-kms://", WithKMS(fakekms), WithEncryptionContextEncoder(Base64Encoder("aws-crypto-aad")))
+	if err != nil {
+		t.Fatalf("NewClientWithOptions() failed: %v", err)
+	}
+	a, err := client.GetAEAD(keyURI)
+	if err != nil {
+		t.Fatalf("client.GetAEAD(keyURI) err = %v, want nil", err)
+	}
+
+	// Simulate a ciphertext produced by another tool using a base64 encoding.
+	plaintext := []byte("plaintext")
+	associatedData := []byte("associatedData")
+	encResponse, err := fakekms.Encrypt(t.Context(), &kms.EncryptInput{
+		KeyId:             aws.String(keyARN),
+		Plaintext:         plaintext,
+		EncryptionContext: map[string]string{"aws-crypto-aad": "YXNzb2NpYXRlZERhdGE="},
+	})
+	if err != nil {
+		t.Fatalf("fakekms.Encrypt() err = %v, want nil", err)
+	}
+	got, err := a.Decrypt(encResponse.CiphertextBlob, associatedData)
+	if err != nil {
+		t.Fatalf("a.Decrypt() err = %v, want nil", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("a.Decrypt() = %q, want %q", got, plaintext)
+	}
+}
+
+func TestEncryptionContextName_keyPolicy(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	// The key policy requires the encryption context of AssociatedData.
+	if err := fakekms.SetKeyPolicy(sourceKeyARN, fakeawskms.Policy{Statements: []fakeawskms.Statement{{
+		Operations:            []types.GrantOperation{types.GrantOperationEncrypt, types.GrantOperationDecrypt},
+		EncryptionContextKeys: []string{"associatedData"},
+	}}}); err != nil {
+		t.Fatalf("fakekms.SetKeyPolicy() failed: %v", err)
+	}
+	plaintext := []byte("plaintext")
+	associatedData := []byte("associatedData")
+
+	a := getAEAD(t, newTestClient(t, WithKMS(fakekms), WithEncryptionContextName(AssociatedData)), sourceKeyURI)
+	ciphertext, err := a.EncryptWithContext(t.Context(), plaintext, associatedData)
+	if err != nil {
+		t.Fatalf("a.EncryptWithContext() err = %v, want nil", err)
+	}
+	if _, err := a.DecryptWithContext(t.Context(), ciphertext, associatedData); err != nil {
+		t.Errorf("a.DecryptWithContext() err = %v, want nil", err)
+	}
+	// Requests with empty associated data have no encryption context.
+	if _, err := a.EncryptWithContext(t.Context(), plaintext, nil); !isAccessDenied(err) {
+		t.Errorf("a.EncryptWithContext() with empty associated data err = %v, want AccessDeniedException", err)
+	}
+	legacy := getAEAD(t, newTestClient(t, WithKMS(fakekms), WithEncryptionContextName(LegacyAdditionalData)), sourceKeyURI)
+	if _, err := legacy.EncryptWithContext(t.Context(), plaintext, associatedData); !isAccessDenied(err) {
+		t.Errorf("legacy.EncryptWithContext() err = %v, want AccessDeniedException", err)
+	}
+}
+
+func TestWithEncryptionContextEncoder_invalidFails(t *testing.T) {
+	if _, err := NewClientWithOptions(t.Context(), "aws-kms://", WithEncryptionContextEncoder(nil)); err == nil {
+		t.Error("NewClientWithOptions(t.Context(), _, WithEncryptionContextEncoder(nil)) err = nil, want error")
+	}
+	if _, err := NewClientWithOptions(t.Context(), "aws-kms://", WithEncryptionContextName(AssociatedData), WithEncryptionContextEncoder(HexEncoder("aad"))); err == nil {
+		t.Error("NewClientWithOptions(t.Context(), _, WithEncryptionContextName(_), WithEncryptionContextEncoder(_)) err = nil, want error")
+	}
+	if _, err := NewClientWithOptions(t.Context(), "aws-kms://", WithEncryptionContextEncoder(HexEncoder("aad")), WithEncryptionContextName(AssociatedData)); err == nil {
+		t.Error("NewClientWithOptions(t.Context(), _, WithEncryptionContextEncoder(_), WithEncryptionContextName(_)) err = nil, want error")
+	}
+}
+
+func TestMarshalEncryptionContext(t *testing.T) {
+	got, err := MarshalEncryptionContext(map[string]string{"b": "2", "a": "1", "c": "\"3\""})
+	if err != nil {
+		t.Fatalf("MarshalEncryptionContext() err = %v, want nil", err)
+	}
+	want := `{"a":"1","b":"2","c":"\"3\""}`
+	if string(got) != want {
+		t.Errorf("MarshalEncryptionContext() = %s, want %s", got, want)
+	}
+
+	roundTrip, err := UnmarshalEncryptionContext(got)
+	if err != nil {
+		t.Fatalf("UnmarshalEncryptionContext() err = %v, want nil", err)
+	}
+	if !maps.Equal(roundTrip, map[string]string{"b": "2", "a": "1", "c": "\"3\""}) {
+		t.Errorf("UnmarshalEncryptionContext() = %v, want original map", roundTrip)
+	}
+
+	empty, err := MarshalEncryptionContext(nil)
+	if err != nil || len(empty) != 0 {
+		t.Errorf("MarshalEncryptionContext(nil) = %q, %v, want empty, nil", empty, err)
+	}
+}
+
+func TestUnmarshalEncryptionContext_invalid(t *testing.T) {
+	for _, data := range []string{
+		"null",
+		"[]",
+		`{"a":1}`,
+		"{",
+		"abc",
+		"{}",
+		`""`,
+		`{ "a" : "1" }`,
+		`{"a":"1"} `,
+		`{"b":"2","a":"1"}`,
+		`{"a":"0","a":"1"}`,
+		`{"a":"\u0031"}`,
+	} {
+		if _, err := UnmarshalEncryptionContext([]byte(data)); err == nil {
+			t.Errorf("UnmarshalEncryptionContext(%q) err = nil, want error", data)
+		}
+	}
+}
+
+func TestWithSerializedEncryptionContext_bindsAssociatedDataBytes(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	a := getAEAD(t, newTestClient(t, WithKMS(fakekms), WithSerializedEncryptionContext()), sourceKeyURI)
+	associatedData := []byte(`{"a":"1","b":"2"}`)
+	ciphertext, err := a.EncryptWithContext(t.Context(), []byte("plaintext"), associatedData)
+	if err != nil {
+		t.Fatalf("a.EncryptWithContext() err = %v, want nil", err)
+	}
+	for _, other := range []string{`{ "a":"1","b":"2"}`, `{"b":"2","a":"1"}`, `{"a":"0","a":"1","b":"2"}`} {
+		if _, err := a.DecryptWithContext(t.Context(), ciphertext, []byte(other)); err == nil {
+			t.Errorf("a.DecryptWithContext() with associated data %s err = nil, want error", other)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+
+	"github.com/tink-crypto/tink-go/v2/aead/subtle"
+)
+
+// ErrPlaintextTooLarge is returned when encrypting a plaintext which exceeds
+// the size limit of the encryption algorithm, which is 4096 bytes for
+// SYMMETRIC_DEFAULT, unless [WithEnvelopeFallback] is used.
+var ErrPlaintextTooLarge = errors.New("plaintext too large for AWS KMS")
+
+// ErrEnvelopeFallbackDisabled is returned when decrypting or re-encrypting a
+// ciphertext of [WithEnvelopeFallback] with a client which does not use that
+// option.
+var ErrEnvelopeFallbackDisabled = errors.New("envelope ciphertexts require WithEnvelopeFallback")
+
+// envelopeHeader starts the ciphertexts of [WithEnvelopeFallback]. AWS KMS
+// ciphertexts of symmetric keys start with a version byte of 1, so they
+// cannot be confused with envelope ciphertexts.
+var envelopeHeader = []byte{'T', 'K', 'A', 'E', 1}
+
+// envelopeKeySize is the size of the AES-256-GCM data keys of envelope
+// ciphertexts.
+const envelopeKeySize = 32
+
+// WithEnvelopeFallback makes AEAD primitives envelope encrypt plaintexts which
+// exceed the size limit of AWS KMS, instead of failing with
+// [ErrPlaintextTooLarge]. This lets callers of the generic tink.AEAD interface
+// encrypt data of any size.
+//
+// An oversized plaintext is encrypted with a random AES-256-GCM data key and
+// the associated data, and the data key is encrypted by AWS KMS with the same
+// associated data. The ciphertext has the format
+//
+//	"TKAE" || 0x01 || len(encrypted key) (4 bytes, big endian) || encrypted key || AES-GCM ciphertext
+//
+// Plaintexts within the limit are encrypted directly by AWS KMS, as without
+// this option. Decryption recognizes both formats, while AEAD primitives of
+// clients without this option reject envelope ciphertexts with
+// [ErrEnvelopeFallbackDisabled], so that data encrypted outside of AWS KMS is
+// only accepted by clients which opted in.
+//
+// [Client.ReEncrypt] re-encrypts only the encrypted data key of envelope
+// ciphertexts, which requires that the associated data does not change.
+func WithEnvelopeFallback() ClientOption {
+	return option(func(ctx context.Context, a *awsClient) error {
+		if a.envelopeFallback {
+			return errors.New("WithEnvelopeFallback option cannot be used, envelope fallback already enabled")
+		}
+		a.envelopeFallback = true
+		return nil
+	})
+}
+
+// isEnvelope reports whether ciphertext was produced by encryptEnvelope.
+func isEnvelope(ciphertext []byte) bool {
+	return bytes.HasPrefix(ciphertext, envelopeHeader)
+}
+
+// encryptEnvelope encrypts plaintext with a data key encrypted by AWS KMS.
+func (a *awsAEAD) encryptEnvelope(ctx context.Context, plaintext, associatedData []byte) ([]byte, error) {
+	dek := make([]byte, envelopeKeySize)
+	if _, err := rand.Read(dek); err != nil {
+		return nil, err
+	}
+	defer clear(dek)
+	encryptedKey, err := a.encryptKMS(ctx, dek, associatedData)
+	if err != nil {
+		return nil, err
+	}
+	payloadAEAD, err := subtle.NewAESGCM(dek)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := payloadAEAD.Encrypt(plaintext, associatedData)
+	if err != nil {
+		return nil, err
+	}
+	return formatEnvelope(encryptedKey, payload), nil
+}
+
+// formatEnvelope returns the envelope ciphertext of encryptedKey and payload.
+func formatEnvelope(encryptedKey, payload []byte) []byte {
+	ciphertext := make([]byte, 0, len(envelopeHeader)+4+len(encryptedKey)+len(payload))
+	ciphertext = append(ciphertext, envelopeHeader...)
+	ciphertext = binary.BigEndian.AppendUint32(ciphertext, uint32(len(encryptedKey)))
+	ciphertext = append(ciphertext, encryptedKey...)
+	return append(ciphertext, payload...)
+}
+
+// parseEnvelope returns the encrypted key and payload of an envelope
+// ciphertext.
+func parseEnvelope(ciphertext []byte) (encryptedKey, payload []byte, err error) {
+	rest := ciphertext[len(envelopeHeader):]
+	if len(rest) < 4 {
+		return nil, nil, errors.New("invalid envelope ciphertext: truncated header")
+	}
+	keySize := binary.BigEndian.Uint32(rest)
+	rest = rest[4:]
+	if uint64(keySize) > uint64(len(rest)) {
+		return nil, nil, errors.New("invalid envelope ciphertext: truncated encrypted key")
+	}
+	return rest[:keySize], rest[keySize:], nil
+}
+
+// decryptEnvelope decrypts a ciphertext produced by encryptEnvelope.
+func (a *awsAEAD) decryptEnvelope(ctx context.Context, ciphertext, associatedData []byte) ([]byte, error) {
+	if !a.envelopeFallback {
+		return nil, ErrEnvelopeFallbackDisabled
+	}
+	encryptedKey, payload, err := parseEnvelope(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	dek, err := a.decryptKMS(ctx, encryptedKey, associatedData)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(dek)
+	payloadAEAD, err := subtle.NewAESGCM(dek)
+	if err != nil {
+		return nil, err
+	}
+	return payloadAEAD.Decrypt(payload, associatedData)
+}
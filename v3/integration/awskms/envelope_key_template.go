@@ -0,0 +1,61 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tink-crypto/tink-go/v2/aead"
+	tinkpb "github.com/tink-crypto/tink-go/v2/proto/tink_go_proto"
+)
+
+// EnvelopeKeyTemplate returns a key template for KMS envelope AEAD keys whose
+// data encryption keys, generated from dekTemplate, are encrypted with the AWS
+// KMS key keyURI. Unlike the AEADs of [aead.NewKMSEnvelopeAEAD2], such keys
+// can be added to any AEAD keyset, for example to rotate from one AWS KMS key
+// to another with a [keyset.Manager].
+//
+// The keys only reference keyURI. When a primitive is created from a keyset
+// containing them, Tink resolves the remote AEAD with the client registered
+// with registry.RegisterKMSClient which supports keyURI, so a client created
+// with [NewClientWithOptions] must be registered before. Its options, such as
+// [WithGrantTokens], apply to the requests.
+//
+// The data encryption keys are encrypted with empty associated data, so they
+// have no AWS KMS encryption context, regardless of the encryption context
+// encoder of the client. The associated data only authenticates the payload.
+// This does not weaken the ciphertexts: each data encryption key is generated
+// for a single ciphertext, and its payload can only be decrypted with it, so
+// an encrypted data key moved to another ciphertext fails to decrypt it.
+// However, AWS KMS key policies and CloudTrail logs cannot refer to the
+// associated data. Use the AEADs of a client with [WithEnvelopeFallback] if
+// they must.
+//
+// dekTemplate must be a template of one of the AEAD key types supported by
+// [aead.CreateKMSEnvelopeAEADKeyTemplate], such as
+// aead.AES256GCMKeyTemplate().
+//
+// [keyset.Manager]: https://pkg.go.dev/github.com/tink-crypto/tink-go/v2/keyset#Manager
+func EnvelopeKeyTemplate(keyURI string, dekTemplate *tinkpb.KeyTemplate) (*tinkpb.KeyTemplate, error) {
+	if !strings.HasPrefix(keyURI, awsPrefix) || len(keyURI) == len(awsPrefix) {
+		return nil, fmt.Errorf("keyURI must be an AWS KMS key URI starting with %s, but got %s", awsPrefix, keyURI)
+	}
+	if dekTemplate == nil {
+		return nil, errors.New("dekTemplate must not be nil")
+	}
+	return aead.CreateKMSEnvelopeAEADKeyTemplate(keyURI, dekTemplate)
+}
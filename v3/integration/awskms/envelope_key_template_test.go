@@ -0,0 +1,210 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"bytes"
+	"encoding/binary"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/tink-crypto/tink-go-awskms/v3/integration/awskms/internal/fakeawskms"
+	"github.com/tink-crypto/tink-go/v2/aead"
+	"github.com/tink-crypto/tink-go/v2/core/registry"
+	"github.com/tink-crypto/tink-go/v2/keyset"
+	"github.com/tink-crypto/tink-go/v2/mac"
+	tinkpb "github.com/tink-crypto/tink-go/v2/proto/tink_go_proto"
+)
+
+func TestEnvelopeKeyTemplate(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN, destinationKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	metrics := &fakeMetrics{}
+	registry.RegisterKMSClient(newTestClient(t, WithKMS(fakekms), WithMetrics(metrics)))
+	t.Cleanup(registry.ClearKMSClients)
+
+	template, err := EnvelopeKeyTemplate(sourceKeyURI, aead.AES256GCMKeyTemplate())
+	if err != nil {
+		t.Fatalf("EnvelopeKeyTemplate() err = %v, want nil", err)
+	}
+	manager := keyset.NewManager()
+	oldKeyID, err := manager.Add(template)
+	if err != nil {
+		t.Fatalf("manager.Add() err = %v, want nil", err)
+	}
+	if err := manager.SetPrimary(oldKeyID); err != nil {
+		t.Fatalf("manager.SetPrimary() err = %v, want nil", err)
+	}
+	handle, err := manager.Handle()
+	if err != nil {
+		t.Fatalf("manager.Handle() err = %v, want nil", err)
+	}
+	primitive, err := aead.New(handle)
+	if err != nil {
+		t.Fatalf("aead.New() err = %v, want nil", err)
+	}
+	plaintext := []byte("plaintext")
+	associatedData := []byte("associatedData")
+	oldCiphertext, err := primitive.Encrypt(plaintext, associatedData)
+	if err != nil {
+		t.Fatalf("primitive.Encrypt() err = %v, want nil", err)
+	}
+	if got := countCalls(metrics, "Encrypt"); got != 1 {
+		t.Errorf("Encrypt calls = %d, want 1", got)
+	}
+
+	// Rotate to the destination key.
+	template, err = EnvelopeKeyTemplate(destinationKeyURI, aead.AES128CTRHMACSHA256KeyTemplate())
+	if err != nil {
+		t.Fatalf("EnvelopeKeyTemplate() err = %v, want nil", err)
+	}
+	newKeyID, err := manager.Add(template)
+	if err != nil {
+		t.Fatalf("manager.Add() err = %v, want nil", err)
+	}
+	if err := manager.SetPrimary(newKeyID); err != nil {
+		t.Fatalf("manager.SetPrimary() err = %v, want nil", err)
+	}
+	handle, err = manager.Handle()
+	if err != nil {
+		t.Fatalf("manager.Handle() err = %v, want nil", err)
+	}
+	primitive, err = aead.New(handle)
+	if err != nil {
+		t.Fatalf("aead.New() err = %v, want nil", err)
+	}
+	newCiphertext, err := primitive.Encrypt(plaintext, associatedData)
+	if err != nil {
+		t.Fatalf("primitive.Encrypt() err = %v, want nil", err)
+	}
+	for _, ciphertext := range [][]byte{oldCiphertext, newCiphertext} {
+		got, err := primitive.Decrypt(ciphertext, associatedData)
+		if err != nil {
+			t.Fatalf("primitive.Decrypt() err = %v, want nil", err)
+		}
+		if !bytes.Equal(got, plaintext) {
+			t.Errorf("primitive.Decrypt() = %q, want %q", got, plaintext)
+		}
+		if _, err := primitive.Decrypt(ciphertext, []byte("other")); err == nil {
+			t.Error("primitive.Decrypt() with wrong associated data err = nil, want error")
+		}
+	}
+
+	// The new ciphertext is only decryptable with the destination key.
+	sourceOnly, err := keyset.NewHandle(mustTemplate(t, sourceKeyURI))
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() err = %v, want nil", err)
+	}
+	sourcePrimitive, err := aead.New(sourceOnly)
+	if err != nil {
+		t.Fatalf("aead.New() err = %v, want nil", err)
+	}
+	if _, err := sourcePrimitive.Decrypt(newCiphertext, associatedData); err == nil {
+		t.Error("sourcePrimitive.Decrypt(newCiphertext) err = nil, want error")
+	}
+}
+
+func TestEnvelopeKeyTemplate_encryptedKeyIsBoundByPayload(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	registry.RegisterKMSClient(newTestClient(t, WithKMS(fakekms), WithEncryptionContextName(AssociatedData)))
+	t.Cleanup(registry.ClearKMSClients)
+	handle, err := keyset.NewHandle(mustTemplate(t, sourceKeyURI))
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() err = %v, want nil", err)
+	}
+	primitive, err := aead.New(handle)
+	if err != nil {
+		t.Fatalf("aead.New() err = %v, want nil", err)
+	}
+	associatedData := []byte("associatedData")
+	ciphertext1, err := primitive.Encrypt([]byte("plaintext 1"), associatedData)
+	if err != nil {
+		t.Fatalf("primitive.Encrypt() err = %v, want nil", err)
+	}
+	ciphertext2, err := primitive.Encrypt([]byte("plaintext 2"), associatedData)
+	if err != nil {
+		t.Fatalf("primitive.Encrypt() err = %v, want nil", err)
+	}
+
+	// The keys have no output prefix, so a ciphertext consists of the length
+	// of the encrypted data key, the encrypted data key and the payload.
+	split := func(ciphertext []byte) (encryptedKey, payload []byte) {
+		n := binary.BigEndian.Uint32(ciphertext)
+		return ciphertext[4 : 4+n], ciphertext[4+n:]
+	}
+	encryptedKey1, payload1 := split(ciphertext1)
+	encryptedKey2, _ := split(ciphertext2)
+
+	// The data key is encrypted without encryption context, whatever the
+	// encoder of the client.
+	if _, err := fakekms.Decrypt(t.Context(), &kms.DecryptInput{CiphertextBlob: encryptedKey1}); err != nil {
+		t.Errorf("fakekms.Decrypt() of the encrypted data key without encryption context err = %v, want nil", err)
+	}
+	// But it cannot be used with another payload, which it does not
+	// authenticate.
+	swapped := slices.Concat(binary.BigEndian.AppendUint32(nil, uint32(len(encryptedKey2))), encryptedKey2, payload1)
+	if _, err := primitive.Decrypt(swapped, associatedData); err == nil {
+		t.Error("primitive.Decrypt() with the encrypted data key of another ciphertext err = nil, want error")
+	}
+}
+
+func mustTemplate(t *testing.T, keyURI string) *tinkpb.KeyTemplate {
+	t.Helper()
+	template, err := EnvelopeKeyTemplate(keyURI, aead.AES256GCMKeyTemplate())
+	if err != nil {
+		t.Fatalf("EnvelopeKeyTemplate() err = %v, want nil", err)
+	}
+	return template
+}
+
+func TestEnvelopeKeyTemplate_withoutRegisteredClientFails(t *testing.T) {
+	registry.ClearKMSClients()
+	template, err := EnvelopeKeyTemplate(sourceKeyURI, aead.AES256GCMKeyTemplate())
+	if err != nil {
+		t.Fatalf("EnvelopeKeyTemplate() err = %v, want nil", err)
+	}
+	handle, err := keyset.NewHandle(template)
+	if err != nil {
+		t.Fatalf("keyset.NewHandle() err = %v, want nil", err)
+	}
+	if _, err := aead.New(handle); err == nil {
+		t.Error("aead.New() without registered client err = nil, want error")
+	}
+}
+
+func TestEnvelopeKeyTemplate_invalidArgumentsFail(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		keyURI string
+		dek    *tinkpb.KeyTemplate
+	}{
+		{"GCP key URI", "gcp-kms://projects/p/locations/l/keyRings/r/cryptoKeys/k", aead.AES256GCMKeyTemplate()},
+		{"empty key ID", "aws-kms://", aead.AES256GCMKeyTemplate()},
+		{"nil DEK template", sourceKeyURI, nil},
+		{"MAC DEK template", sourceKeyURI, mac.HMACSHA256Tag256KeyTemplate()},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := EnvelopeKeyTemplate(test.keyURI, test.dek); err == nil {
+				t.Error("EnvelopeKeyTemplate() err = nil, want error")
+			}
+		})
+	}
+}
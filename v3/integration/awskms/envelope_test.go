@@ -0,0 +1,175 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/tink-crypto/tink-go-awskms/v3/integration/awskms/internal/fakeawskms"
+)
+
+func TestPlaintextTooLarge(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	metrics := &fakeMetrics{}
+	a := getAEAD(t, newTestClient(t, WithKMS(fakekms), WithMetrics(metrics)), sourceKeyURI)
+	if _, err := a.EncryptWithContext(t.Context(), make([]byte, 4096), nil); err != nil {
+		t.Errorf("a.EncryptWithContext() with 4096 bytes err = %v, want nil", err)
+	}
+	calls := len(metrics.calls)
+	if _, err := a.EncryptWithContext(t.Context(), make([]byte, 4097), nil); !errors.Is(err, ErrPlaintextTooLarge) {
+		t.Errorf("a.EncryptWithContext() with 4097 bytes err = %v, want ErrPlaintextTooLarge", err)
+	}
+	if len(metrics.calls) != calls {
+		t.Errorf("AWS KMS calls of too large plaintext = %d, want 0", len(metrics.calls)-calls)
+	}
+}
+
+func TestWithEnvelopeFallback(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	a := getAEAD(t, newTestClient(t, WithKMS(fakekms), WithEnvelopeFallback()), sourceKeyURI)
+	withoutFallback := getAEAD(t, newTestClient(t, WithKMS(fakekms)), sourceKeyURI)
+	associatedData := []byte("associatedData")
+
+	for _, test := range []struct {
+		name         string
+		size         int
+		wantEnvelope bool
+	}{
+		{"empty", 0, false},
+		{"limit", 4096, false},
+		{"above limit", 4097, true},
+		{"large", 1 << 20, true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			plaintext := bytes.Repeat([]byte("a"), test.size)
+			ciphertext, err := a.EncryptWithContext(t.Context(), plaintext, associatedData)
+			if err != nil {
+				t.Fatalf("a.EncryptWithContext() err = %v, want nil", err)
+			}
+			if got := isEnvelope(ciphertext); got != test.wantEnvelope {
+				t.Errorf("isEnvelope(ciphertext) = %v, want %v", got, test.wantEnvelope)
+			}
+			got, err := a.DecryptWithContext(t.Context(), ciphertext, associatedData)
+			if err != nil {
+				t.Fatalf("a.DecryptWithContext() err = %v, want nil", err)
+			}
+			if !bytes.Equal(got, plaintext) {
+				t.Errorf("a.DecryptWithContext() = %d bytes, want %d bytes", len(got), len(plaintext))
+			}
+			// Without the option, only envelope ciphertexts are rejected.
+			_, err = withoutFallback.DecryptWithContext(t.Context(), ciphertext, associatedData)
+			if test.wantEnvelope && !errors.Is(err, ErrEnvelopeFallbackDisabled) {
+				t.Errorf("withoutFallback.DecryptWithContext() err = %v, want %v", err, ErrEnvelopeFallbackDisabled)
+			}
+			if !test.wantEnvelope && err != nil {
+				t.Errorf("withoutFallback.DecryptWithContext() err = %v, want nil", err)
+			}
+			if _, err := a.DecryptWithContext(t.Context(), ciphertext, []byte("other")); err == nil {
+				t.Error("a.DecryptWithContext() with wrong associated data err = nil, want error")
+			}
+		})
+	}
+}
+
+func TestWithEnvelopeFallback_invalidCiphertextFails(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	a := getAEAD(t, newTestClient(t, WithKMS(fakekms), WithEnvelopeFallback()), sourceKeyURI)
+	associatedData := []byte("associatedData")
+	ciphertext, err := a.EncryptWithContext(t.Context(), make([]byte, 5000), associatedData)
+	if err != nil {
+		t.Fatalf("a.EncryptWithContext() err = %v, want nil", err)
+	}
+	tampered := bytes.Clone(ciphertext)
+	tampered[len(tampered)-1] ^= 1
+	for _, test := range []struct {
+		name       string
+		ciphertext []byte
+	}{
+		{"tampered payload", tampered},
+		{"truncated", ciphertext[:len(envelopeHeader)+2]},
+		{"truncated encrypted key", ciphertext[:len(envelopeHeader)+10]},
+		{"header only", envelopeHeader},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := a.DecryptWithContext(t.Context(), test.ciphertext, associatedData); err == nil {
+				t.Error("a.DecryptWithContext() err = nil, want error")
+			}
+		})
+	}
+}
+
+func TestWithEnvelopeFallback_decryptWithoutOptionFails(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	associatedData := []byte("associatedData")
+	ciphertext, err := getAEAD(t, newTestClient(t, WithKMS(fakekms), WithEnvelopeFallback()), sourceKeyURI).EncryptWithContext(t.Context(), make([]byte, 5000), associatedData)
+	if err != nil {
+		t.Fatalf("a.EncryptWithContext() err = %v, want nil", err)
+	}
+	a := getAEAD(t, newTestClient(t, WithKMS(fakekms)), sourceKeyURI)
+	if _, err := a.DecryptWithContext(t.Context(), ciphertext, associatedData); !errors.Is(err, ErrEnvelopeFallbackDisabled) {
+		t.Errorf("a.DecryptWithContext() without WithEnvelopeFallback err = %v, want %v", err, ErrEnvelopeFallbackDisabled)
+	}
+}
+
+func TestWithEnvelopeFallback_encryptionContextNotEnveloped(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	a := getAEAD(t, newTestClient(t, WithKMS(fakekms), WithEnvelopeFallback()), sourceKeyURI).(EncryptionContextAEAD)
+	if _, err := a.EncryptWithEncryptionContext(t.Context(), make([]byte, 5000), nil); !errors.Is(err, ErrPlaintextTooLarge) {
+		t.Errorf("a.EncryptWithEncryptionContext() err = %v, want ErrPlaintextTooLarge", err)
+	}
+}
+
+func TestWithEnvelopeFallback_dryRun(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	a := getAEAD(t, newTestClient(t, WithKMS(fakekms), WithEnvelopeFallback(), WithDryRun()), sourceKeyURI)
+	details := &OperationDetails{}
+	got, err := a.EncryptWithContext(ContextWithOperationDetails(t.Context(), details), make([]byte, 5000), nil)
+	if !errors.Is(err, ErrDryRunSucceeded) || got != nil {
+		t.Errorf("a.EncryptWithContext() = %d bytes, %v, want nil, ErrDryRunSucceeded", len(got), err)
+	}
+	if details.DryRun == nil {
+		t.Error("details.DryRun = nil, want dry run result")
+	}
+}
+
+func TestWithEnvelopeFallback_twiceFails(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	if _, err := NewClientWithOptions(t.Context(), "aws-kms://", WithKMS(fakekms), WithEnvelopeFallback(), WithEnvelopeFallback()); err == nil {
+		t.Error("NewClientWithOptions() err = nil, want error")
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"context"
+	"errors"
+	"slices"
+)
+
+// WithGrantTokens sets grant tokens which are sent with all AWS KMS requests
+// using a key, so that grants can be used before they are eventually
+// consistent.
+//
+// To use grant tokens with a single AEAD, pass this option to
+// [NewAEADWithContext]. Tokens for a single call can be added with
+// [ContextWithGrantTokens].
+func WithGrantTokens(tokens ...string) ClientOption {
+	return option(func(ctx context.Context, a *awsClient) error {
+		if len(tokens) == 0 {
+			return errors.New("WithGrantTokens option requires at least one token")
+		}
+		if a.grantTokens != nil {
+			return errors.New("WithGrantTokens option cannot be used, grant tokens already set")
+		}
+		a.grantTokens = slices.Clone(tokens)
+		return nil
+	})
+}
+
+type grantTokensKey struct{}
+
+// ContextWithGrantTokens returns a copy of ctx with grant tokens which are
+// sent, in addition to those set with [WithGrantTokens], with the AWS KMS
+// requests of operations called with this context.
+func ContextWithGrantTokens(ctx context.Context, tokens ...string) context.Context {
+	return context.WithValue(ctx, grantTokensKey{}, slices.Concat(contextGrantTokens(ctx), tokens))
+}
+
+func contextGrantTokens(ctx context.Context) []string {
+	tokens, _ := ctx.Value(grantTokensKey{}).([]string)
+	return tokens
+}
+
+// grantTokens returns the configured grant tokens followed by those of ctx,
+// without duplicates, or nil if there are none.
+func grantTokens(ctx context.Context, configured []string) []string {
+	var tokens []string
+	for _, t := range slices.Concat(configured, contextGrantTokens(ctx)) {
+		if !slices.Contains(tokens, t) {
+			tokens = append(tokens, t)
+		}
+	}
+	return tokens
+}
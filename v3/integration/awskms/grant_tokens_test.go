@@ -0,0 +1,146 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/aws/smithy-go"
+	"github.com/tink-crypto/tink-go-awskms/v3/integration/awskms/internal/fakeawskms"
+)
+
+func isAccessDenied(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "AccessDeniedException"
+}
+
+func TestWithGrantTokens(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	token, err := fakekms.AddGrant(sourceKeyARN, types.GrantOperationEncrypt, types.GrantOperationDecrypt)
+	if err != nil {
+		t.Fatalf("fakekms.AddGrant() failed: %v", err)
+	}
+	plaintext := []byte("plaintext")
+	associatedData := []byte("associatedData")
+
+	withoutToken := getAEAD(t, newTestClient(t, WithKMS(fakekms)), sourceKeyURI)
+	if _, err := withoutToken.EncryptWithContext(t.Context(), plaintext, associatedData); !isAccessDenied(err) {
+		t.Errorf("a.EncryptWithContext() without grant token err = %v, want AccessDeniedException", err)
+	}
+
+	a := getAEAD(t, newTestClient(t, WithKMS(fakekms), WithGrantTokens(token)), sourceKeyURI)
+	ciphertext, err := a.EncryptWithContext(t.Context(), plaintext, associatedData)
+	if err != nil {
+		t.Fatalf("a.EncryptWithContext() err = %v, want nil", err)
+	}
+	got, err := a.DecryptWithContext(t.Context(), ciphertext, associatedData)
+	if err != nil {
+		t.Fatalf("a.DecryptWithContext() err = %v, want nil", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("a.DecryptWithContext() = %q, want %q", got, plaintext)
+	}
+}
+
+func TestNewAEADWithContext_grantTokens(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	token, err := fakekms.AddGrant(sourceKeyARN, types.GrantOperationEncrypt)
+	if err != nil {
+		t.Fatalf("fakekms.AddGrant() failed: %v", err)
+	}
+	a, err := NewAEADWithContext(t.Context(), sourceKeyARN, WithKMS(fakekms), WithGrantTokens(token))
+	if err != nil {
+		t.Fatalf("NewAEADWithContext() err = %v, want nil", err)
+	}
+	if _, err := a.EncryptWithContext(t.Context(), []byte("plaintext"), nil); err != nil {
+		t.Errorf("a.EncryptWithContext() err = %v, want nil", err)
+	}
+}
+
+func TestContextWithGrantTokens(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	token, err := fakekms.AddGrant(sourceKeyARN, types.GrantOperationEncrypt)
+	if err != nil {
+		t.Fatalf("fakekms.AddGrant() failed: %v", err)
+	}
+	a := getAEAD(t, newTestClient(t, WithKMS(fakekms), WithGrantTokens("other-token")), sourceKeyURI)
+	ctx := ContextWithGrantTokens(t.Context(), token)
+	if _, err := a.EncryptWithContext(ctx, []byte("plaintext"), nil); err != nil {
+		t.Errorf("a.EncryptWithContext() with grant token in context err = %v, want nil", err)
+	}
+	if _, err := a.EncryptWithContext(t.Context(), []byte("plaintext"), nil); !isAccessDenied(err) {
+		t.Errorf("a.EncryptWithContext() without grant token err = %v, want AccessDeniedException", err)
+	}
+}
+
+func TestReEncrypt_grantTokens(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN, destinationKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	token, err := fakekms.AddGrant(destinationKeyARN, types.GrantOperationReEncryptTo)
+	if err != nil {
+		t.Fatalf("fakekms.AddGrant() failed: %v", err)
+	}
+	client := newTestClient(t, WithKMS(fakekms))
+	associatedData := []byte("associatedData")
+	ciphertext := mustEncrypt(t, client, sourceKeyURI, []byte("plaintext"), associatedData)
+
+	if _, err := client.ReEncrypt(t.Context(), ciphertext, associatedData, destinationKeyURI, associatedData); !isAccessDenied(err) {
+		t.Errorf("client.ReEncrypt() without grant token err = %v, want AccessDeniedException", err)
+	}
+	ctx := ContextWithGrantTokens(t.Context(), token)
+	if _, err := client.ReEncrypt(ctx, ciphertext, associatedData, destinationKeyURI, associatedData); err != nil {
+		t.Errorf("client.ReEncrypt() with grant token err = %v, want nil", err)
+	}
+}
+
+func TestGrantTokens(t *testing.T) {
+	ctx := ContextWithGrantTokens(ContextWithGrantTokens(t.Context(), "b", "c"), "a", "d")
+	if got, want := grantTokens(ctx, []string{"a", "b"}), []string{"a", "b", "c", "d"}; !slices.Equal(got, want) {
+		t.Errorf("grantTokens() = %q, want %q", got, want)
+	}
+	if got := grantTokens(t.Context(), nil); got != nil {
+		t.Errorf("grantTokens() = %q, want nil", got)
+	}
+}
+
+func TestWithGrantTokens_invalidFails(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	for _, opts := range [][]ClientOption{
+		{WithGrantTokens()},
+		{WithGrantTokens("a"), WithGrantTokens("b")},
+	} {
+		if _, err := NewClientWithOptions(t.Context(), "aws-kms://", append([]ClientOption{WithKMS(fakekms)}, opts...)...); err == nil {
+			t.Error("NewClientWithOptions() err = nil, want error")
+		}
+	}
+}
@@ -0,0 +1,381 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// instrumentedKMS wraps a KMSAPI and reports every call to the configured
+// observers. It is only installed if at least one observer is configured, so
+// that clients without instrumentation call AWS KMS directly.
+type instrumentedKMS struct {
+	kms     KMSAPI
+	metrics Metrics
+	audit   AuditSink
+	logger  *slog.Logger
+}
+
+var (
+	_ KMSAPI                 = (*instrumentedKMS)(nil)
+	_ reEncryptAPI           = (*instrumentedKMS)(nil)
+	_ describeKeyAPI         = (*instrumentedKMS)(nil)
+	_ generateDataKeyAPI     = (*instrumentedKMS)(nil)
+	_ generateDataKeyPairAPI = (*instrumentedKMS)(nil)
+	_ generateRandomAPI      = (*instrumentedKMS)(nil)
+)
+
+// instrument returns k wrapped with the observers configured on a, or k
+// itself if there are none.
+func instrument(k KMSAPI, a *awsClient) KMSAPI {
+	if a.metrics == nil && a.audit == nil && a.logger == nil {
+		return k
+	}
+	return &instrumentedKMS{
+		kms:     k,
+		metrics: a.metrics,
+		audit:   a.audit,
+		logger:  a.logger,
+	}
+}
+
+// callInfo holds what the observers need to know about a single call.
+type callInfo struct {
+	operation         string
+	keyID             string
+	encryptionContext map[string]string
+	start             time.Time
+	requestBytes      int
+
+	// Populated from the response.
+	responseBytes int
+	keyARN        string
+	metadata      middleware.Metadata
+}
+
+// observe reports the call described by c, which completed with err, to the
+// configured observers. It returns an error if the call must fail because it
+// could not be audited.
+func (k *instrumentedKMS) observe(ctx context.Context, c *callInfo, err error) error {
+	if k.logger != nil {
+		k.log(ctx, c, err)
+	}
+	if k.metrics != nil {
+		k.metrics.RecordCall(ctx, CallRecord{
+			Operation:     c.operation,
+			KeyID:         c.keyID,
+			Duration:      time.Since(c.start),
+			RequestBytes:  c.requestBytes,
+			ResponseBytes: c.responseBytes,
+			ErrorKind:     errorKind(err),
+		})
+	}
+	if k.audit != nil {
+		event := AuditEvent{
+			Time:                  c.start.UTC(),
+			Operation:             c.operation,
+			KeyARN:                c.keyARN,
+			EncryptionContextKeys: sortedKeys(c.encryptionContext),
+			Outcome:               OutcomeSuccess,
+			RequestID:             requestID(c.metadata, err),
+		}
+		if event.KeyARN == "" {
+			event.KeyARN = c.keyID
+		}
+		if p, ok := PrincipalFromContext(ctx); ok {
+			event.Principal = p
+		}
+		if err != nil {
+			event.Outcome = OutcomeFailure
+			event.ErrorKind = errorKind(err)
+		}
+		if auditErr := k.audit.Emit(ctx, event); auditErr != nil {
+			return fmt.Errorf("emitting audit event failed: %w", auditErr)
+		}
+	}
+	return nil
+}
+
+// log logs the call described by c. Only the names of the encryption context
+// entries are logged, since their values may be derived from sensitive
+// associated data.
+func (k *instrumentedKMS) log(ctx context.Context, c *callInfo, err error) {
+	attrs := []slog.Attr{
+		slog.String("operation", c.operation),
+		slog.String("key_id", c.keyID),
+		slog.Any("encryption_context_keys", sortedKeys(c.encryptionContext)),
+		slog.Duration("duration", time.Since(c.start)),
+	}
+	if err != nil {
+		attrs = append(attrs,
+			slog.String("error_kind", errorKind(err)),
+			slog.String("request_id", requestID(c.metadata, err)),
+			slog.String("error", err.Error()))
+		k.logger.LogAttrs(ctx, slog.LevelWarn, "awskms: AWS KMS call failed", attrs...)
+		return
+	}
+	if results, ok := retry.GetAttemptResults(c.metadata); ok {
+		attrs = append(attrs, slog.Int("attempts", len(results.Results)))
+	}
+	attrs = append(attrs, slog.String("request_id", requestID(c.metadata, nil)))
+	k.logger.LogAttrs(ctx, slog.LevelDebug, "awskms: AWS KMS call succeeded", attrs...)
+}
+
+// after reports the call described by c. It returns err, or an error
+// describing why the call could not be audited.
+func (k *instrumentedKMS) after(ctx context.Context, c *callInfo, err error) error {
+	if obsErr := k.observe(ctx, c, err); obsErr != nil && err == nil {
+		return obsErr
+	}
+	return err
+}
+
+func (k *instrumentedKMS) Encrypt(ctx context.Context, params *kms.EncryptInput, optFns ...func(*kms.Options)) (*kms.EncryptOutput, error) {
+	c := &callInfo{
+		operation:         "Encrypt",
+		keyID:             aws.ToString(params.KeyId),
+		encryptionContext: params.EncryptionContext,
+		start:             time.Now(),
+		requestBytes:      len(params.Plaintext),
+	}
+	resp, err := k.kms.Encrypt(ctx, params, optFns...)
+	if err == nil {
+		c.responseBytes = len(resp.CiphertextBlob)
+		c.keyARN = aws.ToString(resp.KeyId)
+		c.metadata = resp.ResultMetadata
+	}
+	if err := k.after(ctx, c, err); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (k *instrumentedKMS) Decrypt(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error) {
+	c := &callInfo{
+		operation:         "Decrypt",
+		keyID:             aws.ToString(params.KeyId),
+		encryptionContext: params.EncryptionContext,
+		start:             time.Now(),
+		requestBytes:      len(params.CiphertextBlob),
+	}
+	resp, err := k.kms.Decrypt(ctx, params, optFns...)
+	if err == nil {
+		c.responseBytes = len(resp.Plaintext)
+		c.keyARN = aws.ToString(resp.KeyId)
+		c.metadata = resp.ResultMetadata
+	}
+	if err := k.after(ctx, c, err); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (k *instrumentedKMS) ReEncrypt(ctx context.Context, params *kms.ReEncryptInput, optFns ...func(*kms.Options)) (*kms.ReEncryptOutput, error) {
+	r, ok := k.kms.(reEncryptAPI)
+	if !ok {
+		return nil, ErrReEncryptUnsupported
+	}
+	c := &callInfo{
+		operation:         "ReEncrypt",
+		keyID:             aws.ToString(params.DestinationKeyId),
+		encryptionContext: params.DestinationEncryptionContext,
+		start:             time.Now(),
+		requestBytes:      len(params.CiphertextBlob),
+	}
+	resp, err := r.ReEncrypt(ctx, params, optFns...)
+	if err == nil {
+		c.responseBytes = len(resp.CiphertextBlob)
+		c.keyARN = aws.ToString(resp.KeyId)
+		c.metadata = resp.ResultMetadata
+	}
+	if err := k.after(ctx, c, err); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (k *instrumentedKMS) DescribeKey(ctx context.Context, params *kms.DescribeKeyInput, optFns ...func(*kms.Options)) (*kms.DescribeKeyOutput, error) {
+	d, ok := k.kms.(describeKeyAPI)
+	if !ok {
+		return nil, errDescribeKeyUnsupported
+	}
+	c := &callInfo{
+		operation: "DescribeKey",
+		keyID:     aws.ToString(params.KeyId),
+		start:     time.Now(),
+	}
+	resp, err := d.DescribeKey(ctx, params, optFns...)
+	if err == nil {
+		if resp.KeyMetadata != nil {
+			c.keyARN = aws.ToString(resp.KeyMetadata.Arn)
+		}
+		c.metadata = resp.ResultMetadata
+	}
+	if err := k.after(ctx, c, err); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (k *instrumentedKMS) GenerateDataKey(ctx context.Context, params *kms.GenerateDataKeyInput, optFns ...func(*kms.Options)) (*kms.GenerateDataKeyOutput, error) {
+	g, ok := k.kms.(generateDataKeyAPI)
+	if !ok {
+		return nil, errGenerateDataKeyUnsupported
+	}
+	c := &callInfo{
+		operation:         "GenerateDataKey",
+		keyID:             aws.ToString(params.KeyId),
+		encryptionContext: params.EncryptionContext,
+		start:             time.Now(),
+	}
+	resp, err := g.GenerateDataKey(ctx, params, optFns...)
+	if err == nil {
+		// Only the size of the encrypted data key is reported.
+		c.responseBytes = len(resp.CiphertextBlob)
+		c.keyARN = aws.ToString(resp.KeyId)
+		c.metadata = resp.ResultMetadata
+	}
+	if err := k.after(ctx, c, err); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (k *instrumentedKMS) GenerateDataKeyPair(ctx context.Context, params *kms.GenerateDataKeyPairInput, optFns ...func(*kms.Options)) (*kms.GenerateDataKeyPairOutput, error) {
+	g, ok := k.kms.(generateDataKeyPairAPI)
+	if !ok {
+		return nil, errGenerateDataKeyPairUnsupported
+	}
+	c := &callInfo{
+		operation:         "GenerateDataKeyPair",
+		keyID:             aws.ToString(params.KeyId),
+		encryptionContext: params.EncryptionContext,
+		start:             time.Now(),
+	}
+	resp, err := g.GenerateDataKeyPair(ctx, params, optFns...)
+	if err == nil {
+		// Only the size of the encrypted private key is reported.
+		c.responseBytes = len(resp.PrivateKeyCiphertextBlob)
+		c.keyARN = aws.ToString(resp.KeyId)
+		c.metadata = resp.ResultMetadata
+	}
+	if err := k.after(ctx, c, err); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (k *instrumentedKMS) GenerateDataKeyPairWithoutPlaintext(ctx context.Context, params *kms.GenerateDataKeyPairWithoutPlaintextInput, optFns ...func(*kms.Options)) (*kms.GenerateDataKeyPairWithoutPlaintextOutput, error) {
+	g, ok := k.kms.(generateDataKeyPairAPI)
+	if !ok {
+		return nil, errGenerateDataKeyPairUnsupported
+	}
+	c := &callInfo{
+		operation:         "GenerateDataKeyPairWithoutPlaintext",
+		keyID:             aws.ToString(params.KeyId),
+		encryptionContext: params.EncryptionContext,
+		start:             time.Now(),
+	}
+	resp, err := g.GenerateDataKeyPairWithoutPlaintext(ctx, params, optFns...)
+	if err == nil {
+		c.responseBytes = len(resp.PrivateKeyCiphertextBlob)
+		c.keyARN = aws.ToString(resp.KeyId)
+		c.metadata = resp.ResultMetadata
+	}
+	if err := k.after(ctx, c, err); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (k *instrumentedKMS) GenerateRandom(ctx context.Context, params *kms.GenerateRandomInput, optFns ...func(*kms.Options)) (*kms.GenerateRandomOutput, error) {
+	g, ok := k.kms.(generateRandomAPI)
+	if !ok {
+		return nil, errGenerateRandomUnsupported
+	}
+	c := &callInfo{
+		operation: "GenerateRandom",
+		// GenerateRandom uses no key. The custom key store, if any, is
+		// reported instead.
+		keyID: aws.ToString(params.CustomKeyStoreId),
+		start: time.Now(),
+	}
+	resp, err := g.GenerateRandom(ctx, params, optFns...)
+	if err == nil {
+		c.responseBytes = len(resp.Plaintext)
+		c.metadata = resp.ResultMetadata
+	}
+	if err := k.after(ctx, c, err); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// errorKind classifies err into a short, low-cardinality string suitable for
+// use as a metric label.
+func errorKind(err error) string {
+	if err == nil {
+		return ""
+	}
+	if errors.Is(err, context.Canceled) {
+		return "Canceled"
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "DeadlineExceeded"
+	}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode()
+	}
+	return "Unknown"
+}
+
+// requestID returns the AWS request ID from the response metadata of a
+// successful call or from the error of a failed one.
+func requestID(metadata middleware.Metadata, err error) string {
+	if err != nil {
+		var respErr interface{ ServiceRequestID() string }
+		if errors.As(err, &respErr) {
+			return respErr.ServiceRequestID()
+		}
+		return ""
+	}
+	id, _ := awsmiddleware.GetRequestIDMetadata(metadata)
+	return id
+}
+
+func sortedKeys(m map[string]string) []string {
+	if len(m) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,139 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakeawskms
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/aws/smithy-go"
+)
+
+// rsaKeyBits are the sizes of the RSA key specs.
+var rsaKeyBits = map[types.KeySpec]int{
+	types.KeySpecRsa2048: 2048,
+	types.KeySpecRsa3072: 3072,
+	types.KeySpecRsa4096: 4096,
+}
+
+// encryptionAlgorithms returns the encryption algorithms of keys with spec s.
+func (s keySpec) encryptionAlgorithms() []types.EncryptionAlgorithmSpec {
+	if s.usage != types.KeyUsageTypeEncryptDecrypt {
+		return nil
+	}
+	switch {
+	case s.spec == types.KeySpecSymmetricDefault:
+		return []types.EncryptionAlgorithmSpec{types.EncryptionAlgorithmSpecSymmetricDefault}
+	case rsaKeyBits[s.spec] > 0:
+		return []types.EncryptionAlgorithmSpec{types.EncryptionAlgorithmSpecRsaesOaepSha1, types.EncryptionAlgorithmSpecRsaesOaepSha256}
+	case s.spec == types.KeySpecSm2:
+		return []types.EncryptionAlgorithmSpec{types.EncryptionAlgorithmSpecSm2pke}
+	}
+	return nil
+}
+
+// isAsymmetric reports whether algorithm is an asymmetric encryption
+// algorithm. The empty algorithm means SYMMETRIC_DEFAULT.
+func isAsymmetric(algorithm types.EncryptionAlgorithmSpec) bool {
+	return algorithm != "" && algorithm != types.EncryptionAlgorithmSpecSymmetricDefault
+}
+
+func validationError(format string, a ...any) error {
+	return &smithy.GenericAPIError{Code: "ValidationException", Message: fmt.Sprintf(format, a...)}
+}
+
+// rsaKey returns the RSA key of keyID and the OAEP hash of algorithm.
+func (f *FakeAWSKMS) rsaKey(keyID string, algorithm types.EncryptionAlgorithmSpec) (*rsa.PrivateKey, hash.Hash, error) {
+	if _, ok := f.aeads[keyID]; !ok {
+		return nil, nil, fmt.Errorf("Unknown keyID: %q not in %q", keyID, f.keyIDs)
+	}
+	if err := f.checkEnabled(keyID); err != nil {
+		return nil, nil, err
+	}
+	key, ok := f.rsaKeys[keyID]
+	if !ok {
+		return nil, nil, &types.InvalidKeyUsageException{Message: aws.String(fmt.Sprintf("key %q does not support encryption algorithm %s in the fake", keyID, algorithm))}
+	}
+	switch algorithm {
+	case types.EncryptionAlgorithmSpecRsaesOaepSha1:
+		return key, sha1.New(), nil
+	case types.EncryptionAlgorithmSpecRsaesOaepSha256:
+		return key, sha256.New(), nil
+	}
+	return nil, nil, &types.InvalidKeyUsageException{Message: aws.String(fmt.Sprintf("key %q does not support encryption algorithm %s", keyID, algorithm))}
+}
+
+// encryptAsymmetric encrypts with an RSA key.
+func (f *FakeAWSKMS) encryptAsymmetric(keyID string, params *kms.EncryptInput) (*kms.EncryptOutput, error) {
+	key, h, err := f.rsaKey(keyID, params.EncryptionAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.authorize(keyID, types.GrantOperationEncrypt, params.GrantTokens, params.EncryptionContext); err != nil {
+		return nil, err
+	}
+	if len(params.EncryptionContext) > 0 {
+		return nil, validationError("EncryptionContext is not supported with %s", params.EncryptionAlgorithm)
+	}
+	if aws.ToBool(params.DryRun) {
+		return nil, errDryRun
+	}
+	ciphertext, err := rsa.EncryptOAEP(h, rand.Reader, &key.PublicKey, params.Plaintext, nil)
+	if err != nil {
+		return nil, validationError("encryption with %s failed: %v", params.EncryptionAlgorithm, err)
+	}
+	return &kms.EncryptOutput{
+		CiphertextBlob:      ciphertext,
+		KeyId:               aws.String(keyID),
+		EncryptionAlgorithm: params.EncryptionAlgorithm,
+	}, nil
+}
+
+// decryptAsymmetric decrypts with an RSA key for the operation op.
+func (f *FakeAWSKMS) decryptAsymmetric(params *kms.DecryptInput, op types.GrantOperation) (*kms.DecryptOutput, error) {
+	if params.KeyId == nil {
+		return nil, validationError("KeyId is required with %s", params.EncryptionAlgorithm)
+	}
+	keyID := f.resolve(*params.KeyId)
+	key, h, err := f.rsaKey(keyID, params.EncryptionAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.authorize(keyID, op, params.GrantTokens, params.EncryptionContext); err != nil {
+		return nil, err
+	}
+	if len(params.EncryptionContext) > 0 {
+		return nil, validationError("EncryptionContext is not supported with %s", params.EncryptionAlgorithm)
+	}
+	plaintext, err := rsa.DecryptOAEP(h, nil, key, params.CiphertextBlob, nil)
+	if err != nil {
+		return nil, &types.InvalidCiphertextException{Message: aws.String(fmt.Sprintf("Decryption with keyID %q failed", keyID))}
+	}
+	if aws.ToBool(params.DryRun) {
+		return nil, errDryRun
+	}
+	return &kms.DecryptOutput{
+		Plaintext:           plaintext,
+		KeyId:               aws.String(keyID),
+		EncryptionAlgorithm: params.EncryptionAlgorithm,
+	}, nil
+}
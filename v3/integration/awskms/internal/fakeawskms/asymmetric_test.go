@@ -0,0 +1,116 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakeawskms
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/aws/smithy-go"
+)
+
+const rsaKeyID = "arn:aws:kms:us-west-2:111122223333:key/rsa-2048"
+
+func TestRSAEncryptDecrypt(t *testing.T) {
+	fakeKMS, err := New([]string{validKeyID})
+	if err != nil {
+		t.Fatalf("New() err = %s, want nil", err)
+	}
+	if err := fakeKMS.AddKey(rsaKeyID, types.KeySpecRsa2048, types.KeyUsageTypeEncryptDecrypt); err != nil {
+		t.Fatalf("fakeKMS.AddKey() err = %v, want nil", err)
+	}
+	descResp, err := fakeKMS.DescribeKey(t.Context(), &kms.DescribeKeyInput{KeyId: aws.String(rsaKeyID)})
+	if err != nil {
+		t.Fatalf("fakeKMS.DescribeKey() err = %v, want nil", err)
+	}
+	wantAlgorithms := []types.EncryptionAlgorithmSpec{types.EncryptionAlgorithmSpecRsaesOaepSha1, types.EncryptionAlgorithmSpecRsaesOaepSha256}
+	if got := descResp.KeyMetadata.EncryptionAlgorithms; !slices.Equal(got, wantAlgorithms) {
+		t.Errorf("KeyMetadata.EncryptionAlgorithms = %v, want %v", got, wantAlgorithms)
+	}
+
+	plaintext := []byte("plaintext")
+	for _, algorithm := range wantAlgorithms {
+		t.Run(string(algorithm), func(t *testing.T) {
+			encResp, err := fakeKMS.Encrypt(t.Context(), &kms.EncryptInput{
+				KeyId:               aws.String(rsaKeyID),
+				Plaintext:           plaintext,
+				EncryptionAlgorithm: algorithm,
+			})
+			if err != nil {
+				t.Fatalf("fakeKMS.Encrypt() err = %v, want nil", err)
+			}
+			decResp, err := fakeKMS.Decrypt(t.Context(), &kms.DecryptInput{
+				KeyId:               aws.String(rsaKeyID),
+				CiphertextBlob:      encResp.CiphertextBlob,
+				EncryptionAlgorithm: algorithm,
+			})
+			if err != nil {
+				t.Fatalf("fakeKMS.Decrypt() err = %v, want nil", err)
+			}
+			if !bytes.Equal(decResp.Plaintext, plaintext) {
+				t.Errorf("decResp.Plaintext = %q, want %q", decResp.Plaintext, plaintext)
+			}
+		})
+	}
+}
+
+func TestRSAEncryptDecrypt_invalidRequestsFail(t *testing.T) {
+	fakeKMS, err := New([]string{validKeyID})
+	if err != nil {
+		t.Fatalf("New() err = %s, want nil", err)
+	}
+	if err := fakeKMS.AddKey(rsaKeyID, types.KeySpecRsa2048, types.KeyUsageTypeEncryptDecrypt); err != nil {
+		t.Fatalf("fakeKMS.AddKey() err = %v, want nil", err)
+	}
+	oaep := types.EncryptionAlgorithmSpecRsaesOaepSha256
+	var invalidKeyUsage *types.InvalidKeyUsageException
+	var apiErr smithy.APIError
+
+	_, err = fakeKMS.Encrypt(t.Context(), &kms.EncryptInput{KeyId: aws.String(rsaKeyID), Plaintext: []byte("plaintext")})
+	if !errors.As(err, &invalidKeyUsage) {
+		t.Errorf("fakeKMS.Encrypt() without algorithm err = %v, want InvalidKeyUsageException", err)
+	}
+	_, err = fakeKMS.Encrypt(t.Context(), &kms.EncryptInput{KeyId: aws.String(validKeyID), Plaintext: []byte("plaintext"), EncryptionAlgorithm: oaep})
+	if !errors.As(err, &invalidKeyUsage) {
+		t.Errorf("fakeKMS.Encrypt() with symmetric key err = %v, want InvalidKeyUsageException", err)
+	}
+	_, err = fakeKMS.Encrypt(t.Context(), &kms.EncryptInput{
+		KeyId:               aws.String(rsaKeyID),
+		Plaintext:           []byte("plaintext"),
+		EncryptionAlgorithm: oaep,
+		EncryptionContext:   map[string]string{"name": "value"},
+	})
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "ValidationException" {
+		t.Errorf("fakeKMS.Encrypt() with encryption context err = %v, want ValidationException", err)
+	}
+	_, err = fakeKMS.Encrypt(t.Context(), &kms.EncryptInput{KeyId: aws.String(rsaKeyID), Plaintext: make([]byte, 191), EncryptionAlgorithm: oaep})
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "ValidationException" {
+		t.Errorf("fakeKMS.Encrypt() with too large plaintext err = %v, want ValidationException", err)
+	}
+	_, err = fakeKMS.Decrypt(t.Context(), &kms.DecryptInput{CiphertextBlob: []byte("ciphertext"), EncryptionAlgorithm: oaep})
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "ValidationException" {
+		t.Errorf("fakeKMS.Decrypt() without key ID err = %v, want ValidationException", err)
+	}
+	_, err = fakeKMS.Decrypt(t.Context(), &kms.DecryptInput{KeyId: aws.String(rsaKeyID), CiphertextBlob: []byte("ciphertext"), EncryptionAlgorithm: oaep})
+	var invalidCiphertext *types.InvalidCiphertextException
+	if !errors.As(err, &invalidCiphertext) {
+		t.Errorf("fakeKMS.Decrypt() with invalid ciphertext err = %v, want InvalidCiphertextException", err)
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command fakeawskms serves the fake AWS KMS of this repository over HTTP, so
+// that it can be used as a local AWS KMS endpoint, for example by the
+// tink-awskms command:
+//
+//	go run ./integration/awskms/internal/fakeawskms/cmd/fakeawskms \
+//		-addr localhost:4599 \
+//		-key arn:aws:kms:us-east-2:235739564943:key/3ee50705-5a82-4f5b-9753-05c4f473922f
+//	tink-awskms encrypt -endpoint http://localhost:4599 \
+//		-key-uri aws-kms://arn:aws:kms:us-east-2:235739564943:key/3ee50705-5a82-4f5b-9753-05c4f473922f
+//
+// Keys are kept in memory, so ciphertexts cannot be decrypted once the server
+// stops. Request signatures are not verified, so any credentials are accepted.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/tink-crypto/tink-go-awskms/v3/integration/awskms/internal/fakeawskms"
+)
+
+// stringsFlag is a flag which can be repeated.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string { return strings.Join(*f, ",") }
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+func main() {
+	addr := flag.String("addr", "localhost:4599", "`address` to listen on")
+	var keys stringsFlag
+	flag.Var(&keys, "key", "`ARN` of a symmetric encryption key to create; can be repeated")
+	flag.Parse()
+	if len(keys) == 0 || flag.NArg() > 0 {
+		fmt.Fprintln(os.Stderr, "Usage: fakeawskms -key <ARN> [-key <ARN>...] [-addr <address>]")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	f, err := fakeawskms.New(keys)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("serving fake AWS KMS on http://%s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, fakeawskms.NewHandler(f)))
+}
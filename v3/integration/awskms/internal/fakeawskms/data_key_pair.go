@@ -0,0 +1,127 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakeawskms
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+// generateKeyPair generates a key pair with spec, and returns the PKCS #8
+// encoded private key and the X.509 SubjectPublicKeyInfo encoded public key.
+// ECC_SECG_P256K1 and SM2 key pairs are not supported by the fake.
+func generateKeyPair(spec types.DataKeyPairSpec) ([]byte, []byte, error) {
+	var privateKey crypto.Signer
+	var err error
+	switch spec {
+	case types.DataKeyPairSpecRsa2048:
+		privateKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	case types.DataKeyPairSpecRsa3072:
+		privateKey, err = rsa.GenerateKey(rand.Reader, 3072)
+	case types.DataKeyPairSpecRsa4096:
+		privateKey, err = rsa.GenerateKey(rand.Reader, 4096)
+	case types.DataKeyPairSpecEccNistP256:
+		privateKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case types.DataKeyPairSpecEccNistP384:
+		privateKey, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case types.DataKeyPairSpecEccNistP521:
+		privateKey, err = ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	default:
+		return nil, nil, validationError("unsupported KeyPairSpec %q", spec)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	privateKeyDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	publicKeyDER, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+	if err != nil {
+		return nil, nil, err
+	}
+	return privateKeyDER, publicKeyDER, nil
+}
+
+// generateDataKeyPair generates a key pair whose private key is encrypted
+// under keyID, for the operation op.
+func (f *FakeAWSKMS) generateDataKeyPair(keyID string, spec types.DataKeyPairSpec, encryptionContext map[string]string, grantTokens []string, dryRun bool, op types.GrantOperation) (*kms.GenerateDataKeyPairOutput, error) {
+	f.mu.Lock()
+	keyID = f.resolve(keyID)
+	a, err := f.symmetricAEAD(keyID)
+	if err == nil {
+		err = f.authorize(keyID, op, grantTokens, encryptionContext)
+	}
+	// Key pairs are generated without holding the lock, as this is slow.
+	f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	privateKey, publicKey, err := generateKeyPair(spec)
+	if err != nil {
+		return nil, err
+	}
+	if dryRun {
+		return nil, errDryRun
+	}
+	ciphertext, err := a.Encrypt(privateKey, serializeEncryptionContext(encryptionContext))
+	if err != nil {
+		return nil, err
+	}
+	return &kms.GenerateDataKeyPairOutput{
+		KeyId:                    aws.String(keyID),
+		KeyPairSpec:              spec,
+		PrivateKeyCiphertextBlob: ciphertext,
+		PrivateKeyPlaintext:      privateKey,
+		PublicKey:                publicKey,
+	}, nil
+}
+
+// GenerateDataKeyPair returns a new key pair, whose private key is returned
+// both in plaintext and encrypted under params.KeyId.
+func (f *FakeAWSKMS) GenerateDataKeyPair(ctx context.Context, params *kms.GenerateDataKeyPairInput, optFns ...func(*kms.Options)) (*kms.GenerateDataKeyPairOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return f.generateDataKeyPair(aws.ToString(params.KeyId), params.KeyPairSpec, params.EncryptionContext, params.GrantTokens, aws.ToBool(params.DryRun), types.GrantOperationGenerateDataKeyPair)
+}
+
+// GenerateDataKeyPairWithoutPlaintext returns a new key pair, whose private
+// key is only returned encrypted under params.KeyId.
+func (f *FakeAWSKMS) GenerateDataKeyPairWithoutPlaintext(ctx context.Context, params *kms.GenerateDataKeyPairWithoutPlaintextInput, optFns ...func(*kms.Options)) (*kms.GenerateDataKeyPairWithoutPlaintextOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	resp, err := f.generateDataKeyPair(aws.ToString(params.KeyId), params.KeyPairSpec, params.EncryptionContext, params.GrantTokens, aws.ToBool(params.DryRun), types.GrantOperationGenerateDataKeyPairWithoutPlaintext)
+	if err != nil {
+		return nil, err
+	}
+	clear(resp.PrivateKeyPlaintext)
+	return &kms.GenerateDataKeyPairWithoutPlaintextOutput{
+		KeyId:                    resp.KeyId,
+		KeyPairSpec:              resp.KeyPairSpec,
+		PrivateKeyCiphertextBlob: resp.PrivateKeyCiphertextBlob,
+		PublicKey:                resp.PublicKey,
+	}, nil
+}
@@ -0,0 +1,117 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakeawskms
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+func TestGenerateDataKeyPair(t *testing.T) {
+	f, err := New([]string{validKeyID})
+	if err != nil {
+		t.Fatalf("New() err = %v, want nil", err)
+	}
+	encryptionContext := map[string]string{"device": "1"}
+	for _, spec := range []types.DataKeyPairSpec{types.DataKeyPairSpecEccNistP256, types.DataKeyPairSpecEccNistP521, types.DataKeyPairSpecRsa2048} {
+		t.Run(string(spec), func(t *testing.T) {
+			resp, err := f.GenerateDataKeyPair(t.Context(), &kms.GenerateDataKeyPairInput{
+				KeyId:             aws.String(validKeyID),
+				KeyPairSpec:       spec,
+				EncryptionContext: encryptionContext,
+			})
+			if err != nil {
+				t.Fatalf("f.GenerateDataKeyPair() err = %v, want nil", err)
+			}
+			if resp.KeyPairSpec != spec || aws.ToString(resp.KeyId) != validKeyID {
+				t.Errorf("resp.KeyPairSpec, resp.KeyId = %s, %q, want %s, %q", resp.KeyPairSpec, aws.ToString(resp.KeyId), spec, validKeyID)
+			}
+			privateKey, err := x509.ParsePKCS8PrivateKey(resp.PrivateKeyPlaintext)
+			if err != nil {
+				t.Fatalf("x509.ParsePKCS8PrivateKey() err = %v, want nil", err)
+			}
+			publicKey, err := x509.ParsePKIXPublicKey(resp.PublicKey)
+			if err != nil {
+				t.Fatalf("x509.ParsePKIXPublicKey() err = %v, want nil", err)
+			}
+			if !publicKey.(interface{ Equal(crypto.PublicKey) bool }).Equal(privateKey.(crypto.Signer).Public()) {
+				t.Error("public key does not match private key")
+			}
+			decResp, err := f.Decrypt(t.Context(), &kms.DecryptInput{
+				KeyId:             aws.String(validKeyID),
+				CiphertextBlob:    resp.PrivateKeyCiphertextBlob,
+				EncryptionContext: encryptionContext,
+			})
+			if err != nil {
+				t.Fatalf("f.Decrypt() err = %v, want nil", err)
+			}
+			if !bytes.Equal(decResp.Plaintext, resp.PrivateKeyPlaintext) {
+				t.Error("decrypted private key differs from PrivateKeyPlaintext")
+			}
+		})
+	}
+}
+
+func TestGenerateDataKeyPairWithoutPlaintext(t *testing.T) {
+	f, err := New([]string{validKeyID})
+	if err != nil {
+		t.Fatalf("New() err = %v, want nil", err)
+	}
+	resp, err := f.GenerateDataKeyPairWithoutPlaintext(t.Context(), &kms.GenerateDataKeyPairWithoutPlaintextInput{
+		KeyId:       aws.String(validKeyID),
+		KeyPairSpec: types.DataKeyPairSpecEccNistP384,
+	})
+	if err != nil {
+		t.Fatalf("f.GenerateDataKeyPairWithoutPlaintext() err = %v, want nil", err)
+	}
+	decResp, err := f.Decrypt(t.Context(), &kms.DecryptInput{CiphertextBlob: resp.PrivateKeyCiphertextBlob})
+	if err != nil {
+		t.Fatalf("f.Decrypt() err = %v, want nil", err)
+	}
+	if _, err := x509.ParsePKCS8PrivateKey(decResp.Plaintext); err != nil {
+		t.Errorf("x509.ParsePKCS8PrivateKey() err = %v, want nil", err)
+	}
+}
+
+func TestGenerateDataKeyPair_fails(t *testing.T) {
+	f, err := New([]string{validKeyID})
+	if err != nil {
+		t.Fatalf("New() err = %v, want nil", err)
+	}
+	for _, params := range []*kms.GenerateDataKeyPairInput{
+		{KeyId: aws.String(validKeyID)},
+		{KeyId: aws.String(validKeyID), KeyPairSpec: types.DataKeyPairSpecSm2},
+		{KeyId: aws.String(validKeyID2), KeyPairSpec: types.DataKeyPairSpecEccNistP256},
+	} {
+		if _, err := f.GenerateDataKeyPair(t.Context(), params); err == nil {
+			t.Errorf("f.GenerateDataKeyPair(%+v) err = nil, want error", params)
+		}
+	}
+	var dryRunErr *types.DryRunOperationException
+	if _, err := f.GenerateDataKeyPair(t.Context(), &kms.GenerateDataKeyPairInput{
+		KeyId:       aws.String(validKeyID),
+		KeyPairSpec: types.DataKeyPairSpecEccNistP256,
+		DryRun:      aws.Bool(true),
+	}); !errors.As(err, &dryRunErr) {
+		t.Errorf("f.GenerateDataKeyPair() with DryRun err = %v, want DryRunOperationException", err)
+	}
+}
@@ -18,20 +18,80 @@ package fakeawskms
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
 	"errors"
 	"fmt"
+	"io"
+	"slices"
 	"sort"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/aws/smithy-go"
 	"github.com/tink-crypto/tink-go/v2/aead"
 	"github.com/tink-crypto/tink-go/v2/keyset"
 	"github.com/tink-crypto/tink-go/v2/tink"
 )
 
-// FakeAWSKMS is a fake implementation of awskms.KMSAPI.
+// FakeAWSKMS is a fake implementation of awskms.KMSAPI. It is safe for
+// concurrent use.
+//
+// Like AWS KMS, requests with DryRun set fail with a DryRunOperationException
+// if they would have succeeded, and only enabled keys can be used for
+// cryptographic operations, see CreateKey, DisableKey and ScheduleKeyDeletion.
 type FakeAWSKMS struct {
-	aeads  map[string]tink.AEAD
-	keyIDs []string
+	*state
+	// principal makes the requests, see AsPrincipal.
+	principal string
+}
+
+// state holds the keys of a FakeAWSKMS, shared by the clients returned by
+// AsPrincipal.
+type state struct {
+	// mu guards all fields below, as the fake may be used concurrently, for
+	// example when it is served by a Handler. Exported methods lock mu, the
+	// unexported ones expect it to be held.
+	mu sync.Mutex
+
+	aeads   map[string]tink.AEAD
+	handles map[string]*keyset.Handle
+	keyIDs  []string
+	specs   map[string]keySpec
+	aliases map[string]string
+	rsaKeys map[string]*rsa.PrivateKey
+	// grants maps grant tokens to grants. Keys with grants can only be used
+	// with the token of a grant for the operation.
+	grants map[string]grant
+	// customKeyStores holds the IDs of the custom key stores added with
+	// AddCustomKeyStore.
+	customKeyStores map[string]bool
+	// random is the source of GenerateRandom, or nil for crypto/rand.
+	random io.Reader
+	// policies holds the key policies set with SetKeyPolicy.
+	policies map[string]Policy
+	// lifecycles holds the states and other changing metadata of the keys.
+	lifecycles map[string]*keyLifecycle
+}
+
+// grant allows operations with a key.
+type grant struct {
+	keyID      string
+	operations []types.GrantOperation
+}
+
+// keySpec describes the type of a key.
+type keySpec struct {
+	spec  types.KeySpec
+	usage types.KeyUsageType
+}
+
+var symmetricKeySpec = keySpec{
+	spec:  types.KeySpecSymmetricDefault,
+	usage: types.KeyUsageTypeEncryptDecrypt,
 }
 
 // serializeEncryptionContext serializes the context map in a canonical way into a byte array.
@@ -55,31 +115,181 @@ func serializeEncryptionContext(encryptionContext map[string]string) []byte {
 
 // New returns a new fake AWS KMS API.
 func New(validKeyIDs []string) (*FakeAWSKMS, error) {
-	aeads := make(map[string]tink.AEAD)
+	f := &FakeAWSKMS{state: &state{
+		aeads:   make(map[string]tink.AEAD),
+		handles: make(map[string]*keyset.Handle),
+		specs:   make(map[string]keySpec),
+		aliases: make(map[string]string),
+		rsaKeys: make(map[string]*rsa.PrivateKey),
+		grants:  make(map[string]grant),
+
+		customKeyStores: make(map[string]bool),
+		policies:        make(map[string]Policy),
+		lifecycles:      make(map[string]*keyLifecycle),
+	}}
 	for _, keyID := range validKeyIDs {
-		handle, err := keyset.NewHandle(aead.AES256GCMKeyTemplate())
-		if err != nil {
+		if err := f.AddKey(keyID, types.KeySpecSymmetricDefault, types.KeyUsageTypeEncryptDecrypt); err != nil {
 			return nil, err
 		}
-		a, err := aead.New(handle)
+	}
+	return f, nil
+}
+
+// AddKey adds a key with the given spec and usage. Only symmetric encryption
+// keys, created by New, and RSA encryption keys, which support the RSAES_OAEP
+// algorithms, can be used for cryptographic operations. Other keys are
+// rejected with an InvalidKeyUsageException, which allows testing validation
+// of key metadata. The key is enabled, see CreateKey for other key states.
+func (f *FakeAWSKMS) AddKey(keyID string, spec types.KeySpec, usage types.KeyUsageType) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.addKey(keyID, keySpec{spec: spec, usage: usage}, &keyLifecycle{
+		state:  types.KeyStateEnabled,
+		origin: types.OriginTypeAwsKms,
+	})
+}
+
+// addKey adds a key with the given spec and initial lifecycle.
+func (f *FakeAWSKMS) addKey(keyID string, spec keySpec, lifecycle *keyLifecycle) error {
+	if _, ok := f.aeads[keyID]; ok {
+		return fmt.Errorf("key %q already exists", keyID)
+	}
+	handle, err := keyset.NewHandle(aead.AES256GCMKeyTemplate())
+	if err != nil {
+		return err
+	}
+	a, err := aead.New(handle)
+	if err != nil {
+		return err
+	}
+	if bits := rsaKeyBits[spec.spec]; bits > 0 && spec.usage == types.KeyUsageTypeEncryptDecrypt {
+		key, err := rsa.GenerateKey(rand.Reader, bits)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		aeads[keyID] = a
+		f.rsaKeys[keyID] = key
 	}
-	return &FakeAWSKMS{
-		aeads:  aeads,
-		keyIDs: validKeyIDs,
-	}, nil
+	f.aeads[keyID] = a
+	f.handles[keyID] = handle
+	f.keyIDs = append(f.keyIDs, keyID)
+	f.specs[keyID] = spec
+	lifecycle.creationDate = time.Now()
+	f.lifecycles[keyID] = lifecycle
+	return nil
+}
+
+// SetAlias makes alias refer to keyID, replacing any previous target. Like in
+// AWS KMS, operations using the alias report keyID as the key which performed
+// them.
+func (f *FakeAWSKMS) SetAlias(alias, keyID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.aeads[keyID]; !ok {
+		return fmt.Errorf("Unknown keyID: %q not in %q", keyID, f.keyIDs)
+	}
+	f.aliases[alias] = keyID
+	return nil
+}
+
+// AddGrant adds a grant allowing operations with keyID and returns its grant
+// token.
+//
+// Once a key has a grant, the fake behaves as if the caller had no permissions
+// in the key policy: operations with the key fail with an
+// AccessDeniedException, unless the request contains the token of a grant for
+// the operation or the key has a policy allowing it, see SetKeyPolicy.
+func (f *FakeAWSKMS) AddGrant(keyID string, operations ...types.GrantOperation) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.aeads[keyID]; !ok {
+		return "", fmt.Errorf("Unknown keyID: %q not in %q", keyID, f.keyIDs)
+	}
+	if len(operations) == 0 {
+		return "", errors.New("a grant requires at least one operation")
+	}
+	token := fmt.Sprintf("grant-token-%d", len(f.grants)+1)
+	f.grants[token] = grant{keyID: keyID, operations: operations}
+	return token, nil
+}
+
+// authorize checks that operation op is allowed with keyID given the grant
+// tokens and encryption context of the request.
+func (f *FakeAWSKMS) authorize(keyID string, op types.GrantOperation, grantTokens []string, encryptionContext map[string]string) error {
+	hasGrants := false
+	for token, g := range f.grants {
+		if g.keyID != keyID {
+			continue
+		}
+		hasGrants = true
+		if slices.Contains(grantTokens, token) && slices.Contains(g.operations, op) {
+			return nil
+		}
+	}
+	policy, hasPolicy := f.policies[keyID]
+	if !hasGrants && !hasPolicy {
+		return nil
+	}
+	if hasPolicy && policy.allows(f.principal, op, encryptionContext) {
+		return nil
+	}
+	principal := f.principal
+	if principal == "" {
+		principal = "anonymous"
+	}
+	return &smithy.GenericAPIError{
+		Code:    "AccessDeniedException",
+		Message: fmt.Sprintf("User: %s is not authorized to perform: kms:%s on resource: %s because no resource-based policy allows the kms:%s action", principal, op, keyID, op),
+	}
+}
+
+// errDryRun is returned by operations with DryRun set which would have
+// succeeded.
+var errDryRun = &types.DryRunOperationException{Message: aws.String("The request would have succeeded, but the DryRun option is set.")}
+
+// resolve returns the key ID an alias refers to, or keyID if it is not an
+// alias.
+func (f *FakeAWSKMS) resolve(keyID string) string {
+	if target, ok := f.aliases[keyID]; ok {
+		return target
+	}
+	return keyID
+}
+
+// symmetricAEAD returns the AEAD of keyID, if it is an enabled symmetric
+// encryption key.
+func (f *FakeAWSKMS) symmetricAEAD(keyID string) (tink.AEAD, error) {
+	a, ok := f.aeads[keyID]
+	if !ok {
+		return nil, fmt.Errorf("Unknown keyID: %q not in %q", keyID, f.keyIDs)
+	}
+	if err := f.checkEnabled(keyID); err != nil {
+		return nil, err
+	}
+	if s := f.specs[keyID]; s != symmetricKeySpec {
+		return nil, &types.InvalidKeyUsageException{Message: aws.String(fmt.Sprintf("key %q with spec %s and usage %s cannot be used for symmetric encryption", keyID, s.spec, s.usage))}
+	}
+	return a, nil
 }
 
 func (f *FakeAWSKMS) Encrypt(ctx context.Context, params *kms.EncryptInput, optFns ...func(*kms.Options)) (*kms.EncryptOutput, error) {
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
-	a, ok := f.aeads[*params.KeyId]
-	if !ok {
-		return nil, fmt.Errorf("Unknown keyID: %q not in %q", *params.KeyId, f.keyIDs)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	keyID := f.resolve(aws.ToString(params.KeyId))
+	if isAsymmetric(params.EncryptionAlgorithm) {
+		return f.encryptAsymmetric(keyID, params)
+	}
+	a, err := f.symmetricAEAD(keyID)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.authorize(keyID, types.GrantOperationEncrypt, params.GrantTokens, params.EncryptionContext); err != nil {
+		return nil, err
+	}
+	if aws.ToBool(params.DryRun) {
+		return nil, errDryRun
 	}
 	serializedEncryptionContext := serializeEncryptionContext(params.EncryptionContext)
 	ciphertext, err := a.Encrypt(params.Plaintext, serializedEncryptionContext)
@@ -88,7 +298,7 @@ func (f *FakeAWSKMS) Encrypt(ctx context.Context, params *kms.EncryptInput, optF
 	}
 	return &kms.EncryptOutput{
 		CiphertextBlob: ciphertext,
-		KeyId:          params.KeyId,
+		KeyId:          aws.String(keyID),
 	}, nil
 }
 
@@ -96,30 +306,155 @@ func (f *FakeAWSKMS) Decrypt(ctx context.Context, params *kms.DecryptInput, optF
 	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.decrypt(params, types.GrantOperationDecrypt)
+}
+
+// decrypt decrypts for the operation op, which is Decrypt or ReEncryptFrom.
+func (f *FakeAWSKMS) decrypt(params *kms.DecryptInput, op types.GrantOperation) (*kms.DecryptOutput, error) {
+	if isAsymmetric(params.EncryptionAlgorithm) {
+		return f.decryptAsymmetric(params, op)
+	}
 	serializedEncryptionContext := serializeEncryptionContext(params.EncryptionContext)
 	if params.KeyId != nil {
-		a, ok := f.aeads[*params.KeyId]
-		if !ok {
-			return nil, fmt.Errorf("Unknown keyID: %q not in %q", *params.KeyId, f.keyIDs)
+		keyID := f.resolve(*params.KeyId)
+		a, err := f.symmetricAEAD(keyID)
+		if err != nil {
+			return nil, err
+		}
+		if err := f.authorize(keyID, op, params.GrantTokens, params.EncryptionContext); err != nil {
+			return nil, err
 		}
 		plaintext, err := a.Decrypt(params.CiphertextBlob, serializedEncryptionContext)
 		if err != nil {
-			return nil, fmt.Errorf("Decryption with keyID %q failed", *params.KeyId)
+			return nil, &types.InvalidCiphertextException{Message: aws.String(fmt.Sprintf("Decryption with keyID %q failed", *params.KeyId))}
+		}
+		if aws.ToBool(params.DryRun) {
+			return nil, errDryRun
 		}
 		return &kms.DecryptOutput{
 			Plaintext: plaintext,
-			KeyId:     params.KeyId,
+			KeyId:     aws.String(keyID),
 		}, nil
 	}
 	// When KeyId is not set, try out all AEADs.
 	for keyID, a := range f.aeads {
 		plaintext, err := a.Decrypt(params.CiphertextBlob, serializedEncryptionContext)
 		if err == nil {
+			if err := f.checkEnabled(keyID); err != nil {
+				return nil, err
+			}
+			if err := f.authorize(keyID, op, params.GrantTokens, params.EncryptionContext); err != nil {
+				return nil, err
+			}
+			if aws.ToBool(params.DryRun) {
+				return nil, errDryRun
+			}
 			return &kms.DecryptOutput{
 				Plaintext: plaintext,
 				KeyId:     &keyID,
 			}, nil
 		}
 	}
-	return nil, errors.New("unable to decrypt message")
+	return nil, &types.InvalidCiphertextException{Message: aws.String("unable to decrypt message")}
+}
+
+// GenerateDataKey returns a random data key and its encryption under
+// params.KeyId.
+func (f *FakeAWSKMS) GenerateDataKey(ctx context.Context, params *kms.GenerateDataKeyInput, optFns ...func(*kms.Options)) (*kms.GenerateDataKeyOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	keyID := f.resolve(aws.ToString(params.KeyId))
+	a, err := f.symmetricAEAD(keyID)
+	if err != nil {
+		return nil, err
+	}
+	var size int
+	switch {
+	case params.KeySpec == types.DataKeySpecAes256 && params.NumberOfBytes == nil:
+		size = 32
+	case params.KeySpec == types.DataKeySpecAes128 && params.NumberOfBytes == nil:
+		size = 16
+	case params.KeySpec == "" && aws.ToInt32(params.NumberOfBytes) >= 1 && aws.ToInt32(params.NumberOfBytes) <= 1024:
+		size = int(aws.ToInt32(params.NumberOfBytes))
+	default:
+		return nil, validationError("exactly one of KeySpec and NumberOfBytes must be set to a valid value")
+	}
+	if err := f.authorize(keyID, types.GrantOperationGenerateDataKey, params.GrantTokens, params.EncryptionContext); err != nil {
+		return nil, err
+	}
+	if aws.ToBool(params.DryRun) {
+		return nil, errDryRun
+	}
+	plaintext := make([]byte, size)
+	if _, err := rand.Read(plaintext); err != nil {
+		return nil, err
+	}
+	ciphertext, err := a.Encrypt(plaintext, serializeEncryptionContext(params.EncryptionContext))
+	if err != nil {
+		return nil, err
+	}
+	return &kms.GenerateDataKeyOutput{
+		CiphertextBlob: ciphertext,
+		Plaintext:      plaintext,
+		KeyId:          aws.String(keyID),
+	}, nil
+}
+
+// ReEncrypt decrypts params.CiphertextBlob and encrypts the plaintext under
+// params.DestinationKeyId, without returning the plaintext.
+func (f *FakeAWSKMS) ReEncrypt(ctx context.Context, params *kms.ReEncryptInput, optFns ...func(*kms.Options)) (*kms.ReEncryptOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	destinationKeyID := f.resolve(aws.ToString(params.DestinationKeyId))
+	destination, err := f.symmetricAEAD(destinationKeyID)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.authorize(destinationKeyID, types.GrantOperationReEncryptTo, params.GrantTokens, params.DestinationEncryptionContext); err != nil {
+		return nil, err
+	}
+	decResponse, err := f.decrypt(&kms.DecryptInput{
+		KeyId:             params.SourceKeyId,
+		CiphertextBlob:    params.CiphertextBlob,
+		EncryptionContext: params.SourceEncryptionContext,
+		GrantTokens:       params.GrantTokens,
+		DryRun:            params.DryRun,
+	}, types.GrantOperationReEncryptFrom)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := destination.Encrypt(decResponse.Plaintext, serializeEncryptionContext(params.DestinationEncryptionContext))
+	if err != nil {
+		return nil, err
+	}
+	return &kms.ReEncryptOutput{
+		CiphertextBlob: ciphertext,
+		KeyId:          aws.String(destinationKeyID),
+		SourceKeyId:    decResponse.KeyId,
+	}, nil
+}
+
+// DescribeKey returns the metadata of a key, in any key state.
+func (f *FakeAWSKMS) DescribeKey(ctx context.Context, params *kms.DescribeKeyInput, optFns ...func(*kms.Options)) (*kms.DescribeKeyOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	keyID := f.resolve(aws.ToString(params.KeyId))
+	if _, ok := f.specs[keyID]; !ok {
+		return nil, &types.NotFoundException{Message: aws.String(fmt.Sprintf("Unknown keyID: %q not in %q", keyID, f.keyIDs))}
+	}
+	if err := f.authorize(keyID, types.GrantOperationDescribeKey, params.GrantTokens, nil); err != nil {
+		return nil, err
+	}
+	return &kms.DescribeKeyOutput{KeyMetadata: f.keyMetadata(keyID)}, nil
 }
@@ -23,6 +23,8 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/service/kms"
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/aws/smithy-go"
 )
 
 const validKeyID = "arn:aws:kms:us-west-2:111122223333:key/1234abcd-12ab-34cd-56ef-1234567890ab"
@@ -314,3 +316,395 @@ func TestSerializeEncryptionContext(t *testing.T) {
 		t.Fatalf("serializeEncryptionContext(context) = %s, want %s", gotEmpty, "{}")
 	}
 }
+
+func TestDecryptWithInvalidCiphertext_returnsInvalidCiphertextException(t *testing.T) {
+	fakeKMS, err := New([]string{validKeyID})
+	if err != nil {
+		t.Fatalf("New() err = %s, want nil", err)
+	}
+	for _, keyID := range []*string{aws.String(validKeyID), nil} {
+		decRequest := &kms.DecryptInput{
+			KeyId:          keyID,
+			CiphertextBlob: []byte("invalidCiphertext"),
+		}
+		_, err := fakeKMS.Decrypt(t.Context(), decRequest)
+		var invalidCiphertextErr *types.InvalidCiphertextException
+		if !errors.As(err, &invalidCiphertextErr) {
+			t.Errorf("fakeKMS.Decrypt(t.Context(), decRequest) err = %v, want InvalidCiphertextException", err)
+		}
+	}
+}
+
+func TestReEncrypt(t *testing.T) {
+	fakeKMS, err := New([]string{validKeyID, validKeyID2})
+	if err != nil {
+		t.Fatalf("New() err = %s, want nil", err)
+	}
+	plaintext := []byte("plaintext")
+	sourceContext := map[string]string{"contextName": "source"}
+	destinationContext := map[string]string{"otherName": "destination"}
+	encResponse, err := fakeKMS.Encrypt(t.Context(), &kms.EncryptInput{
+		KeyId:             aws.String(validKeyID),
+		Plaintext:         plaintext,
+		EncryptionContext: sourceContext,
+	})
+	if err != nil {
+		t.Fatalf("fakeKMS.Encrypt() err = %s, want nil", err)
+	}
+
+	for _, sourceKeyID := range []*string{nil, aws.String(validKeyID)} {
+		reEncResponse, err := fakeKMS.ReEncrypt(t.Context(), &kms.ReEncryptInput{
+			CiphertextBlob:               encResponse.CiphertextBlob,
+			SourceKeyId:                  sourceKeyID,
+			SourceEncryptionContext:      sourceContext,
+			DestinationKeyId:             aws.String(validKeyID2),
+			DestinationEncryptionContext: destinationContext,
+		})
+		if err != nil {
+			t.Fatalf("fakeKMS.ReEncrypt() err = %s, want nil", err)
+		}
+		if got := aws.ToString(reEncResponse.SourceKeyId); got != validKeyID {
+			t.Errorf("reEncResponse.SourceKeyId = %q, want %q", got, validKeyID)
+		}
+		if got := aws.ToString(reEncResponse.KeyId); got != validKeyID2 {
+			t.Errorf("reEncResponse.KeyId = %q, want %q", got, validKeyID2)
+		}
+		decResponse, err := fakeKMS.Decrypt(t.Context(), &kms.DecryptInput{
+			KeyId:             aws.String(validKeyID2),
+			CiphertextBlob:    reEncResponse.CiphertextBlob,
+			EncryptionContext: destinationContext,
+		})
+		if err != nil {
+			t.Fatalf("fakeKMS.Decrypt() err = %s, want nil", err)
+		}
+		if !bytes.Equal(decResponse.Plaintext, plaintext) {
+			t.Errorf("decResponse.Plaintext = %q, want %q", decResponse.Plaintext, plaintext)
+		}
+	}
+}
+
+func TestReEncryptWithInvalidInputFails(t *testing.T) {
+	fakeKMS, err := New([]string{validKeyID, validKeyID2})
+	if err != nil {
+		t.Fatalf("New() err = %s, want nil", err)
+	}
+	sourceContext := map[string]string{"contextName": "source"}
+	encResponse, err := fakeKMS.Encrypt(t.Context(), &kms.EncryptInput{
+		KeyId:             aws.String(validKeyID),
+		Plaintext:         []byte("plaintext"),
+		EncryptionContext: sourceContext,
+	})
+	if err != nil {
+		t.Fatalf("fakeKMS.Encrypt() err = %s, want nil", err)
+	}
+
+	_, err = fakeKMS.ReEncrypt(t.Context(), &kms.ReEncryptInput{
+		CiphertextBlob:          encResponse.CiphertextBlob,
+		SourceEncryptionContext: map[string]string{"contextName": "other"},
+		DestinationKeyId:        aws.String(validKeyID2),
+	})
+	var invalidCiphertext *types.InvalidCiphertextException
+	if !errors.As(err, &invalidCiphertext) {
+		t.Errorf("fakeKMS.ReEncrypt() with wrong source context err = %v, want InvalidCiphertextException", err)
+	}
+
+	if _, err := fakeKMS.ReEncrypt(t.Context(), &kms.ReEncryptInput{
+		CiphertextBlob:          encResponse.CiphertextBlob,
+		SourceEncryptionContext: sourceContext,
+		DestinationKeyId:        aws.String("arn:aws:kms:us-west-2:111122223333:key/unknown"),
+	}); err == nil {
+		t.Error("fakeKMS.ReEncrypt() with unknown destination key err = nil, want error")
+	}
+}
+
+func TestDescribeKey(t *testing.T) {
+	fakeKMS, err := New([]string{validKeyID})
+	if err != nil {
+		t.Fatalf("New() err = %s, want nil", err)
+	}
+	resp, err := fakeKMS.DescribeKey(t.Context(), &kms.DescribeKeyInput{KeyId: aws.String(validKeyID)})
+	if err != nil {
+		t.Fatalf("fakeKMS.DescribeKey() err = %v, want nil", err)
+	}
+	m := resp.KeyMetadata
+	if got := aws.ToString(m.Arn); got != validKeyID {
+		t.Errorf("KeyMetadata.Arn = %q, want %q", got, validKeyID)
+	}
+	if got, want := aws.ToString(m.KeyId), "1234abcd-12ab-34cd-56ef-1234567890ab"; got != want {
+		t.Errorf("KeyMetadata.KeyId = %q, want %q", got, want)
+	}
+	if got, want := aws.ToString(m.AWSAccountId), "111122223333"; got != want {
+		t.Errorf("KeyMetadata.AWSAccountId = %q, want %q", got, want)
+	}
+	if m.KeyState != types.KeyStateEnabled || m.KeySpec != types.KeySpecSymmetricDefault || m.KeyUsage != types.KeyUsageTypeEncryptDecrypt {
+		t.Errorf("KeyMetadata = %+v, want enabled symmetric encryption key", m)
+	}
+
+	_, err = fakeKMS.DescribeKey(t.Context(), &kms.DescribeKeyInput{KeyId: aws.String(validKeyID2)})
+	var notFound *types.NotFoundException
+	if !errors.As(err, &notFound) {
+		t.Errorf("fakeKMS.DescribeKey() with unknown key err = %v, want NotFoundException", err)
+	}
+}
+
+func TestAddKey(t *testing.T) {
+	fakeKMS, err := New([]string{validKeyID})
+	if err != nil {
+		t.Fatalf("New() err = %s, want nil", err)
+	}
+	if err := fakeKMS.AddKey(validKeyID2, types.KeySpecHmac256, types.KeyUsageTypeGenerateVerifyMac); err != nil {
+		t.Fatalf("fakeKMS.AddKey() err = %v, want nil", err)
+	}
+	if err := fakeKMS.AddKey(validKeyID, types.KeySpecSymmetricDefault, types.KeyUsageTypeEncryptDecrypt); err == nil {
+		t.Error("fakeKMS.AddKey() with existing key err = nil, want error")
+	}
+
+	resp, err := fakeKMS.DescribeKey(t.Context(), &kms.DescribeKeyInput{KeyId: aws.String(validKeyID2)})
+	if err != nil {
+		t.Fatalf("fakeKMS.DescribeKey() err = %v, want nil", err)
+	}
+	if resp.KeyMetadata.KeySpec != types.KeySpecHmac256 || resp.KeyMetadata.KeyUsage != types.KeyUsageTypeGenerateVerifyMac {
+		t.Errorf("KeyMetadata = %+v, want HMAC key", resp.KeyMetadata)
+	}
+
+	_, err = fakeKMS.Encrypt(t.Context(), &kms.EncryptInput{
+		KeyId:     aws.String(validKeyID2),
+		Plaintext: []byte("plaintext"),
+	})
+	var invalidKeyUsage *types.InvalidKeyUsageException
+	if !errors.As(err, &invalidKeyUsage) {
+		t.Errorf("fakeKMS.Encrypt() with HMAC key err = %v, want InvalidKeyUsageException", err)
+	}
+}
+
+func TestSetAlias(t *testing.T) {
+	fakeKMS, err := New([]string{validKeyID, validKeyID2})
+	if err != nil {
+		t.Fatalf("New() err = %s, want nil", err)
+	}
+	const alias = "alias/my-key"
+	if err := fakeKMS.SetAlias(alias, "unknown"); err == nil {
+		t.Error("fakeKMS.SetAlias() with unknown key err = nil, want error")
+	}
+	if err := fakeKMS.SetAlias(alias, validKeyID); err != nil {
+		t.Fatalf("fakeKMS.SetAlias() err = %v, want nil", err)
+	}
+
+	encResp, err := fakeKMS.Encrypt(t.Context(), &kms.EncryptInput{
+		KeyId:     aws.String(alias),
+		Plaintext: []byte("plaintext"),
+	})
+	if err != nil {
+		t.Fatalf("fakeKMS.Encrypt() err = %v, want nil", err)
+	}
+	if got := aws.ToString(encResp.KeyId); got != validKeyID {
+		t.Errorf("EncryptOutput.KeyId = %q, want %q", got, validKeyID)
+	}
+	decResp, err := fakeKMS.Decrypt(t.Context(), &kms.DecryptInput{
+		KeyId:          aws.String(alias),
+		CiphertextBlob: encResp.CiphertextBlob,
+	})
+	if err != nil {
+		t.Fatalf("fakeKMS.Decrypt() err = %v, want nil", err)
+	}
+	if got := aws.ToString(decResp.KeyId); got != validKeyID {
+		t.Errorf("DecryptOutput.KeyId = %q, want %q", got, validKeyID)
+	}
+	descResp, err := fakeKMS.DescribeKey(t.Context(), &kms.DescribeKeyInput{KeyId: aws.String(alias)})
+	if err != nil {
+		t.Fatalf("fakeKMS.DescribeKey() err = %v, want nil", err)
+	}
+	if got := aws.ToString(descResp.KeyMetadata.Arn); got != validKeyID {
+		t.Errorf("KeyMetadata.Arn = %q, want %q", got, validKeyID)
+	}
+
+	if err := fakeKMS.SetAlias(alias, validKeyID2); err != nil {
+		t.Fatalf("fakeKMS.SetAlias() err = %v, want nil", err)
+	}
+	encResp, err = fakeKMS.Encrypt(t.Context(), &kms.EncryptInput{
+		KeyId:     aws.String(alias),
+		Plaintext: []byte("plaintext"),
+	})
+	if err != nil {
+		t.Fatalf("fakeKMS.Encrypt() err = %v, want nil", err)
+	}
+	if got := aws.ToString(encResp.KeyId); got != validKeyID2 {
+		t.Errorf("EncryptOutput.KeyId after retargeting = %q, want %q", got, validKeyID2)
+	}
+}
+
+func TestAddGrant(t *testing.T) {
+	fakeKMS, err := New([]string{validKeyID, validKeyID2})
+	if err != nil {
+		t.Fatalf("New() err = %s, want nil", err)
+	}
+	if _, err := fakeKMS.AddGrant("unknown", types.GrantOperationEncrypt); err == nil {
+		t.Error("fakeKMS.AddGrant() with unknown key err = nil, want error")
+	}
+	if _, err := fakeKMS.AddGrant(validKeyID); err == nil {
+		t.Error("fakeKMS.AddGrant() without operations err = nil, want error")
+	}
+	token, err := fakeKMS.AddGrant(validKeyID, types.GrantOperationEncrypt)
+	if err != nil {
+		t.Fatalf("fakeKMS.AddGrant() err = %v, want nil", err)
+	}
+	otherToken, err := fakeKMS.AddGrant(validKeyID2, types.GrantOperationEncrypt, types.GrantOperationDecrypt)
+	if err != nil {
+		t.Fatalf("fakeKMS.AddGrant() err = %v, want nil", err)
+	}
+
+	encrypt := func(grantTokens ...string) error {
+		_, err := fakeKMS.Encrypt(t.Context(), &kms.EncryptInput{
+			KeyId:       aws.String(validKeyID),
+			Plaintext:   []byte("plaintext"),
+			GrantTokens: grantTokens,
+		})
+		return err
+	}
+	if err := encrypt(token); err != nil {
+		t.Errorf("fakeKMS.Encrypt() with grant token err = %v, want nil", err)
+	}
+	for _, tokens := range [][]string{nil, {otherToken}, {"unknown"}} {
+		var apiErr smithy.APIError
+		if err := encrypt(tokens...); !errors.As(err, &apiErr) || apiErr.ErrorCode() != "AccessDeniedException" {
+			t.Errorf("fakeKMS.Encrypt() with grant tokens %q err = %v, want AccessDeniedException", tokens, err)
+		}
+	}
+
+	encResp, err := fakeKMS.Encrypt(t.Context(), &kms.EncryptInput{
+		KeyId:       aws.String(validKeyID),
+		Plaintext:   []byte("plaintext"),
+		GrantTokens: []string{token},
+	})
+	if err != nil {
+		t.Fatalf("fakeKMS.Encrypt() err = %v, want nil", err)
+	}
+	// The grant does not allow Decrypt.
+	_, err = fakeKMS.Decrypt(t.Context(), &kms.DecryptInput{
+		KeyId:          aws.String(validKeyID),
+		CiphertextBlob: encResp.CiphertextBlob,
+		GrantTokens:    []string{token},
+	})
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "AccessDeniedException" {
+		t.Errorf("fakeKMS.Decrypt() err = %v, want AccessDeniedException", err)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	fakeKMS, err := New([]string{validKeyID, validKeyID2})
+	if err != nil {
+		t.Fatalf("New() err = %s, want nil", err)
+	}
+	encResp, err := fakeKMS.Encrypt(t.Context(), &kms.EncryptInput{
+		KeyId:     aws.String(validKeyID),
+		Plaintext: []byte("plaintext"),
+	})
+	if err != nil {
+		t.Fatalf("fakeKMS.Encrypt() err = %v, want nil", err)
+	}
+	var dryRun *types.DryRunOperationException
+
+	_, err = fakeKMS.Encrypt(t.Context(), &kms.EncryptInput{
+		KeyId:     aws.String(validKeyID),
+		Plaintext: []byte("plaintext"),
+		DryRun:    aws.Bool(true),
+	})
+	if !errors.As(err, &dryRun) {
+		t.Errorf("fakeKMS.Encrypt() with DryRun err = %v, want DryRunOperationException", err)
+	}
+	_, err = fakeKMS.Decrypt(t.Context(), &kms.DecryptInput{
+		KeyId:          aws.String(validKeyID),
+		CiphertextBlob: encResp.CiphertextBlob,
+		DryRun:         aws.Bool(true),
+	})
+	if !errors.As(err, &dryRun) {
+		t.Errorf("fakeKMS.Decrypt() with DryRun err = %v, want DryRunOperationException", err)
+	}
+	_, err = fakeKMS.ReEncrypt(t.Context(), &kms.ReEncryptInput{
+		CiphertextBlob:   encResp.CiphertextBlob,
+		DestinationKeyId: aws.String(validKeyID2),
+		DryRun:           aws.Bool(true),
+	})
+	if !errors.As(err, &dryRun) {
+		t.Errorf("fakeKMS.ReEncrypt() with DryRun err = %v, want DryRunOperationException", err)
+	}
+
+	// Dry runs of requests which would fail report the failure.
+	_, err = fakeKMS.Decrypt(t.Context(), &kms.DecryptInput{
+		KeyId:          aws.String(validKeyID2),
+		CiphertextBlob: encResp.CiphertextBlob,
+		DryRun:         aws.Bool(true),
+	})
+	var invalidCiphertext *types.InvalidCiphertextException
+	if !errors.As(err, &invalidCiphertext) {
+		t.Errorf("fakeKMS.Decrypt() with DryRun and wrong key err = %v, want InvalidCiphertextException", err)
+	}
+	if _, err := fakeKMS.AddGrant(validKeyID, types.GrantOperationDecrypt); err != nil {
+		t.Fatalf("fakeKMS.AddGrant() err = %v, want nil", err)
+	}
+	_, err = fakeKMS.Encrypt(t.Context(), &kms.EncryptInput{
+		KeyId:     aws.String(validKeyID),
+		Plaintext: []byte("plaintext"),
+		DryRun:    aws.Bool(true),
+	})
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "AccessDeniedException" {
+		t.Errorf("fakeKMS.Encrypt() with DryRun and without grant err = %v, want AccessDeniedException", err)
+	}
+}
+
+func TestGenerateDataKey(t *testing.T) {
+	fakeKMS, err := New([]string{validKeyID})
+	if err != nil {
+		t.Fatalf("New() err = %s, want nil", err)
+	}
+	encryptionContext := map[string]string{"name": "value"}
+	resp, err := fakeKMS.GenerateDataKey(t.Context(), &kms.GenerateDataKeyInput{
+		KeyId:             aws.String(validKeyID),
+		KeySpec:           types.DataKeySpecAes256,
+		EncryptionContext: encryptionContext,
+	})
+	if err != nil {
+		t.Fatalf("fakeKMS.GenerateDataKey() err = %v, want nil", err)
+	}
+	if len(resp.Plaintext) != 32 {
+		t.Errorf("len(resp.Plaintext) = %d, want 32", len(resp.Plaintext))
+	}
+	if got := aws.ToString(resp.KeyId); got != validKeyID {
+		t.Errorf("resp.KeyId = %q, want %q", got, validKeyID)
+	}
+	decResp, err := fakeKMS.Decrypt(t.Context(), &kms.DecryptInput{
+		KeyId:             aws.String(validKeyID),
+		CiphertextBlob:    resp.CiphertextBlob,
+		EncryptionContext: encryptionContext,
+	})
+	if err != nil {
+		t.Fatalf("fakeKMS.Decrypt() err = %v, want nil", err)
+	}
+	if !bytes.Equal(decResp.Plaintext, resp.Plaintext) {
+		t.Errorf("decResp.Plaintext = %x, want %x", decResp.Plaintext, resp.Plaintext)
+	}
+
+	resp, err = fakeKMS.GenerateDataKey(t.Context(), &kms.GenerateDataKeyInput{
+		KeyId:         aws.String(validKeyID),
+		NumberOfBytes: aws.Int32(64),
+	})
+	if err != nil {
+		t.Fatalf("fakeKMS.GenerateDataKey() err = %v, want nil", err)
+	}
+	if len(resp.Plaintext) != 64 {
+		t.Errorf("len(resp.Plaintext) = %d, want 64", len(resp.Plaintext))
+	}
+
+	for _, params := range []*kms.GenerateDataKeyInput{
+		{KeyId: aws.String(validKeyID)},
+		{KeyId: aws.String(validKeyID), KeySpec: types.DataKeySpecAes256, NumberOfBytes: aws.Int32(32)},
+		{KeyId: aws.String(validKeyID), NumberOfBytes: aws.Int32(1025)},
+		{KeyId: aws.String(validKeyID2), KeySpec: types.DataKeySpecAes256},
+	} {
+		if _, err := fakeKMS.GenerateDataKey(t.Context(), params); err == nil {
+			t.Errorf("fakeKMS.GenerateDataKey(%+v) err = nil, want error", params)
+		}
+	}
+}
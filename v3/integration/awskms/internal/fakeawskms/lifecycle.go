@@ -0,0 +1,329 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakeawskms
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/tink-crypto/tink-go/v2/aead"
+	"github.com/tink-crypto/tink-go/v2/keyset"
+)
+
+// createdKeyARNPrefix is the ARN prefix of the keys created by CreateKey.
+const createdKeyARNPrefix = "arn:aws:kms:us-west-2:111122223333:key/"
+
+// Administrative operations, which are not grant operations. They can be
+// allowed by key policies, see Statement.
+const (
+	operationEnableKey           types.GrantOperation = "EnableKey"
+	operationDisableKey          types.GrantOperation = "DisableKey"
+	operationScheduleKeyDeletion types.GrantOperation = "ScheduleKeyDeletion"
+	operationRotateKeyOnDemand   types.GrantOperation = "RotateKeyOnDemand"
+)
+
+// keyLifecycle holds the metadata of a key which changes over its lifetime or
+// is set by CreateKey.
+type keyLifecycle struct {
+	state            types.KeyState
+	origin           types.OriginType
+	multiRegion      bool
+	description      string
+	customKeyStoreID string
+	creationDate     time.Time
+	deletionDate     *time.Time
+}
+
+// checkEnabled checks that keyID can be used in cryptographic operations.
+func (f *FakeAWSKMS) checkEnabled(keyID string) error {
+	switch state := f.lifecycles[keyID].state; state {
+	case types.KeyStateEnabled:
+		return nil
+	case types.KeyStateDisabled:
+		return &types.DisabledException{Message: aws.String(fmt.Sprintf("%s is disabled.", keyID))}
+	default:
+		return invalidStateError(keyID, state)
+	}
+}
+
+func invalidStateError(keyID string, state types.KeyState) error {
+	return &types.KMSInvalidStateException{Message: aws.String(fmt.Sprintf("%s is %s", keyID, state))}
+}
+
+// lifecycle returns the lifecycle of a key given by its ID, ARN or alias, and
+// its key ID in the fake.
+func (f *FakeAWSKMS) lifecycle(keyID string) (string, *keyLifecycle, error) {
+	keyID = f.resolve(keyID)
+	l, ok := f.lifecycles[keyID]
+	if !ok {
+		return "", nil, &types.NotFoundException{Message: aws.String(fmt.Sprintf("Unknown keyID: %q not in %q", keyID, f.keyIDs))}
+	}
+	return keyID, l, nil
+}
+
+// keyMetadata returns the metadata of keyID.
+func (f *FakeAWSKMS) keyMetadata(keyID string) *types.KeyMetadata {
+	s := f.specs[keyID]
+	l := f.lifecycles[keyID]
+	metadata := &types.KeyMetadata{
+		KeyId:                aws.String(keyID),
+		Enabled:              l.state == types.KeyStateEnabled,
+		KeyState:             l.state,
+		KeySpec:              s.spec,
+		KeyUsage:             s.usage,
+		Origin:               l.origin,
+		KeyManager:           types.KeyManagerTypeCustomer,
+		MultiRegion:          aws.Bool(l.multiRegion),
+		CreationDate:         aws.Time(l.creationDate),
+		DeletionDate:         l.deletionDate,
+		EncryptionAlgorithms: s.encryptionAlgorithms(),
+	}
+	if l.description != "" {
+		metadata.Description = aws.String(l.description)
+	}
+	if l.customKeyStoreID != "" {
+		metadata.CustomKeyStoreId = aws.String(l.customKeyStoreID)
+	}
+	// Key ARNs have the form arn:<partition>:kms:<region>:<account>:key/<id>.
+	if parts := strings.SplitN(keyID, ":", 6); len(parts) == 6 && parts[0] == "arn" && strings.HasPrefix(parts[5], "key/") {
+		metadata.Arn = aws.String(keyID)
+		metadata.AWSAccountId = aws.String(parts[4])
+		metadata.KeyId = aws.String(strings.TrimPrefix(parts[5], "key/"))
+	}
+	return metadata
+}
+
+// SetKeyState sets the state of keyID, for example to
+// types.KeyStateUnavailable, which the fake cannot reach otherwise. Keys can
+// only be used for cryptographic operations in the state
+// types.KeyStateEnabled.
+func (f *FakeAWSKMS) SetKeyState(keyID string, state types.KeyState) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	l, ok := f.lifecycles[keyID]
+	if !ok {
+		return fmt.Errorf("Unknown keyID: %q not in %q", keyID, f.keyIDs)
+	}
+	l.state = state
+	return nil
+}
+
+// newKeyID returns a random key ID, in the format of multi-Region keys if
+// multiRegion is set.
+func newKeyID(multiRegion bool) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	if multiRegion {
+		return "mrk-" + hex.EncodeToString(b), nil
+	}
+	h := hex.EncodeToString(b)
+	return strings.Join([]string{h[:8], h[8:12], h[12:16], h[16:20], h[20:]}, "-"), nil
+}
+
+// CreateKey creates a key in the region and account of createdKeyARNPrefix.
+// Like other keys, it can be referred to by its ARN, and also by its key ID.
+//
+// Keys with the origin types.OriginTypeExternal are created in the state
+// types.KeyStatePendingImport and cannot be used, as importing key material
+// is not supported. Keys with the origin types.OriginTypeAwsCloudhsm require
+// a custom key store added with AddCustomKeyStore. Tags and policies of the
+// request are ignored, see SetKeyPolicy.
+func (f *FakeAWSKMS) CreateKey(ctx context.Context, params *kms.CreateKeyInput, optFns ...func(*kms.Options)) (*kms.CreateKeyOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s := keySpec{spec: params.KeySpec, usage: params.KeyUsage}
+	if s.spec == "" {
+		s.spec = types.KeySpecSymmetricDefault
+	}
+	if s.usage == "" {
+		s.usage = types.KeyUsageTypeEncryptDecrypt
+	}
+	if s.spec == types.KeySpecSymmetricDefault && s.usage != types.KeyUsageTypeEncryptDecrypt {
+		return nil, validationError("key spec %s does not support key usage %s", s.spec, s.usage)
+	}
+	l := &keyLifecycle{
+		state:       types.KeyStateEnabled,
+		origin:      params.Origin,
+		multiRegion: aws.ToBool(params.MultiRegion),
+		description: aws.ToString(params.Description),
+	}
+	if l.origin == "" {
+		l.origin = types.OriginTypeAwsKms
+	}
+	switch l.origin {
+	case types.OriginTypeAwsKms:
+	case types.OriginTypeExternal:
+		l.state = types.KeyStatePendingImport
+	case types.OriginTypeAwsCloudhsm:
+		id := aws.ToString(params.CustomKeyStoreId)
+		if !f.customKeyStores[id] {
+			return nil, &types.CustomKeyStoreNotFoundException{Message: aws.String(fmt.Sprintf("custom key store %q not found", id))}
+		}
+		if s != symmetricKeySpec || l.multiRegion {
+			return nil, &types.UnsupportedOperationException{Message: aws.String("keys in custom key stores must be single-Region symmetric encryption keys")}
+		}
+		l.customKeyStoreID = id
+	default:
+		return nil, &types.UnsupportedOperationException{Message: aws.String(fmt.Sprintf("origin %s is not supported by the fake", l.origin))}
+	}
+	if params.CustomKeyStoreId != nil && l.origin != types.OriginTypeAwsCloudhsm {
+		return nil, validationError("CustomKeyStoreId requires origin %s", types.OriginTypeAwsCloudhsm)
+	}
+	id, err := newKeyID(l.multiRegion)
+	if err != nil {
+		return nil, err
+	}
+	keyID := createdKeyARNPrefix + id
+	if err := f.addKey(keyID, s, l); err != nil {
+		return nil, err
+	}
+	f.aliases[id] = keyID
+	return &kms.CreateKeyOutput{KeyMetadata: f.keyMetadata(keyID)}, nil
+}
+
+// EnableKey enables a disabled key.
+func (f *FakeAWSKMS) EnableKey(ctx context.Context, params *kms.EnableKeyInput, optFns ...func(*kms.Options)) (*kms.EnableKeyOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	keyID, l, err := f.lifecycle(aws.ToString(params.KeyId))
+	if err != nil {
+		return nil, err
+	}
+	if err := f.authorize(keyID, operationEnableKey, nil, nil); err != nil {
+		return nil, err
+	}
+	if l.state != types.KeyStateEnabled && l.state != types.KeyStateDisabled {
+		return nil, invalidStateError(keyID, l.state)
+	}
+	l.state = types.KeyStateEnabled
+	return &kms.EnableKeyOutput{}, nil
+}
+
+// DisableKey disables an enabled key, so that it cannot be used for
+// cryptographic operations until it is enabled again.
+func (f *FakeAWSKMS) DisableKey(ctx context.Context, params *kms.DisableKeyInput, optFns ...func(*kms.Options)) (*kms.DisableKeyOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	keyID, l, err := f.lifecycle(aws.ToString(params.KeyId))
+	if err != nil {
+		return nil, err
+	}
+	if err := f.authorize(keyID, operationDisableKey, nil, nil); err != nil {
+		return nil, err
+	}
+	if l.state != types.KeyStateEnabled && l.state != types.KeyStateDisabled {
+		return nil, invalidStateError(keyID, l.state)
+	}
+	l.state = types.KeyStateDisabled
+	return &kms.DisableKeyOutput{}, nil
+}
+
+// ScheduleKeyDeletion puts a key in the state types.KeyStatePendingDeletion.
+// The fake never deletes keys, but they cannot be used anymore.
+func (f *FakeAWSKMS) ScheduleKeyDeletion(ctx context.Context, params *kms.ScheduleKeyDeletionInput, optFns ...func(*kms.Options)) (*kms.ScheduleKeyDeletionOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	days := int32(30)
+	if params.PendingWindowInDays != nil {
+		days = *params.PendingWindowInDays
+	}
+	if days < 7 || days > 30 {
+		return nil, validationError("PendingWindowInDays must be between 7 and 30, but got %d", days)
+	}
+	keyID, l, err := f.lifecycle(aws.ToString(params.KeyId))
+	if err != nil {
+		return nil, err
+	}
+	if err := f.authorize(keyID, operationScheduleKeyDeletion, nil, nil); err != nil {
+		return nil, err
+	}
+	if l.state == types.KeyStatePendingDeletion {
+		return nil, invalidStateError(keyID, l.state)
+	}
+	deletionDate := time.Now().AddDate(0, 0, int(days))
+	l.state = types.KeyStatePendingDeletion
+	l.deletionDate = &deletionDate
+	return &kms.ScheduleKeyDeletionOutput{
+		KeyId:               aws.String(keyID),
+		KeyState:            l.state,
+		DeletionDate:        aws.Time(deletionDate),
+		PendingWindowInDays: aws.Int32(days),
+	}, nil
+}
+
+// RotateKeyOnDemand rotates the key material of an enabled symmetric
+// encryption key with origin types.OriginTypeAwsKms. Like in AWS KMS, the
+// key keeps its ID and ciphertexts encrypted with previous key material can
+// still be decrypted.
+func (f *FakeAWSKMS) RotateKeyOnDemand(ctx context.Context, params *kms.RotateKeyOnDemandInput, optFns ...func(*kms.Options)) (*kms.RotateKeyOnDemandOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	keyID, l, err := f.lifecycle(aws.ToString(params.KeyId))
+	if err != nil {
+		return nil, err
+	}
+	if err := f.authorize(keyID, operationRotateKeyOnDemand, nil, nil); err != nil {
+		return nil, err
+	}
+	if f.specs[keyID] != symmetricKeySpec || l.origin != types.OriginTypeAwsKms {
+		return nil, &types.UnsupportedOperationException{Message: aws.String(fmt.Sprintf("key %q does not support rotation", keyID))}
+	}
+	if err := f.checkEnabled(keyID); err != nil {
+		return nil, err
+	}
+	manager := keyset.NewManagerFromHandle(f.handles[keyID])
+	id, err := manager.Add(aead.AES256GCMKeyTemplate())
+	if err != nil {
+		return nil, err
+	}
+	if err := manager.SetPrimary(id); err != nil {
+		return nil, err
+	}
+	handle, err := manager.Handle()
+	if err != nil {
+		return nil, err
+	}
+	a, err := aead.New(handle)
+	if err != nil {
+		return nil, err
+	}
+	f.handles[keyID] = handle
+	f.aeads[keyID] = a
+	return &kms.RotateKeyOnDemandOutput{KeyId: aws.String(keyID)}, nil
+}
@@ -0,0 +1,413 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakeawskms
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+func describeKey(t *testing.T, f *FakeAWSKMS, keyID string) *types.KeyMetadata {
+	t.Helper()
+	resp, err := f.DescribeKey(t.Context(), &kms.DescribeKeyInput{KeyId: aws.String(keyID)})
+	if err != nil {
+		t.Fatalf("f.DescribeKey() err = %v, want nil", err)
+	}
+	return resp.KeyMetadata
+}
+
+func isDisabled(err error) bool {
+	var disabled *types.DisabledException
+	return errors.As(err, &disabled)
+}
+
+func isInvalidState(err error) bool {
+	var invalidState *types.KMSInvalidStateException
+	return errors.As(err, &invalidState)
+}
+
+func TestCreateKey(t *testing.T) {
+	f, err := New(nil)
+	if err != nil {
+		t.Fatalf("New() err = %v, want nil", err)
+	}
+	resp, err := f.CreateKey(t.Context(), &kms.CreateKeyInput{Description: aws.String("test key")})
+	if err != nil {
+		t.Fatalf("f.CreateKey() err = %v, want nil", err)
+	}
+	m := resp.KeyMetadata
+	arn := aws.ToString(m.Arn)
+	if !strings.HasPrefix(arn, createdKeyARNPrefix) || arn != createdKeyARNPrefix+aws.ToString(m.KeyId) {
+		t.Errorf("KeyMetadata.Arn = %q, KeyMetadata.KeyId = %q, want ARN of the key ID", arn, aws.ToString(m.KeyId))
+	}
+	if !m.Enabled || m.KeyState != types.KeyStateEnabled || m.KeySpec != types.KeySpecSymmetricDefault || m.KeyUsage != types.KeyUsageTypeEncryptDecrypt || m.Origin != types.OriginTypeAwsKms {
+		t.Errorf("KeyMetadata = %+v, want enabled symmetric encryption key", m)
+	}
+	if aws.ToString(m.Description) != "test key" || aws.ToBool(m.MultiRegion) || m.CreationDate == nil {
+		t.Errorf("KeyMetadata = %+v, want description and creation date of a single-Region key", m)
+	}
+
+	// The key can be used with its ARN and its key ID.
+	ciphertext, err := encryptWith(f, aws.ToString(m.KeyId), nil)
+	if err != nil {
+		t.Fatalf("encryptWith() with key ID err = %v, want nil", err)
+	}
+	decResponse, err := f.Decrypt(t.Context(), &kms.DecryptInput{KeyId: m.Arn, CiphertextBlob: ciphertext})
+	if err != nil {
+		t.Fatalf("f.Decrypt() with ARN err = %v, want nil", err)
+	}
+	if got := aws.ToString(decResponse.KeyId); got != arn {
+		t.Errorf("decResponse.KeyId = %q, want %q", got, arn)
+	}
+	if got := describeKey(t, f, arn); !got.CreationDate.Equal(*m.CreationDate) {
+		t.Errorf("DescribeKey().CreationDate = %v, want %v", got.CreationDate, m.CreationDate)
+	}
+}
+
+func TestCreateKey_metadata(t *testing.T) {
+	f, err := New(nil)
+	if err != nil {
+		t.Fatalf("New() err = %v, want nil", err)
+	}
+	if err := f.AddCustomKeyStore("cks-1234567890abcdef0"); err != nil {
+		t.Fatalf("f.AddCustomKeyStore() err = %v, want nil", err)
+	}
+	for _, test := range []struct {
+		name  string
+		input *kms.CreateKeyInput
+		check func(m *types.KeyMetadata) bool
+	}{
+		{
+			name:  "multi-Region",
+			input: &kms.CreateKeyInput{MultiRegion: aws.Bool(true)},
+			check: func(m *types.KeyMetadata) bool {
+				return aws.ToBool(m.MultiRegion) && strings.HasPrefix(aws.ToString(m.KeyId), "mrk-")
+			},
+		},
+		{
+			name:  "HMAC",
+			input: &kms.CreateKeyInput{KeySpec: types.KeySpecHmac256, KeyUsage: types.KeyUsageTypeGenerateVerifyMac},
+			check: func(m *types.KeyMetadata) bool {
+				return m.KeySpec == types.KeySpecHmac256 && m.KeyUsage == types.KeyUsageTypeGenerateVerifyMac
+			},
+		},
+		{
+			name:  "external",
+			input: &kms.CreateKeyInput{Origin: types.OriginTypeExternal},
+			check: func(m *types.KeyMetadata) bool {
+				return m.Origin == types.OriginTypeExternal && m.KeyState == types.KeyStatePendingImport && !m.Enabled
+			},
+		},
+		{
+			name:  "custom key store",
+			input: &kms.CreateKeyInput{Origin: types.OriginTypeAwsCloudhsm, CustomKeyStoreId: aws.String("cks-1234567890abcdef0")},
+			check: func(m *types.KeyMetadata) bool {
+				return m.Origin == types.OriginTypeAwsCloudhsm && aws.ToString(m.CustomKeyStoreId) == "cks-1234567890abcdef0" && m.Enabled
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			resp, err := f.CreateKey(t.Context(), test.input)
+			if err != nil {
+				t.Fatalf("f.CreateKey() err = %v, want nil", err)
+			}
+			if !test.check(resp.KeyMetadata) {
+				t.Errorf("f.CreateKey() = %+v, want other metadata", resp.KeyMetadata)
+			}
+			if got := describeKey(t, f, aws.ToString(resp.KeyMetadata.Arn)); !test.check(got) {
+				t.Errorf("f.DescribeKey() = %+v, want other metadata", got)
+			}
+		})
+	}
+}
+
+func TestCreateKey_pendingImportCannotBeUsed(t *testing.T) {
+	f, err := New(nil)
+	if err != nil {
+		t.Fatalf("New() err = %v, want nil", err)
+	}
+	resp, err := f.CreateKey(t.Context(), &kms.CreateKeyInput{Origin: types.OriginTypeExternal})
+	if err != nil {
+		t.Fatalf("f.CreateKey() err = %v, want nil", err)
+	}
+	if _, err := encryptWith(f, aws.ToString(resp.KeyMetadata.Arn), nil); !isInvalidState(err) {
+		t.Errorf("encryptWith() err = %v, want KMSInvalidStateException", err)
+	}
+	if _, err := f.EnableKey(t.Context(), &kms.EnableKeyInput{KeyId: resp.KeyMetadata.Arn}); !isInvalidState(err) {
+		t.Errorf("f.EnableKey() err = %v, want KMSInvalidStateException", err)
+	}
+	if _, err := f.RotateKeyOnDemand(t.Context(), &kms.RotateKeyOnDemandInput{KeyId: resp.KeyMetadata.Arn}); err == nil {
+		t.Error("f.RotateKeyOnDemand() err = nil, want error")
+	}
+}
+
+func TestCreateKey_fails(t *testing.T) {
+	f, err := New(nil)
+	if err != nil {
+		t.Fatalf("New() err = %v, want nil", err)
+	}
+	for _, test := range []struct {
+		name  string
+		input *kms.CreateKeyInput
+	}{
+		{"invalid usage", &kms.CreateKeyInput{KeyUsage: types.KeyUsageTypeSignVerify}},
+		{"unknown custom key store", &kms.CreateKeyInput{Origin: types.OriginTypeAwsCloudhsm, CustomKeyStoreId: aws.String("cks-unknown")}},
+		{"custom key store without origin", &kms.CreateKeyInput{CustomKeyStoreId: aws.String("cks-unknown")}},
+		{"external key store", &kms.CreateKeyInput{Origin: types.OriginTypeExternalKeyStore}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := f.CreateKey(t.Context(), test.input); err == nil {
+				t.Error("f.CreateKey() err = nil, want error")
+			}
+		})
+	}
+}
+
+func TestDisableKey(t *testing.T) {
+	f, err := New([]string{validKeyID})
+	if err != nil {
+		t.Fatalf("New() err = %v, want nil", err)
+	}
+	ciphertext, err := encryptWith(f, validKeyID, nil)
+	if err != nil {
+		t.Fatalf("encryptWith() err = %v, want nil", err)
+	}
+	if _, err := f.DisableKey(t.Context(), &kms.DisableKeyInput{KeyId: aws.String(validKeyID)}); err != nil {
+		t.Fatalf("f.DisableKey() err = %v, want nil", err)
+	}
+	if m := describeKey(t, f, validKeyID); m.Enabled || m.KeyState != types.KeyStateDisabled {
+		t.Errorf("f.DescribeKey() = %+v, want disabled key", m)
+	}
+	if _, err := encryptWith(f, validKeyID, nil); !isDisabled(err) {
+		t.Errorf("encryptWith() err = %v, want DisabledException", err)
+	}
+	if _, err := f.Decrypt(t.Context(), &kms.DecryptInput{CiphertextBlob: ciphertext}); !isDisabled(err) {
+		t.Errorf("f.Decrypt() err = %v, want DisabledException", err)
+	}
+	if _, err := f.DisableKey(t.Context(), &kms.DisableKeyInput{KeyId: aws.String(validKeyID)}); err != nil {
+		t.Errorf("f.DisableKey() with disabled key err = %v, want nil", err)
+	}
+
+	if _, err := f.EnableKey(t.Context(), &kms.EnableKeyInput{KeyId: aws.String(validKeyID)}); err != nil {
+		t.Fatalf("f.EnableKey() err = %v, want nil", err)
+	}
+	if m := describeKey(t, f, validKeyID); !m.Enabled || m.KeyState != types.KeyStateEnabled {
+		t.Errorf("f.DescribeKey() = %+v, want enabled key", m)
+	}
+	if _, err := f.Decrypt(t.Context(), &kms.DecryptInput{CiphertextBlob: ciphertext}); err != nil {
+		t.Errorf("f.Decrypt() err = %v, want nil", err)
+	}
+
+	var notFound *types.NotFoundException
+	if _, err := f.DisableKey(t.Context(), &kms.DisableKeyInput{KeyId: aws.String(validKeyID2)}); !errors.As(err, &notFound) {
+		t.Errorf("f.DisableKey() with unknown key err = %v, want NotFoundException", err)
+	}
+}
+
+func TestScheduleKeyDeletion(t *testing.T) {
+	f, err := New([]string{validKeyID})
+	if err != nil {
+		t.Fatalf("New() err = %v, want nil", err)
+	}
+	for _, days := range []int32{0, 6, 31} {
+		if _, err := f.ScheduleKeyDeletion(t.Context(), &kms.ScheduleKeyDeletionInput{KeyId: aws.String(validKeyID), PendingWindowInDays: aws.Int32(days)}); err == nil {
+			t.Errorf("f.ScheduleKeyDeletion() with %d days err = nil, want error", days)
+		}
+	}
+	resp, err := f.ScheduleKeyDeletion(t.Context(), &kms.ScheduleKeyDeletionInput{KeyId: aws.String(validKeyID)})
+	if err != nil {
+		t.Fatalf("f.ScheduleKeyDeletion() err = %v, want nil", err)
+	}
+	if resp.KeyState != types.KeyStatePendingDeletion || aws.ToInt32(resp.PendingWindowInDays) != 30 || aws.ToString(resp.KeyId) != validKeyID {
+		t.Errorf("f.ScheduleKeyDeletion() = %+v, want deletion of %q in 30 days", resp, validKeyID)
+	}
+	if d := time.Until(aws.ToTime(resp.DeletionDate)); d < 29*24*time.Hour || d > 31*24*time.Hour {
+		t.Errorf("resp.DeletionDate = %v, want in 30 days", resp.DeletionDate)
+	}
+	m := describeKey(t, f, validKeyID)
+	if m.Enabled || m.KeyState != types.KeyStatePendingDeletion || !aws.ToTime(m.DeletionDate).Equal(aws.ToTime(resp.DeletionDate)) {
+		t.Errorf("f.DescribeKey() = %+v, want key pending deletion", m)
+	}
+
+	if _, err := encryptWith(f, validKeyID, nil); !isInvalidState(err) {
+		t.Errorf("encryptWith() err = %v, want KMSInvalidStateException", err)
+	}
+	if _, err := f.EnableKey(t.Context(), &kms.EnableKeyInput{KeyId: aws.String(validKeyID)}); !isInvalidState(err) {
+		t.Errorf("f.EnableKey() err = %v, want KMSInvalidStateException", err)
+	}
+	if _, err := f.DisableKey(t.Context(), &kms.DisableKeyInput{KeyId: aws.String(validKeyID)}); !isInvalidState(err) {
+		t.Errorf("f.DisableKey() err = %v, want KMSInvalidStateException", err)
+	}
+	if _, err := f.ScheduleKeyDeletion(t.Context(), &kms.ScheduleKeyDeletionInput{KeyId: aws.String(validKeyID)}); !isInvalidState(err) {
+		t.Errorf("f.ScheduleKeyDeletion() again err = %v, want KMSInvalidStateException", err)
+	}
+}
+
+func TestRotateKeyOnDemand(t *testing.T) {
+	f, err := New([]string{validKeyID})
+	if err != nil {
+		t.Fatalf("New() err = %v, want nil", err)
+	}
+	encryptionContext := map[string]string{"contextName": "contextValue"}
+	oldCiphertext, err := encryptWith(f, validKeyID, encryptionContext)
+	if err != nil {
+		t.Fatalf("encryptWith() err = %v, want nil", err)
+	}
+	resp, err := f.RotateKeyOnDemand(t.Context(), &kms.RotateKeyOnDemandInput{KeyId: aws.String(validKeyID)})
+	if err != nil {
+		t.Fatalf("f.RotateKeyOnDemand() err = %v, want nil", err)
+	}
+	if got := aws.ToString(resp.KeyId); got != validKeyID {
+		t.Errorf("resp.KeyId = %q, want %q", got, validKeyID)
+	}
+	newCiphertext, err := encryptWith(f, validKeyID, encryptionContext)
+	if err != nil {
+		t.Fatalf("encryptWith() after rotation err = %v, want nil", err)
+	}
+	// Both key materials are prefixed with the ID of their key in the keyset
+	// of the fake.
+	if bytes.Equal(oldCiphertext[:5], newCiphertext[:5]) {
+		t.Error("ciphertexts before and after rotation use the same key material")
+	}
+	for _, ciphertext := range [][]byte{oldCiphertext, newCiphertext} {
+		decResponse, err := f.Decrypt(t.Context(), &kms.DecryptInput{
+			CiphertextBlob:    ciphertext,
+			EncryptionContext: encryptionContext,
+		})
+		if err != nil {
+			t.Fatalf("f.Decrypt() err = %v, want nil", err)
+		}
+		if got := aws.ToString(decResponse.KeyId); got != validKeyID {
+			t.Errorf("decResponse.KeyId = %q, want %q", got, validKeyID)
+		}
+	}
+}
+
+func TestRotateKeyOnDemand_fails(t *testing.T) {
+	f, err := New([]string{validKeyID})
+	if err != nil {
+		t.Fatalf("New() err = %v, want nil", err)
+	}
+	if err := f.AddKey(validKeyID2, types.KeySpecRsa2048, types.KeyUsageTypeEncryptDecrypt); err != nil {
+		t.Fatalf("f.AddKey() err = %v, want nil", err)
+	}
+	var unsupported *types.UnsupportedOperationException
+	if _, err := f.RotateKeyOnDemand(t.Context(), &kms.RotateKeyOnDemandInput{KeyId: aws.String(validKeyID2)}); !errors.As(err, &unsupported) {
+		t.Errorf("f.RotateKeyOnDemand() with RSA key err = %v, want UnsupportedOperationException", err)
+	}
+	if _, err := f.DisableKey(t.Context(), &kms.DisableKeyInput{KeyId: aws.String(validKeyID)}); err != nil {
+		t.Fatalf("f.DisableKey() err = %v, want nil", err)
+	}
+	if _, err := f.RotateKeyOnDemand(t.Context(), &kms.RotateKeyOnDemandInput{KeyId: aws.String(validKeyID)}); !isDisabled(err) {
+		t.Errorf("f.RotateKeyOnDemand() with disabled key err = %v, want DisabledException", err)
+	}
+}
+
+func TestSetKeyState(t *testing.T) {
+	f, err := New([]string{validKeyID})
+	if err != nil {
+		t.Fatalf("New() err = %v, want nil", err)
+	}
+	if err := f.AddKey(validKeyID2, types.KeySpecRsa2048, types.KeyUsageTypeEncryptDecrypt); err != nil {
+		t.Fatalf("f.AddKey() err = %v, want nil", err)
+	}
+	for _, keyID := range []string{validKeyID, validKeyID2} {
+		if err := f.SetKeyState(keyID, types.KeyStateUnavailable); err != nil {
+			t.Fatalf("f.SetKeyState() err = %v, want nil", err)
+		}
+		if m := describeKey(t, f, keyID); m.Enabled || m.KeyState != types.KeyStateUnavailable {
+			t.Errorf("f.DescribeKey() = %+v, want unavailable key", m)
+		}
+	}
+	if _, err := encryptWith(f, validKeyID, nil); !isInvalidState(err) {
+		t.Errorf("encryptWith() err = %v, want KMSInvalidStateException", err)
+	}
+	if _, err := f.Encrypt(t.Context(), &kms.EncryptInput{
+		KeyId:               aws.String(validKeyID2),
+		Plaintext:           []byte("plaintext"),
+		EncryptionAlgorithm: types.EncryptionAlgorithmSpecRsaesOaepSha256,
+	}); !isInvalidState(err) {
+		t.Errorf("f.Encrypt() with RSA key err = %v, want KMSInvalidStateException", err)
+	}
+	if err := f.SetKeyState("unknown", types.KeyStateEnabled); err == nil {
+		t.Error("f.SetKeyState() with unknown key err = nil, want error")
+	}
+}
+
+func TestKeyPolicy_administrativeOperations(t *testing.T) {
+	f, err := New([]string{validKeyID})
+	if err != nil {
+		t.Fatalf("New() err = %v, want nil", err)
+	}
+	if err := f.SetKeyPolicy(validKeyID, Policy{Statements: []Statement{
+		{Principals: []string{writerARN}, Operations: []types.GrantOperation{"DisableKey"}},
+	}}); err != nil {
+		t.Fatalf("f.SetKeyPolicy() err = %v, want nil", err)
+	}
+	input := &kms.DisableKeyInput{KeyId: aws.String(validKeyID)}
+	if _, err := f.AsPrincipal(readerARN).DisableKey(t.Context(), input); !isAccessDenied(err) {
+		t.Errorf("reader.DisableKey() err = %v, want AccessDeniedException", err)
+	}
+	if _, err := f.AsPrincipal(writerARN).DisableKey(t.Context(), input); err != nil {
+		t.Errorf("writer.DisableKey() err = %v, want nil", err)
+	}
+}
+
+func TestHandler_keyLifecycle(t *testing.T) {
+	f, err := New(nil)
+	if err != nil {
+		t.Fatalf("New() err = %v, want nil", err)
+	}
+	client := newServedClient(t, f)
+	createResponse, err := client.CreateKey(t.Context(), &kms.CreateKeyInput{})
+	if err != nil {
+		t.Fatalf("client.CreateKey() err = %v, want nil", err)
+	}
+	keyID := createResponse.KeyMetadata.KeyId
+	if _, err := client.RotateKeyOnDemand(t.Context(), &kms.RotateKeyOnDemandInput{KeyId: keyID}); err != nil {
+		t.Errorf("client.RotateKeyOnDemand() err = %v, want nil", err)
+	}
+	if _, err := client.DisableKey(t.Context(), &kms.DisableKeyInput{KeyId: keyID}); err != nil {
+		t.Fatalf("client.DisableKey() err = %v, want nil", err)
+	}
+	if _, err := client.Encrypt(t.Context(), &kms.EncryptInput{KeyId: keyID, Plaintext: []byte("plaintext")}); !isDisabled(err) {
+		t.Errorf("client.Encrypt() err = %v, want DisabledException", err)
+	}
+	if _, err := client.EnableKey(t.Context(), &kms.EnableKeyInput{KeyId: keyID}); err != nil {
+		t.Fatalf("client.EnableKey() err = %v, want nil", err)
+	}
+	scheduleResponse, err := client.ScheduleKeyDeletion(t.Context(), &kms.ScheduleKeyDeletionInput{KeyId: keyID, PendingWindowInDays: aws.Int32(7)})
+	if err != nil {
+		t.Fatalf("client.ScheduleKeyDeletion() err = %v, want nil", err)
+	}
+	describeResponse, err := client.DescribeKey(t.Context(), &kms.DescribeKeyInput{KeyId: keyID})
+	if err != nil {
+		t.Fatalf("client.DescribeKey() err = %v, want nil", err)
+	}
+	m := describeResponse.KeyMetadata
+	if m.KeyState != types.KeyStatePendingDeletion || !aws.ToTime(m.DeletionDate).Equal(aws.ToTime(scheduleResponse.DeletionDate)) {
+		t.Errorf("client.DescribeKey() = %+v, want key pending deletion", m)
+	}
+	if got, want := aws.ToTime(m.CreationDate).Unix(), aws.ToTime(createResponse.KeyMetadata.CreationDate).Unix(); got != want {
+		t.Errorf("CreationDate = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakeawskms
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+// Policy is a simplified AWS KMS key policy, see SetKeyPolicy. An operation is
+// allowed if any of its statements allows it; there are no Deny statements.
+type Policy struct {
+	Statements []Statement
+}
+
+// Statement is an Allow statement of a Policy.
+type Statement struct {
+	// Principals are the principals the statement applies to, see
+	// AsPrincipal. If empty, it applies to all principals, like "*".
+	Principals []string
+	// Operations are the allowed operations, such as
+	// types.GrantOperationEncrypt. Like in grants, ReEncrypt requires
+	// ReEncryptFrom on the source key and ReEncryptTo on the destination key.
+	// Administrative operations are named after their API, for example
+	// types.GrantOperation("DisableKey").
+	Operations []types.GrantOperation
+	// EncryptionContext holds the entries the encryption context of the
+	// request must contain, like a StringEquals condition on the
+	// kms:EncryptionContext:<key> condition keys.
+	EncryptionContext map[string]string
+	// EncryptionContextKeys are the keys the encryption context of the request
+	// must contain, with any value, like a ForAnyValue:StringEquals condition
+	// on the kms:EncryptionContextKeys condition key for each of them.
+	EncryptionContextKeys []string
+}
+
+// SetKeyPolicy sets the policy of keyID, replacing any previous one. Once a key
+// has a policy, operations with the key fail with an AccessDeniedException
+// unless the policy allows them for the principal of the client, with the
+// encryption context of the request, or the request contains the token of a
+// grant for the operation, see AddGrant.
+//
+// Like for the kms:EncryptionContext condition keys of AWS KMS, the
+// encryption context of a ReEncrypt request is checked against the policy of
+// the source key for the source context, and against the policy of the
+// destination key for the destination context. Keys without a policy and
+// without grants allow all operations.
+func (f *FakeAWSKMS) SetKeyPolicy(keyID string, policy Policy) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.aeads[keyID]; !ok {
+		return fmt.Errorf("Unknown keyID: %q not in %q", keyID, f.keyIDs)
+	}
+	statements := make([]Statement, len(policy.Statements))
+	for i, s := range policy.Statements {
+		if len(s.Operations) == 0 {
+			return errors.New("a policy statement requires at least one operation")
+		}
+		statements[i] = Statement{
+			Principals:            slices.Clone(s.Principals),
+			Operations:            slices.Clone(s.Operations),
+			EncryptionContext:     maps.Clone(s.EncryptionContext),
+			EncryptionContextKeys: slices.Clone(s.EncryptionContextKeys),
+		}
+	}
+	f.policies[keyID] = Policy{Statements: statements}
+	return nil
+}
+
+// AsPrincipal returns a client of the same fake AWS KMS whose requests are
+// made by principal, for example "arn:aws:iam::111122223333:role/writer",
+// which is matched against the Principals of the key policies. Keys, aliases,
+// grants and policies are shared with f, including those added later.
+//
+// Requests of clients returned by New have no principal, so only statements
+// without Principals apply to them.
+func (f *FakeAWSKMS) AsPrincipal(principal string) *FakeAWSKMS {
+	return &FakeAWSKMS{state: f.state, principal: principal}
+}
+
+// allows reports whether any statement of p allows op for principal with
+// encryptionContext.
+func (p Policy) allows(principal string, op types.GrantOperation, encryptionContext map[string]string) bool {
+	for _, s := range p.Statements {
+		if s.allows(principal, op, encryptionContext) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s Statement) allows(principal string, op types.GrantOperation, encryptionContext map[string]string) bool {
+	if len(s.Principals) > 0 && !slices.Contains(s.Principals, principal) {
+		return false
+	}
+	if !slices.Contains(s.Operations, op) {
+		return false
+	}
+	for key, want := range s.EncryptionContext {
+		if got, ok := encryptionContext[key]; !ok || got != want {
+			return false
+		}
+	}
+	for _, key := range s.EncryptionContextKeys {
+		if _, ok := encryptionContext[key]; !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,271 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakeawskms
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/aws/smithy-go"
+)
+
+const (
+	writerARN = "arn:aws:iam::111122223333:role/writer"
+	readerARN = "arn:aws:iam::111122223333:role/reader"
+)
+
+func isAccessDenied(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "AccessDeniedException"
+}
+
+func encryptWith(f *FakeAWSKMS, keyID string, encryptionContext map[string]string) ([]byte, error) {
+	resp, err := f.Encrypt(context.Background(), &kms.EncryptInput{
+		KeyId:             aws.String(keyID),
+		Plaintext:         []byte("plaintext"),
+		EncryptionContext: encryptionContext,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.CiphertextBlob, nil
+}
+
+func TestSetKeyPolicy_operations(t *testing.T) {
+	f, err := New([]string{validKeyID})
+	if err != nil {
+		t.Fatalf("New() err = %v, want nil", err)
+	}
+	ciphertext, err := encryptWith(f, validKeyID, nil)
+	if err != nil {
+		t.Fatalf("encryptWith() without policy err = %v, want nil", err)
+	}
+	if err := f.SetKeyPolicy(validKeyID, Policy{Statements: []Statement{
+		{Operations: []types.GrantOperation{types.GrantOperationDecrypt}},
+	}}); err != nil {
+		t.Fatalf("f.SetKeyPolicy() err = %v, want nil", err)
+	}
+
+	_, err = encryptWith(f, validKeyID, nil)
+	if !isAccessDenied(err) {
+		t.Errorf("encryptWith() err = %v, want AccessDeniedException", err)
+	}
+	if err != nil && !strings.Contains(err.Error(), "kms:Encrypt") {
+		t.Errorf("encryptWith() err = %v, want mention of kms:Encrypt", err)
+	}
+	if _, err := f.Decrypt(t.Context(), &kms.DecryptInput{CiphertextBlob: ciphertext}); err != nil {
+		t.Errorf("f.Decrypt() err = %v, want nil", err)
+	}
+	if _, err := f.DescribeKey(t.Context(), &kms.DescribeKeyInput{KeyId: aws.String(validKeyID)}); !isAccessDenied(err) {
+		t.Errorf("f.DescribeKey() err = %v, want AccessDeniedException", err)
+	}
+}
+
+func TestSetKeyPolicy_encryptionContext(t *testing.T) {
+	f, err := New([]string{validKeyID})
+	if err != nil {
+		t.Fatalf("New() err = %v, want nil", err)
+	}
+	if err := f.SetKeyPolicy(validKeyID, Policy{Statements: []Statement{
+		{
+			Operations:        []types.GrantOperation{types.GrantOperationEncrypt},
+			EncryptionContext: map[string]string{"tenant": "a"},
+		},
+		{
+			Operations:            []types.GrantOperation{types.GrantOperationGenerateDataKey},
+			EncryptionContextKeys: []string{"associatedData"},
+		},
+	}}); err != nil {
+		t.Fatalf("f.SetKeyPolicy() err = %v, want nil", err)
+	}
+
+	for _, test := range []struct {
+		name              string
+		encryptionContext map[string]string
+		wantAllowed       bool
+	}{
+		{"required entry", map[string]string{"tenant": "a"}, true},
+		{"additional entries", map[string]string{"tenant": "a", "table": "users"}, true},
+		{"other value", map[string]string{"tenant": "b"}, false},
+		{"other key", map[string]string{"associatedData": "a"}, false},
+		{"no context", nil, false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := encryptWith(f, validKeyID, test.encryptionContext)
+			if test.wantAllowed && err != nil {
+				t.Errorf("encryptWith() err = %v, want nil", err)
+			}
+			if !test.wantAllowed && !isAccessDenied(err) {
+				t.Errorf("encryptWith() err = %v, want AccessDeniedException", err)
+			}
+		})
+	}
+
+	generateDataKey := func(encryptionContext map[string]string) error {
+		_, err := f.GenerateDataKey(t.Context(), &kms.GenerateDataKeyInput{
+			KeyId:             aws.String(validKeyID),
+			KeySpec:           types.DataKeySpecAes256,
+			EncryptionContext: encryptionContext,
+		})
+		return err
+	}
+	if err := generateDataKey(map[string]string{"associatedData": "0102"}); err != nil {
+		t.Errorf("f.GenerateDataKey() with required key err = %v, want nil", err)
+	}
+	if err := generateDataKey(map[string]string{"additionalData": "0102"}); !isAccessDenied(err) {
+		t.Errorf("f.GenerateDataKey() without required key err = %v, want AccessDeniedException", err)
+	}
+}
+
+func TestSetKeyPolicy_principals(t *testing.T) {
+	f, err := New([]string{validKeyID})
+	if err != nil {
+		t.Fatalf("New() err = %v, want nil", err)
+	}
+	if err := f.SetKeyPolicy(validKeyID, Policy{Statements: []Statement{
+		{Principals: []string{writerARN}, Operations: []types.GrantOperation{types.GrantOperationEncrypt, types.GrantOperationDecrypt}},
+		{Principals: []string{readerARN}, Operations: []types.GrantOperation{types.GrantOperationDecrypt}},
+	}}); err != nil {
+		t.Fatalf("f.SetKeyPolicy() err = %v, want nil", err)
+	}
+	writer := f.AsPrincipal(writerARN)
+	reader := f.AsPrincipal(readerARN)
+
+	ciphertext, err := encryptWith(writer, validKeyID, nil)
+	if err != nil {
+		t.Fatalf("encryptWith(writer) err = %v, want nil", err)
+	}
+	if _, err := encryptWith(reader, validKeyID, nil); !isAccessDenied(err) {
+		t.Errorf("encryptWith(reader) err = %v, want AccessDeniedException", err)
+	}
+	_, err = encryptWith(f, validKeyID, nil)
+	if !isAccessDenied(err) {
+		t.Errorf("encryptWith() without principal err = %v, want AccessDeniedException", err)
+	}
+	if _, err := reader.Decrypt(t.Context(), &kms.DecryptInput{CiphertextBlob: ciphertext}); err != nil {
+		t.Errorf("reader.Decrypt() err = %v, want nil", err)
+	}
+
+	// Keys added later are shared.
+	if err := f.AddKey(validKeyID2, types.KeySpecSymmetricDefault, types.KeyUsageTypeEncryptDecrypt); err != nil {
+		t.Fatalf("f.AddKey() err = %v, want nil", err)
+	}
+	if _, err := encryptWith(reader, validKeyID2, nil); err != nil {
+		t.Errorf("encryptWith(reader) with key without policy err = %v, want nil", err)
+	}
+}
+
+func TestSetKeyPolicy_withGrant(t *testing.T) {
+	f, err := New([]string{validKeyID})
+	if err != nil {
+		t.Fatalf("New() err = %v, want nil", err)
+	}
+	if err := f.SetKeyPolicy(validKeyID, Policy{Statements: []Statement{
+		{Principals: []string{writerARN}, Operations: []types.GrantOperation{types.GrantOperationEncrypt}},
+	}}); err != nil {
+		t.Fatalf("f.SetKeyPolicy() err = %v, want nil", err)
+	}
+	token, err := f.AddGrant(validKeyID, types.GrantOperationEncrypt)
+	if err != nil {
+		t.Fatalf("f.AddGrant() err = %v, want nil", err)
+	}
+	reader := f.AsPrincipal(readerARN)
+	if _, err := reader.Encrypt(t.Context(), &kms.EncryptInput{
+		KeyId:       aws.String(validKeyID),
+		Plaintext:   []byte("plaintext"),
+		GrantTokens: []string{token},
+	}); err != nil {
+		t.Errorf("reader.Encrypt() with grant token err = %v, want nil", err)
+	}
+	if _, err := encryptWith(f.AsPrincipal(writerARN), validKeyID, nil); err != nil {
+		t.Errorf("encryptWith(writer) without grant token err = %v, want nil", err)
+	}
+}
+
+func TestSetKeyPolicy_reEncrypt(t *testing.T) {
+	f, err := New([]string{validKeyID})
+	if err != nil {
+		t.Fatalf("New() err = %v, want nil", err)
+	}
+	if err := f.AddKey(validKeyID2, types.KeySpecSymmetricDefault, types.KeyUsageTypeEncryptDecrypt); err != nil {
+		t.Fatalf("f.AddKey() err = %v, want nil", err)
+	}
+	sourceContext := map[string]string{"tenant": "a"}
+	ciphertext, err := encryptWith(f, validKeyID, sourceContext)
+	if err != nil {
+		t.Fatalf("encryptWith() err = %v, want nil", err)
+	}
+	if err := f.SetKeyPolicy(validKeyID, Policy{Statements: []Statement{
+		{Operations: []types.GrantOperation{types.GrantOperationReEncryptFrom}, EncryptionContext: sourceContext},
+	}}); err != nil {
+		t.Fatalf("f.SetKeyPolicy() err = %v, want nil", err)
+	}
+	if err := f.SetKeyPolicy(validKeyID2, Policy{Statements: []Statement{
+		{Operations: []types.GrantOperation{types.GrantOperationReEncryptTo}, EncryptionContextKeys: []string{"tenant"}},
+	}}); err != nil {
+		t.Fatalf("f.SetKeyPolicy() err = %v, want nil", err)
+	}
+
+	reEncrypt := func(destinationContext map[string]string) error {
+		_, err := f.ReEncrypt(t.Context(), &kms.ReEncryptInput{
+			CiphertextBlob:               ciphertext,
+			SourceEncryptionContext:      sourceContext,
+			DestinationKeyId:             aws.String(validKeyID2),
+			DestinationEncryptionContext: destinationContext,
+		})
+		return err
+	}
+	if err := reEncrypt(map[string]string{"tenant": "b"}); err != nil {
+		t.Errorf("f.ReEncrypt() err = %v, want nil", err)
+	}
+	if err := reEncrypt(nil); !isAccessDenied(err) {
+		t.Errorf("f.ReEncrypt() without destination context err = %v, want AccessDeniedException", err)
+	}
+}
+
+func TestSetKeyPolicy_copiesPolicy(t *testing.T) {
+	f, err := New([]string{validKeyID})
+	if err != nil {
+		t.Fatalf("New() err = %v, want nil", err)
+	}
+	encryptionContext := map[string]string{"tenant": "a"}
+	if err := f.SetKeyPolicy(validKeyID, Policy{Statements: []Statement{
+		{Operations: []types.GrantOperation{types.GrantOperationEncrypt}, EncryptionContext: encryptionContext},
+	}}); err != nil {
+		t.Fatalf("f.SetKeyPolicy() err = %v, want nil", err)
+	}
+	encryptionContext["tenant"] = "b"
+	if _, err := encryptWith(f, validKeyID, map[string]string{"tenant": "a"}); err != nil {
+		t.Errorf("encryptWith() err = %v, want nil", err)
+	}
+}
+
+func TestSetKeyPolicy_fails(t *testing.T) {
+	f, err := New([]string{validKeyID})
+	if err != nil {
+		t.Fatalf("New() err = %v, want nil", err)
+	}
+	if err := f.SetKeyPolicy(validKeyID2, Policy{}); err == nil {
+		t.Error("f.SetKeyPolicy() with unknown key err = nil, want error")
+	}
+	if err := f.SetKeyPolicy(validKeyID, Policy{Statements: []Statement{{Principals: []string{writerARN}}}}); err == nil {
+		t.Error("f.SetKeyPolicy() with statement without operations err = nil, want error")
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakeawskms
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	mathrand "math/rand/v2"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+// AddCustomKeyStore adds a custom key store, which can be used as
+// CustomKeyStoreId of GenerateRandom requests.
+func (f *FakeAWSKMS) AddCustomKeyStore(id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.customKeyStores[id] {
+		return fmt.Errorf("custom key store %q already exists", id)
+	}
+	f.customKeyStores[id] = true
+	return nil
+}
+
+// SetRandomSeed makes GenerateRandom return a deterministic sequence of
+// bytes derived from seed, so that tests using it are reproducible. These
+// bytes are not secure.
+func (f *FakeAWSKMS) SetRandomSeed(seed uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var key [32]byte
+	binary.BigEndian.PutUint64(key[:], seed)
+	f.random = mathrand.NewChaCha8(key)
+}
+
+// GenerateRandom returns random bytes, from the seeded source if SetRandomSeed
+// was called.
+func (f *FakeAWSKMS) GenerateRandom(ctx context.Context, params *kms.GenerateRandomInput, optFns ...func(*kms.Options)) (*kms.GenerateRandomOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	size := aws.ToInt32(params.NumberOfBytes)
+	if size < 1 || size > 1024 {
+		return nil, validationError("NumberOfBytes must be between 1 and 1024, but got %d", size)
+	}
+	if params.Recipient != nil {
+		return nil, validationError("Recipient is not supported")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if id := aws.ToString(params.CustomKeyStoreId); id != "" && !f.customKeyStores[id] {
+		return nil, &types.CustomKeyStoreNotFoundException{Message: aws.String(fmt.Sprintf("custom key store %q not found", id))}
+	}
+	plaintext := make([]byte, size)
+	random := f.random
+	if random == nil {
+		random = rand.Reader
+	}
+	if _, err := random.Read(plaintext); err != nil {
+		return nil, err
+	}
+	return &kms.GenerateRandomOutput{Plaintext: plaintext}, nil
+}
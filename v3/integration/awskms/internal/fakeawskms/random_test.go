@@ -0,0 +1,100 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakeawskms
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+func generateRandom(t *testing.T, f *FakeAWSKMS, size int32) []byte {
+	t.Helper()
+	resp, err := f.GenerateRandom(t.Context(), &kms.GenerateRandomInput{NumberOfBytes: aws.Int32(size)})
+	if err != nil {
+		t.Fatalf("f.GenerateRandom() err = %v, want nil", err)
+	}
+	if len(resp.Plaintext) != int(size) {
+		t.Fatalf("len(resp.Plaintext) = %d, want %d", len(resp.Plaintext), size)
+	}
+	return resp.Plaintext
+}
+
+func TestGenerateRandom(t *testing.T) {
+	f, err := New(nil)
+	if err != nil {
+		t.Fatalf("New() err = %v, want nil", err)
+	}
+	if bytes.Equal(generateRandom(t, f, 32), generateRandom(t, f, 32)) {
+		t.Error("f.GenerateRandom() returned the same bytes twice")
+	}
+	generateRandom(t, f, 1024)
+
+	for _, size := range []int32{0, -1, 1025} {
+		if _, err := f.GenerateRandom(t.Context(), &kms.GenerateRandomInput{NumberOfBytes: aws.Int32(size)}); err == nil {
+			t.Errorf("f.GenerateRandom(%d) err = nil, want error", size)
+		}
+	}
+}
+
+func TestGenerateRandom_seeded(t *testing.T) {
+	f1, err := New(nil)
+	if err != nil {
+		t.Fatalf("New() err = %v, want nil", err)
+	}
+	f2, err := New(nil)
+	if err != nil {
+		t.Fatalf("New() err = %v, want nil", err)
+	}
+	f1.SetRandomSeed(42)
+	f2.SetRandomSeed(42)
+	first := generateRandom(t, f1, 100)
+	if got := generateRandom(t, f2, 100); !bytes.Equal(got, first) {
+		t.Errorf("f2.GenerateRandom() = %x, want %x", got, first)
+	}
+	if got := generateRandom(t, f1, 100); bytes.Equal(got, first) {
+		t.Error("f1.GenerateRandom() returned the same bytes twice")
+	}
+	f2.SetRandomSeed(43)
+	if got := generateRandom(t, f2, 100); bytes.Equal(got, first) {
+		t.Error("f.GenerateRandom() with another seed returned the same bytes")
+	}
+}
+
+func TestGenerateRandom_customKeyStore(t *testing.T) {
+	f, err := New(nil)
+	if err != nil {
+		t.Fatalf("New() err = %v, want nil", err)
+	}
+	const id = "cks-1234567890abcdef0"
+	req := &kms.GenerateRandomInput{NumberOfBytes: aws.Int32(16), CustomKeyStoreId: aws.String(id)}
+	var notFound *types.CustomKeyStoreNotFoundException
+	if _, err := f.GenerateRandom(t.Context(), req); !errors.As(err, &notFound) {
+		t.Errorf("f.GenerateRandom() with unknown custom key store err = %v, want CustomKeyStoreNotFoundException", err)
+	}
+	if err := f.AddCustomKeyStore(id); err != nil {
+		t.Fatalf("f.AddCustomKeyStore() err = %v, want nil", err)
+	}
+	if err := f.AddCustomKeyStore(id); err == nil {
+		t.Error("f.AddCustomKeyStore() of existing custom key store err = nil, want error")
+	}
+	if _, err := f.GenerateRandom(t.Context(), req); err != nil {
+		t.Errorf("f.GenerateRandom() with custom key store err = %v, want nil", err)
+	}
+}
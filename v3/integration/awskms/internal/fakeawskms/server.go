@@ -0,0 +1,165 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakeawskms
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/smithy-go"
+)
+
+const targetPrefix = "TrentService."
+
+// Handler serves the AWS KMS JSON protocol backed by a FakeAWSKMS, so that it
+// can be used as the endpoint of a real AWS KMS client. Request signatures
+// are not verified.
+type Handler struct {
+	kms *FakeAWSKMS
+}
+
+// NewHandler returns a Handler serving f. Requests are made by the principal
+// of f, see AsPrincipal.
+func NewHandler(f *FakeAWSKMS) *Handler {
+	return &Handler{kms: f}
+}
+
+// operation calls the method of the fake KMS for a decoded request.
+type operation func(h *Handler, r *http.Request, decode func(any) error) (any, error)
+
+func handle[In, Out any](call func(f *FakeAWSKMS, r *http.Request, in *In) (*Out, error)) operation {
+	return func(h *Handler, r *http.Request, decode func(any) error) (any, error) {
+		in := new(In)
+		if err := decode(in); err != nil {
+			return nil, &smithy.GenericAPIError{Code: "SerializationException", Message: err.Error()}
+		}
+		return call(h.kms, r, in)
+	}
+}
+
+var operations = map[string]operation{
+	"Encrypt": handle(func(f *FakeAWSKMS, r *http.Request, in *kms.EncryptInput) (*kms.EncryptOutput, error) {
+		return f.Encrypt(r.Context(), in)
+	}),
+	"Decrypt": handle(func(f *FakeAWSKMS, r *http.Request, in *kms.DecryptInput) (*kms.DecryptOutput, error) {
+		return f.Decrypt(r.Context(), in)
+	}),
+	"ReEncrypt": handle(func(f *FakeAWSKMS, r *http.Request, in *kms.ReEncryptInput) (*kms.ReEncryptOutput, error) {
+		return f.ReEncrypt(r.Context(), in)
+	}),
+	"GenerateDataKey": handle(func(f *FakeAWSKMS, r *http.Request, in *kms.GenerateDataKeyInput) (*kms.GenerateDataKeyOutput, error) {
+		return f.GenerateDataKey(r.Context(), in)
+	}),
+	"GenerateDataKeyPair": handle(func(f *FakeAWSKMS, r *http.Request, in *kms.GenerateDataKeyPairInput) (*kms.GenerateDataKeyPairOutput, error) {
+		return f.GenerateDataKeyPair(r.Context(), in)
+	}),
+	"GenerateDataKeyPairWithoutPlaintext": handle(func(f *FakeAWSKMS, r *http.Request, in *kms.GenerateDataKeyPairWithoutPlaintextInput) (*kms.GenerateDataKeyPairWithoutPlaintextOutput, error) {
+		return f.GenerateDataKeyPairWithoutPlaintext(r.Context(), in)
+	}),
+	"GenerateRandom": handle(func(f *FakeAWSKMS, r *http.Request, in *kms.GenerateRandomInput) (*kms.GenerateRandomOutput, error) {
+		return f.GenerateRandom(r.Context(), in)
+	}),
+	"DescribeKey": handle(func(f *FakeAWSKMS, r *http.Request, in *kms.DescribeKeyInput) (*kms.DescribeKeyOutput, error) {
+		return f.DescribeKey(r.Context(), in)
+	}),
+	"CreateKey": handle(func(f *FakeAWSKMS, r *http.Request, in *kms.CreateKeyInput) (*kms.CreateKeyOutput, error) {
+		return f.CreateKey(r.Context(), in)
+	}),
+	"EnableKey": handle(func(f *FakeAWSKMS, r *http.Request, in *kms.EnableKeyInput) (*kms.EnableKeyOutput, error) {
+		return f.EnableKey(r.Context(), in)
+	}),
+	"DisableKey": handle(func(f *FakeAWSKMS, r *http.Request, in *kms.DisableKeyInput) (*kms.DisableKeyOutput, error) {
+		return f.DisableKey(r.Context(), in)
+	}),
+	"ScheduleKeyDeletion": handle(func(f *FakeAWSKMS, r *http.Request, in *kms.ScheduleKeyDeletionInput) (*kms.ScheduleKeyDeletionOutput, error) {
+		return f.ScheduleKeyDeletion(r.Context(), in)
+	}),
+	"RotateKeyOnDemand": handle(func(f *FakeAWSKMS, r *http.Request, in *kms.RotateKeyOnDemandInput) (*kms.RotateKeyOnDemandOutput, error) {
+		return f.RotateKeyOnDemand(r.Context(), in)
+	}),
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	target := r.Header.Get("X-Amz-Target")
+	op, ok := operations[strings.TrimPrefix(target, targetPrefix)]
+	if r.Method != http.MethodPost || !strings.HasPrefix(target, targetPrefix) || !ok {
+		writeError(w, &smithy.GenericAPIError{Code: "UnknownOperationException", Message: "unsupported operation " + target})
+		return
+	}
+	decode := func(v any) error { return json.NewDecoder(r.Body).Decode(v) }
+	out, err := op(h, r, decode)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	json.NewEncoder(w).Encode(jsonValue(reflect.ValueOf(out)))
+}
+
+// jsonValue converts v into a value which encoding/json encodes like the AWS
+// JSON protocol, in which timestamps are seconds since the epoch.
+func jsonValue(v reflect.Value) any {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return jsonValue(v.Elem())
+	case reflect.Struct:
+		if t, ok := v.Interface().(time.Time); ok {
+			return float64(t.UnixMilli()) / 1000
+		}
+		m := make(map[string]any)
+		for i := range v.NumField() {
+			if f := v.Type().Field(i); f.IsExported() {
+				m[f.Name] = jsonValue(v.Field(i))
+			}
+		}
+		return m
+	case reflect.Slice:
+		if v.IsNil() || v.Type().Elem().Kind() != reflect.Struct {
+			return v.Interface()
+		}
+		l := make([]any, v.Len())
+		for i := range l {
+			l[i] = jsonValue(v.Index(i))
+		}
+		return l
+	}
+	return v.Interface()
+}
+
+// writeError writes err in the format of AWS KMS errors. Errors which are not
+// API errors are reported as validation errors.
+func writeError(w http.ResponseWriter, err error) {
+	code := "ValidationException"
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		code = apiErr.ErrorCode()
+	}
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	w.Header().Set("X-Amzn-ErrorType", code)
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{
+		"__type":  code,
+		"message": err.Error(),
+	})
+}
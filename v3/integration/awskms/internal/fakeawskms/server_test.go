@@ -0,0 +1,197 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakeawskms
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+func newServedClient(t *testing.T, f *FakeAWSKMS) *kms.Client {
+	t.Helper()
+	server := httptest.NewServer(NewHandler(f))
+	t.Cleanup(server.Close)
+	return kms.New(kms.Options{
+		Region:       "us-west-2",
+		BaseEndpoint: aws.String(server.URL),
+		Credentials:  aws.AnonymousCredentials{},
+	})
+}
+
+func TestHandler(t *testing.T) {
+	fakeKMS, err := New([]string{validKeyID, validKeyID2})
+	if err != nil {
+		t.Fatalf("New() err = %s, want nil", err)
+	}
+	client := newServedClient(t, fakeKMS)
+	plaintext := []byte("plaintext")
+	encryptionContext := map[string]string{"contextName": "contextValue"}
+
+	encResponse, err := client.Encrypt(t.Context(), &kms.EncryptInput{
+		KeyId:             aws.String(validKeyID),
+		Plaintext:         plaintext,
+		EncryptionContext: encryptionContext,
+	})
+	if err != nil {
+		t.Fatalf("client.Encrypt() err = %v, want nil", err)
+	}
+	if got := aws.ToString(encResponse.KeyId); got != validKeyID {
+		t.Errorf("encResponse.KeyId = %q, want %q", got, validKeyID)
+	}
+	reEncResponse, err := client.ReEncrypt(t.Context(), &kms.ReEncryptInput{
+		CiphertextBlob:               encResponse.CiphertextBlob,
+		SourceEncryptionContext:      encryptionContext,
+		DestinationKeyId:             aws.String(validKeyID2),
+		DestinationEncryptionContext: encryptionContext,
+	})
+	if err != nil {
+		t.Fatalf("client.ReEncrypt() err = %v, want nil", err)
+	}
+	decResponse, err := client.Decrypt(t.Context(), &kms.DecryptInput{
+		KeyId:             aws.String(validKeyID2),
+		CiphertextBlob:    reEncResponse.CiphertextBlob,
+		EncryptionContext: encryptionContext,
+	})
+	if err != nil {
+		t.Fatalf("client.Decrypt() err = %v, want nil", err)
+	}
+	if !bytes.Equal(decResponse.Plaintext, plaintext) {
+		t.Errorf("decResponse.Plaintext = %q, want %q", decResponse.Plaintext, plaintext)
+	}
+
+	describeResponse, err := client.DescribeKey(t.Context(), &kms.DescribeKeyInput{KeyId: aws.String(validKeyID)})
+	if err != nil {
+		t.Fatalf("client.DescribeKey() err = %v, want nil", err)
+	}
+	if got := aws.ToString(describeResponse.KeyMetadata.Arn); got != validKeyID {
+		t.Errorf("describeResponse.KeyMetadata.Arn = %q, want %q", got, validKeyID)
+	}
+
+	_, err = client.Decrypt(t.Context(), &kms.DecryptInput{
+		KeyId:          aws.String(validKeyID),
+		CiphertextBlob: encResponse.CiphertextBlob,
+	})
+	var invalidCiphertext *types.InvalidCiphertextException
+	if !errors.As(err, &invalidCiphertext) {
+		t.Errorf("client.Decrypt() with wrong context err = %v, want InvalidCiphertextException", err)
+	}
+}
+
+func TestHandlerConcurrentRequests(t *testing.T) {
+	fakeKMS, err := New([]string{validKeyID})
+	if err != nil {
+		t.Fatalf("New() err = %s, want nil", err)
+	}
+	client := newServedClient(t, fakeKMS)
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Go(func() {
+			if _, err := client.Encrypt(t.Context(), &kms.EncryptInput{
+				KeyId:     aws.String(validKeyID),
+				Plaintext: []byte("plaintext"),
+			}); err != nil {
+				t.Errorf("client.Encrypt() err = %v, want nil", err)
+			}
+		})
+		// Keys and aliases may be added while requests are served.
+		wg.Go(func() {
+			keyID := fmt.Sprintf("%s-%d", validKeyID2, i)
+			if err := fakeKMS.AddKey(keyID, types.KeySpecSymmetricDefault, types.KeyUsageTypeEncryptDecrypt); err != nil {
+				t.Errorf("fakeKMS.AddKey() err = %v, want nil", err)
+			}
+			if err := fakeKMS.SetAlias(fmt.Sprintf("alias/key-%d", i), keyID); err != nil {
+				t.Errorf("fakeKMS.SetAlias() err = %v, want nil", err)
+			}
+		})
+	}
+	wg.Wait()
+}
+
+func TestHandlerConcurrentKeyLifecycle(t *testing.T) {
+	fakeKMS, err := New([]string{validKeyID})
+	if err != nil {
+		t.Fatalf("New() err = %s, want nil", err)
+	}
+	client := newServedClient(t, fakeKMS)
+	keyID := aws.String(validKeyID)
+	policy := Policy{Statements: []Statement{{Operations: []types.GrantOperation{
+		types.GrantOperationEncrypt, operationEnableKey, operationDisableKey, operationRotateKeyOnDemand,
+	}}}}
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Go(func() {
+			_, err := client.Encrypt(t.Context(), &kms.EncryptInput{KeyId: keyID, Plaintext: []byte("plaintext")})
+			if err != nil && !isDisabled(err) {
+				t.Errorf("client.Encrypt() err = %v, want nil or DisabledException", err)
+			}
+		})
+		wg.Go(func() {
+			if _, err := client.DisableKey(t.Context(), &kms.DisableKeyInput{KeyId: keyID}); err != nil {
+				t.Errorf("client.DisableKey() err = %v, want nil", err)
+			}
+			if _, err := client.EnableKey(t.Context(), &kms.EnableKeyInput{KeyId: keyID}); err != nil {
+				t.Errorf("client.EnableKey() err = %v, want nil", err)
+			}
+		})
+		wg.Go(func() {
+			_, err := client.RotateKeyOnDemand(t.Context(), &kms.RotateKeyOnDemandInput{KeyId: keyID})
+			if err != nil && !isDisabled(err) {
+				t.Errorf("client.RotateKeyOnDemand() err = %v, want nil or DisabledException", err)
+			}
+		})
+		wg.Go(func() {
+			if err := fakeKMS.SetKeyPolicy(validKeyID, policy); err != nil {
+				t.Errorf("fakeKMS.SetKeyPolicy() err = %v, want nil", err)
+			}
+		})
+	}
+	wg.Wait()
+}
+
+func TestHandlerUnsupportedOperationFails(t *testing.T) {
+	fakeKMS, err := New([]string{validKeyID})
+	if err != nil {
+		t.Fatalf("New() err = %s, want nil", err)
+	}
+	client := newServedClient(t, fakeKMS)
+	if _, err := client.ListKeys(t.Context(), &kms.ListKeysInput{}); err == nil {
+		t.Error("client.ListKeys() err = nil, want error")
+	}
+}
+
+func TestHandlerDryRun(t *testing.T) {
+	f, err := New([]string{validKeyID})
+	if err != nil {
+		t.Fatalf("New() err = %v, want nil", err)
+	}
+	client := newServedClient(t, f)
+	_, err = client.Encrypt(t.Context(), &kms.EncryptInput{
+		KeyId:     aws.String(validKeyID),
+		Plaintext: []byte("plaintext"),
+		DryRun:    aws.Bool(true),
+	})
+	var dryRun *types.DryRunOperationException
+	if !errors.As(err, &dryRun) {
+		t.Errorf("client.Encrypt() with DryRun err = %v, want DryRunOperationException", err)
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// ErrKeyIDMismatch is returned by AEAD operations if AWS KMS reports that the
+// operation was performed by a different key than the configured one.
+//
+// AEAD primitives check that the key which performed an Encrypt or Decrypt
+// operation, as reported in the response, is the key of the key URI, and fail
+// with ErrKeyIDMismatch otherwise, including if the response contains no key
+// ID. Key URIs referring to an alias are only verified against a key ARN
+// pinned with [WithPinnedKeyARN], which detects aliases which were retargeted
+// to an unexpected key. See [WithoutKeyIDVerification] to disable the checks.
+var ErrKeyIDMismatch = errors.New("AWS KMS used a different key than requested")
+
+var keyARNPattern = regexp.MustCompile(`^arn:aws[a-zA-Z0-9-_]*:kms:[a-z0-9-]+:[0-9]+:key/`)
+
+// WithoutKeyIDVerification disables the verification of the KeyId returned
+// by AWS KMS, see [ErrKeyIDMismatch]. It is meant for AWS KMS clients which
+// do not report key IDs, such as emulators or mocks. It cannot be combined
+// with [WithPinnedKeyARN].
+func WithoutKeyIDVerification() ClientOption {
+	return option(func(ctx context.Context, a *awsClient) error {
+		if a.skipKeyIDVerification {
+			return errors.New("WithoutKeyIDVerification option cannot be used, verification already disabled")
+		}
+		if len(a.pinnedKeyARNs) > 0 {
+			return errors.New("WithoutKeyIDVerification option cannot be used, key ARNs are pinned")
+		}
+		a.skipKeyIDVerification = true
+		return nil
+	})
+}
+
+// WithPinnedKeyARN sets the key ARN that AWS KMS must report for operations
+// with keyURI, which usually refers to an alias. When the alias is retargeted
+// to another key, operations fail with [ErrKeyIDMismatch] until the pinned key
+// ARN is updated.
+func WithPinnedKeyARN(keyURI, keyARN string) ClientOption {
+	return option(func(ctx context.Context, a *awsClient) error {
+		if a.skipKeyIDVerification {
+			return errors.New("WithPinnedKeyARN option cannot be used, verification disabled")
+		}
+		if !strings.HasPrefix(strings.ToLower(keyURI), awsPrefix) {
+			return fmt.Errorf("keyURI must start with %q, but got %q", awsPrefix, keyURI)
+		}
+		if !keyARNPattern.MatchString(keyARN) {
+			return fmt.Errorf("invalid key ARN %q", keyARN)
+		}
+		keyID := keyURI[len(awsPrefix):]
+		if _, ok := a.pinnedKeyARNs[keyID]; ok {
+			return fmt.Errorf("WithPinnedKeyARN option cannot be used, key ARN of %q already set", keyURI)
+		}
+		if a.pinnedKeyARNs == nil {
+			a.pinnedKeyARNs = make(map[string]string)
+		}
+		a.pinnedKeyARNs[keyID] = keyARN
+		return nil
+	})
+}
+
+// isAlias reports whether keyID refers to an alias, by name or ARN.
+func isAlias(keyID string) bool {
+	return strings.HasPrefix(keyID, "alias/") || (strings.HasPrefix(keyID, "arn:") && strings.Contains(keyID, ":alias/"))
+}
+
+// verifyKeyID checks that usedKeyID, the KeyId of an AWS KMS response, is the
+// key of a, and returns it.
+func (a *awsAEAD) verifyKeyID(ctx context.Context, usedKeyID *string) (string, error) {
+	used := aws.ToString(usedKeyID)
+	// Aliases can be retargeted, so they are only verified if pinned.
+	if !a.verifyKeyIDs || (a.pinnedKeyARN == "" && isAlias(a.keyID)) {
+		return used, nil
+	}
+	if used == "" {
+		return "", fmt.Errorf("%w: response contains no key ID", ErrKeyIDMismatch)
+	}
+	var want string
+	switch {
+	case a.pinnedKeyARN != "":
+		want = a.pinnedKeyARN
+	case keyARNPattern.MatchString(a.keyID):
+		want = a.keyID
+	default:
+		// A key ID, which is the last component of the key ARN.
+		if used == a.keyID || strings.HasSuffix(used, ":key/"+a.keyID) {
+			return used, nil
+		}
+		want = a.keyID
+	}
+	if used != want {
+		return "", fmt.Errorf("%w: requested %s, used %s", ErrKeyIDMismatch, want, used)
+	}
+	return used, nil
+}
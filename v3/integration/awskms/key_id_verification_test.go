@@ -0,0 +1,286 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/tink-crypto/tink-go-awskms/v3/integration/awskms/internal/fakeawskms"
+	"github.com/tink-crypto/tink-go/v2/tink"
+)
+
+const aliasARN = "arn:aws:kms:us-east-2:235739564943:alias/tink-test"
+
+// keyIDOverrideKMS reports keyID as the key of all Encrypt and Decrypt
+// responses.
+type keyIDOverrideKMS struct {
+	KMSAPI
+	keyID *string
+}
+
+func (k keyIDOverrideKMS) Encrypt(ctx context.Context, params *kms.EncryptInput, optFns ...func(*kms.Options)) (*kms.EncryptOutput, error) {
+	resp, err := k.KMSAPI.Encrypt(ctx, params, optFns...)
+	if err != nil {
+		return nil, err
+	}
+	resp.KeyId = k.keyID
+	return resp, nil
+}
+
+func (k keyIDOverrideKMS) Decrypt(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error) {
+	resp, err := k.KMSAPI.Decrypt(ctx, params, optFns...)
+	if err != nil {
+		return nil, err
+	}
+	resp.KeyId = k.keyID
+	return resp, nil
+}
+
+func TestKeyIDVerification_alias(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN, destinationKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	if err := fakekms.SetAlias(aliasARN, sourceKeyARN); err != nil {
+		t.Fatalf("fakekms.SetAlias() failed: %v", err)
+	}
+	client := newTestClient(t, WithKMS(fakekms), WithPinnedKeyARN("aws-kms://"+aliasARN, sourceKeyARN))
+	a, err := client.GetAEAD("aws-kms://" + aliasARN)
+	if err != nil {
+		t.Fatalf("client.GetAEAD() err = %v, want nil", err)
+	}
+	aeadWithContext := a.(tink.AEADWithContext)
+	unpinned := getAEAD(t, newTestClient(t, WithKMS(fakekms)), "aws-kms://"+aliasARN)
+
+	plaintext := []byte("plaintext")
+	associatedData := []byte("associatedData")
+	details := &OperationDetails{}
+	ciphertext, err := aeadWithContext.EncryptWithContext(ContextWithOperationDetails(t.Context(), details), plaintext, associatedData)
+	if err != nil {
+		t.Fatalf("a.EncryptWithContext() err = %v, want nil", err)
+	}
+	if details.KeyARN != sourceKeyARN {
+		t.Errorf("details.KeyARN = %q, want %q", details.KeyARN, sourceKeyARN)
+	}
+	details = &OperationDetails{}
+	if _, err := aeadWithContext.DecryptWithContext(ContextWithOperationDetails(t.Context(), details), ciphertext, associatedData); err != nil {
+		t.Fatalf("a.DecryptWithContext() err = %v, want nil", err)
+	}
+	if details.KeyARN != sourceKeyARN {
+		t.Errorf("details.KeyARN = %q, want %q", details.KeyARN, sourceKeyARN)
+	}
+
+	// Retargeting the alias is only detected if its key ARN is pinned.
+	if err := fakekms.SetAlias(aliasARN, destinationKeyARN); err != nil {
+		t.Fatalf("fakekms.SetAlias() failed: %v", err)
+	}
+	if _, err := a.Encrypt(plaintext, associatedData); !errors.Is(err, ErrKeyIDMismatch) {
+		t.Errorf("a.Encrypt() after retargeting the alias err = %v, want ErrKeyIDMismatch", err)
+	}
+	details = &OperationDetails{}
+	if _, err := unpinned.EncryptWithContext(ContextWithOperationDetails(t.Context(), details), plaintext, associatedData); err != nil {
+		t.Errorf("unpinned.EncryptWithContext() after retargeting the alias err = %v, want nil", err)
+	}
+	if details.KeyARN != destinationKeyARN {
+		t.Errorf("details.KeyARN = %q, want %q", details.KeyARN, destinationKeyARN)
+	}
+}
+
+func TestKeyIDVerification_aliasWithoutPinnedKeyARNIsNotVerified(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	if err := fakekms.SetAlias(aliasARN, sourceKeyARN); err != nil {
+		t.Fatalf("fakekms.SetAlias() failed: %v", err)
+	}
+	// The pinned key ARN applies to its key URI only.
+	client := newTestClient(t, WithKMS(keyIDOverrideKMS{fakekms, aws.String(destinationKeyARN)}), WithPinnedKeyARN("aws-kms://alias/other", sourceKeyARN))
+	a, err := client.GetAEAD("aws-kms://" + aliasARN)
+	if err != nil {
+		t.Fatalf("client.GetAEAD() err = %v, want nil", err)
+	}
+	if _, err := a.Encrypt([]byte("plaintext"), nil); err != nil {
+		t.Errorf("a.Encrypt() err = %v, want nil", err)
+	}
+}
+
+func TestKeyIDVerification_mismatchFails(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	plaintext := []byte("plaintext")
+	associatedData := []byte("associatedData")
+	ciphertext, err := getAEAD(t, newTestClient(t, WithKMS(fakekms)), sourceKeyURI).EncryptWithContext(t.Context(), plaintext, associatedData)
+	if err != nil {
+		t.Fatalf("a.EncryptWithContext() err = %v, want nil", err)
+	}
+
+	for _, test := range []struct {
+		name  string
+		keyID *string
+	}{
+		{"other key", aws.String(destinationKeyARN)},
+		{"other key ID", aws.String("arn:aws:kms:us-east-2:235739564943:key/00000000-5a82-4f5b-9753-05c4f473922f")},
+		{"missing key ID", nil},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			a := getAEAD(t, newTestClient(t, WithKMS(keyIDOverrideKMS{fakekms, test.keyID})), sourceKeyURI)
+			if _, err := a.EncryptWithContext(t.Context(), plaintext, associatedData); !errors.Is(err, ErrKeyIDMismatch) {
+				t.Errorf("a.EncryptWithContext() err = %v, want ErrKeyIDMismatch", err)
+			}
+			if _, err := a.DecryptWithContext(t.Context(), ciphertext, associatedData); !errors.Is(err, ErrKeyIDMismatch) {
+				t.Errorf("a.DecryptWithContext() err = %v, want ErrKeyIDMismatch", err)
+			}
+
+			unverified := getAEAD(t, newTestClient(t, WithKMS(keyIDOverrideKMS{fakekms, test.keyID}), WithoutKeyIDVerification()), sourceKeyURI)
+			if _, err := unverified.EncryptWithContext(t.Context(), plaintext, associatedData); err != nil {
+				t.Errorf("unverified.EncryptWithContext() err = %v, want nil", err)
+			}
+			if _, err := unverified.DecryptWithContext(t.Context(), ciphertext, associatedData); err != nil {
+				t.Errorf("unverified.DecryptWithContext() err = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestKeyIDVerification_keyID(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	// AWS KMS reports the key ARN of keys referred to by their key ID, like for
+	// aliases.
+	const keyID = "3ee50705-5a82-4f5b-9753-05c4f473922f"
+	if err := fakekms.SetAlias(keyID, sourceKeyARN); err != nil {
+		t.Fatalf("fakekms.SetAlias() failed: %v", err)
+	}
+	client := newTestClient(t, WithKMS(fakekms))
+	a, err := client.GetAEAD("aws-kms://" + keyID)
+	if err != nil {
+		t.Fatalf("client.GetAEAD() err = %v, want nil", err)
+	}
+	if _, err := a.Encrypt([]byte("plaintext"), nil); err != nil {
+		t.Errorf("a.Encrypt() err = %v, want nil", err)
+	}
+}
+
+func TestKeyIDVerification_invalidOptionsFail(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	for _, test := range []struct {
+		name string
+		opts []ClientOption
+	}{
+		{"verification disabled twice", []ClientOption{WithoutKeyIDVerification(), WithoutKeyIDVerification()}},
+		{"pinned key ARN without verification", []ClientOption{WithoutKeyIDVerification(), WithPinnedKeyARN("aws-kms://"+aliasARN, sourceKeyARN)}},
+		{"verification disabled with pinned key ARN", []ClientOption{WithPinnedKeyARN("aws-kms://"+aliasARN, sourceKeyARN), WithoutKeyIDVerification()}},
+		{"same key URI pinned twice", []ClientOption{WithPinnedKeyARN("aws-kms://"+aliasARN, sourceKeyARN), WithPinnedKeyARN("aws-kms://"+aliasARN, destinationKeyARN)}},
+		{"invalid key URI", []ClientOption{WithPinnedKeyARN(aliasARN, sourceKeyARN)}},
+		{"invalid key ARN", []ClientOption{WithPinnedKeyARN("aws-kms://"+aliasARN, aliasARN)}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := NewClientWithOptions(t.Context(), "aws-kms://", append([]ClientOption{WithKMS(fakekms)}, test.opts...)...); err == nil {
+				t.Error("NewClientWithOptions() err = nil, want error")
+			}
+		})
+	}
+}
+
+// reEncryptKeyIDOverrideKMS reports sourceKeyID and keyID as the keys of all
+// ReEncrypt responses.
+type reEncryptKeyIDOverrideKMS struct {
+	*fakeawskms.FakeAWSKMS
+	sourceKeyID, keyID *string
+}
+
+func (k reEncryptKeyIDOverrideKMS) ReEncrypt(ctx context.Context, params *kms.ReEncryptInput, optFns ...func(*kms.Options)) (*kms.ReEncryptOutput, error) {
+	resp, err := k.FakeAWSKMS.ReEncrypt(ctx, params, optFns...)
+	if err != nil {
+		return nil, err
+	}
+	resp.SourceKeyId = k.sourceKeyID
+	resp.KeyId = k.keyID
+	return resp, nil
+}
+
+func TestKeyIDVerification_reEncrypt(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN, destinationKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	ciphertext := mustEncrypt(t, newTestClient(t, WithKMS(fakekms)), sourceKeyURI, []byte("plaintext"), nil)
+	for _, test := range []struct {
+		name        string
+		sourceKeyID *string
+		keyID       *string
+		wantErr     bool
+	}{
+		{"matching keys", aws.String(sourceKeyARN), aws.String(destinationKeyARN), false},
+		{"other source key", aws.String(destinationKeyARN), aws.String(destinationKeyARN), true},
+		{"other destination key", aws.String(sourceKeyARN), aws.String(sourceKeyARN), true},
+		{"missing destination key ID", aws.String(sourceKeyARN), nil, true},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			k := reEncryptKeyIDOverrideKMS{fakekms, test.sourceKeyID, test.keyID}
+			client := newTestClient(t, WithKMS(k))
+			_, err := client.ReEncrypt(t.Context(), ciphertext, nil, destinationKeyURI, nil, WithSourceKeyURI(sourceKeyURI))
+			if test.wantErr && !errors.Is(err, ErrKeyIDMismatch) {
+				t.Errorf("client.ReEncrypt() err = %v, want ErrKeyIDMismatch", err)
+			}
+			if !test.wantErr && err != nil {
+				t.Errorf("client.ReEncrypt() err = %v, want nil", err)
+			}
+			unverified := newTestClient(t, WithKMS(k), WithoutKeyIDVerification())
+			if _, err := unverified.ReEncrypt(t.Context(), ciphertext, nil, destinationKeyURI, nil, WithSourceKeyURI(sourceKeyURI)); err != nil {
+				t.Errorf("unverified.ReEncrypt() err = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestKeyIDVerification_reEncryptToAlias(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN, destinationKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	if err := fakekms.SetAlias(aliasARN, destinationKeyARN); err != nil {
+		t.Fatalf("fakekms.SetAlias() failed: %v", err)
+	}
+	ciphertext := mustEncrypt(t, newTestClient(t, WithKMS(fakekms)), sourceKeyURI, []byte("plaintext"), nil)
+
+	unpinned := newTestClient(t, WithKMS(fakekms))
+	if _, err := unpinned.ReEncrypt(t.Context(), ciphertext, nil, "aws-kms://"+aliasARN, nil); err != nil {
+		t.Errorf("unpinned.ReEncrypt() to alias err = %v, want nil", err)
+	}
+
+	client := newTestClient(t, WithKMS(fakekms), WithPinnedKeyARN("aws-kms://"+aliasARN, destinationKeyARN))
+	if _, err := client.ReEncrypt(t.Context(), ciphertext, nil, "aws-kms://"+aliasARN, nil); err != nil {
+		t.Errorf("client.ReEncrypt() err = %v, want nil", err)
+	}
+	if err := fakekms.SetAlias(aliasARN, sourceKeyARN); err != nil {
+		t.Fatalf("fakekms.SetAlias() failed: %v", err)
+	}
+	if _, err := client.ReEncrypt(t.Context(), ciphertext, nil, "aws-kms://"+aliasARN, nil); !errors.Is(err, ErrKeyIDMismatch) {
+		t.Errorf("client.ReEncrypt() after retargeting the alias err = %v, want ErrKeyIDMismatch", err)
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+// keyMetadataCacheName is the name of the key metadata cache reported to
+// [Metrics.RecordCacheLookup].
+const keyMetadataCacheName = "key_metadata"
+
+var (
+	// ErrUnsupportedKeyUsage is wrapped by a [KeyValidationError] if the key
+	// usage is not ENCRYPT_DECRYPT.
+	ErrUnsupportedKeyUsage = errors.New("key usage is not ENCRYPT_DECRYPT")
+	// ErrUnsupportedKeySpec is wrapped by a [KeyValidationError] if the key
+	// spec is not SYMMETRIC_DEFAULT.
+	ErrUnsupportedKeySpec = errors.New("key spec is not SYMMETRIC_DEFAULT")
+)
+
+// KeyValidationError is returned by GetAEAD if key validation is enabled with
+// [WithKeyValidation] or [WithEncryptionAlgorithm] and the key cannot be used
+// as an AEAD.
+type KeyValidationError struct {
+	KeyURI   string
+	KeySpec  types.KeySpec
+	KeyUsage types.KeyUsageType
+	// EncryptionAlgorithm is the algorithm selected with
+	// [WithEncryptionAlgorithm], if any.
+	EncryptionAlgorithm types.EncryptionAlgorithmSpec
+	// Err is ErrUnsupportedKeyUsage, ErrUnsupportedKeySpec or
+	// ErrUnsupportedEncryptionAlgorithm.
+	Err error
+}
+
+func (e *KeyValidationError) Error() string {
+	if e.EncryptionAlgorithm != "" {
+		return fmt.Sprintf("key %s with spec %s and usage %s cannot be used as AEAD with encryption algorithm %s: %v", e.KeyURI, e.KeySpec, e.KeyUsage, e.EncryptionAlgorithm, e.Err)
+	}
+	return fmt.Sprintf("key %s with spec %s and usage %s cannot be used as AEAD: %v", e.KeyURI, e.KeySpec, e.KeyUsage, e.Err)
+}
+
+func (e *KeyValidationError) Unwrap() error { return e.Err }
+
+// WithKeyValidation makes GetAEAD check that the key is a symmetric encryption
+// key, using DescribeKey. Keys of other types, such as asymmetric or HMAC keys,
+// are rejected with a [KeyValidationError].
+//
+// This requires the kms:DescribeKey permission. The spec and usage of keys
+// never change, so they are cached by key ARN for the lifetime of the client.
+// Key URIs referring to aliases are resolved with DescribeKey every time, as
+// aliases can be retargeted.
+func WithKeyValidation() ClientOption {
+	return option(func(ctx context.Context, a *awsClient) error {
+		if a.validateKeys {
+			return errors.New("WithKeyValidation option cannot be used, key validation already enabled")
+		}
+		a.validateKeys = true
+		return nil
+	})
+}
+
+// validateKey checks that keyID can be used as an AEAD, with the encryption
+// algorithm of c if set, and returns its metadata.
+func (c *awsClient) validateKey(ctx context.Context, keyURI, keyID string) (*types.KeyMetadata, error) {
+	m, err := c.keyMetadata(ctx, keyID)
+	if err != nil {
+		return nil, fmt.Errorf("validating key %s failed: %w", keyURI, err)
+	}
+	validationErr := &KeyValidationError{
+		KeyURI:              keyURI,
+		KeySpec:             m.KeySpec,
+		KeyUsage:            m.KeyUsage,
+		EncryptionAlgorithm: c.encryptionAlgorithm,
+	}
+	switch {
+	case m.KeyUsage != types.KeyUsageTypeEncryptDecrypt:
+		validationErr.Err = ErrUnsupportedKeyUsage
+	case c.encryptionAlgorithm != "":
+		if !slices.Contains(m.EncryptionAlgorithms, c.encryptionAlgorithm) {
+			validationErr.Err = ErrUnsupportedEncryptionAlgorithm
+		}
+	case m.KeySpec != types.KeySpecSymmetricDefault:
+		validationErr.Err = ErrUnsupportedKeySpec
+	}
+	if validationErr.Err != nil {
+		return nil, validationErr
+	}
+	return m, nil
+}
+
+// keyMetadata returns the metadata of keyID which never changes over the
+// lifetime of a key, see immutableKeyMetadata, from the cache if possible.
+// Aliases are not cached, since they can be retargeted.
+func (c *awsClient) keyMetadata(ctx context.Context, keyID string) (*types.KeyMetadata, error) {
+	alias := isAlias(keyID)
+	if !alias {
+		c.keyMetadataMu.Lock()
+		m, ok := c.keyMetadataCache[keyID]
+		c.keyMetadataMu.Unlock()
+		if c.metrics != nil {
+			c.metrics.RecordCacheLookup(ctx, keyMetadataCacheName, keyID, ok)
+		}
+		if ok {
+			return m, nil
+		}
+	}
+
+	k, ok := c.kms.(describeKeyAPI)
+	if !ok {
+		return nil, errDescribeKeyUnsupported
+	}
+	resp, err := k.DescribeKey(ctx, &kms.DescribeKeyInput{
+		KeyId:       aws.String(keyID),
+		GrantTokens: grantTokens(ctx, c.grantTokens),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.KeyMetadata == nil {
+		return nil, errors.New("response contains no key metadata")
+	}
+	m := immutableKeyMetadata(resp.KeyMetadata)
+	c.keyMetadataMu.Lock()
+	defer c.keyMetadataMu.Unlock()
+	if c.keyMetadataCache == nil {
+		c.keyMetadataCache = make(map[string]*types.KeyMetadata)
+	}
+	// Key ARNs and key IDs always refer to the same key.
+	if arn := aws.ToString(m.Arn); arn != "" {
+		c.keyMetadataCache[arn] = m
+	}
+	if !alias {
+		c.keyMetadataCache[keyID] = m
+	}
+	return m, nil
+}
+
+// immutableKeyMetadata returns the fields of m which never change over the
+// lifetime of a key. Others, such as the key state, must not be cached.
+func immutableKeyMetadata(m *types.KeyMetadata) *types.KeyMetadata {
+	return &types.KeyMetadata{
+		KeyId:                m.KeyId,
+		Arn:                  m.Arn,
+		AWSAccountId:         m.AWSAccountId,
+		KeySpec:              m.KeySpec,
+		KeyUsage:             m.KeyUsage,
+		EncryptionAlgorithms: slices.Clone(m.EncryptionAlgorithms),
+		Origin:               m.Origin,
+		MultiRegion:          m.MultiRegion,
+		CreationDate:         m.CreationDate,
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/tink-crypto/tink-go-awskms/v3/integration/awskms/internal/fakeawskms"
+)
+
+const (
+	hmacKeyARN = "arn:aws:kms:us-east-2:235739564943:key/4b1c0ef2-6d0b-4a4c-9f1e-0b3c5a1d2e3f"
+	rsaKeyARN  = "arn:aws:kms:us-east-2:235739564943:key/7a2d9c1e-3b4f-4e5a-8c6d-1f2e3d4c5b6a"
+)
+
+func TestWithKeyValidation(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	metrics := &fakeMetrics{}
+	client := newTestClient(t, WithKMS(fakekms), WithKeyValidation(), WithMetrics(metrics))
+	for range 2 {
+		a, err := client.GetAEAD(sourceKeyURI)
+		if err != nil {
+			t.Fatalf("client.GetAEAD() err = %v, want nil", err)
+		}
+		if _, err := a.Encrypt([]byte("plaintext"), nil); err != nil {
+			t.Errorf("a.Encrypt() err = %v, want nil", err)
+		}
+	}
+	// The metadata is fetched once and then cached.
+	describeCalls := 0
+	for _, call := range metrics.calls {
+		if call.Operation == "DescribeKey" {
+			describeCalls++
+		}
+	}
+	if describeCalls != 1 {
+		t.Errorf("DescribeKey calls = %d, want 1", describeCalls)
+	}
+	if len(metrics.cacheLookups) != 2 || metrics.cacheLookups[0] || !metrics.cacheLookups[1] {
+		t.Errorf("metrics.cacheLookups = %v, want [false true]", metrics.cacheLookups)
+	}
+}
+
+func TestWithKeyValidation_rejectsKeys(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	if err := fakekms.AddKey(hmacKeyARN, types.KeySpecHmac256, types.KeyUsageTypeGenerateVerifyMac); err != nil {
+		t.Fatalf("fakekms.AddKey() failed: %v", err)
+	}
+	if err := fakekms.AddKey(rsaKeyARN, types.KeySpecRsa2048, types.KeyUsageTypeEncryptDecrypt); err != nil {
+		t.Fatalf("fakekms.AddKey() failed: %v", err)
+	}
+	client := newTestClient(t, WithKMS(fakekms), WithKeyValidation())
+	tests := []struct {
+		keyURI       string
+		wantErr      error
+		wantKeySpec  types.KeySpec
+		wantKeyUsage types.KeyUsageType
+	}{
+		{"aws-kms://" + hmacKeyARN, ErrUnsupportedKeyUsage, types.KeySpecHmac256, types.KeyUsageTypeGenerateVerifyMac},
+		{"aws-kms://" + rsaKeyARN, ErrUnsupportedKeySpec, types.KeySpecRsa2048, types.KeyUsageTypeEncryptDecrypt},
+	}
+	for _, test := range tests {
+		t.Run(string(test.wantKeySpec), func(t *testing.T) {
+			_, err := client.GetAEAD(test.keyURI)
+			if !errors.Is(err, test.wantErr) {
+				t.Errorf("client.GetAEAD() err = %v, want %v", err, test.wantErr)
+			}
+			var validationErr *KeyValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("client.GetAEAD() err = %v, want KeyValidationError", err)
+			}
+			if validationErr.KeyURI != test.keyURI || validationErr.KeySpec != test.wantKeySpec || validationErr.KeyUsage != test.wantKeyUsage {
+				t.Errorf("validationErr = %+v, want key %q with spec %v and usage %v", validationErr, test.keyURI, test.wantKeySpec, test.wantKeyUsage)
+			}
+		})
+	}
+}
+
+func TestWithKeyValidation_aliasIsNotCached(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	if err := fakekms.AddKey(rsaKeyARN, types.KeySpecRsa2048, types.KeyUsageTypeEncryptDecrypt); err != nil {
+		t.Fatalf("fakekms.AddKey() failed: %v", err)
+	}
+	if err := fakekms.SetAlias(aliasARN, sourceKeyARN); err != nil {
+		t.Fatalf("fakekms.SetAlias() failed: %v", err)
+	}
+	client := newTestClient(t, WithKMS(fakekms), WithKeyValidation())
+	if _, err := client.GetAEAD("aws-kms://" + aliasARN); err != nil {
+		t.Fatalf("client.GetAEAD() err = %v, want nil", err)
+	}
+	// The alias is resolved again, so retargeting it to an unsupported key is
+	// detected.
+	if err := fakekms.SetAlias(aliasARN, rsaKeyARN); err != nil {
+		t.Fatalf("fakekms.SetAlias() failed: %v", err)
+	}
+	if _, err := client.GetAEAD("aws-kms://" + aliasARN); !errors.Is(err, ErrUnsupportedKeySpec) {
+		t.Errorf("client.GetAEAD() after retargeting the alias err = %v, want %v", err, ErrUnsupportedKeySpec)
+	}
+	// The metadata of the key is cached by its ARN, without its state.
+	m, ok := client.(*awsClient).keyMetadataCache[sourceKeyARN]
+	if !ok {
+		t.Fatalf("keyMetadataCache[%q] not found", sourceKeyARN)
+	}
+	if m.KeyState != "" {
+		t.Errorf("cached m.KeyState = %v, want empty", m.KeyState)
+	}
+	if _, ok := client.(*awsClient).keyMetadataCache[aliasARN]; ok {
+		t.Errorf("keyMetadataCache[%q] found, want not cached", aliasARN)
+	}
+}
+
+func TestWithKeyValidation_fails(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	client := newTestClient(t, WithKMS(fakekms), WithKeyValidation())
+	if _, err := client.GetAEAD(destinationKeyURI); err == nil {
+		t.Error("client.GetAEAD() with unknown key err = nil, want error")
+	}
+	client = newTestClient(t, WithKMS(kmsAPIOnly{fakekms}), WithKeyValidation())
+	if _, err := client.GetAEAD(sourceKeyURI); !errors.Is(err, errDescribeKeyUnsupported) {
+		t.Errorf("client.GetAEAD() err = %v, want %v", err, errDescribeKeyUnsupported)
+	}
+	if _, err := NewClientWithOptions(t.Context(), "aws-kms://", WithKeyValidation(), WithKeyValidation()); err == nil {
+		t.Error("NewClientWithOptions(t.Context(), _, WithKeyValidation(), WithKeyValidation()) err = nil, want error")
+	}
+}
+
+func TestWithoutKeyValidation_returnsAEADForAnyKey(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	if err := fakekms.AddKey(hmacKeyARN, types.KeySpecHmac256, types.KeyUsageTypeGenerateVerifyMac); err != nil {
+		t.Fatalf("fakekms.AddKey() failed: %v", err)
+	}
+	client := newTestClient(t, WithKMS(fakekms))
+	a, err := client.GetAEAD("aws-kms://" + hmacKeyARN)
+	if err != nil {
+		t.Fatalf("client.GetAEAD() err = %v, want nil", err)
+	}
+	if _, err := a.Encrypt([]byte("plaintext"), nil); err == nil {
+		t.Error("a.Encrypt() with HMAC key err = nil, want error")
+	}
+}
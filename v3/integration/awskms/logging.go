@@ -0,0 +1,68 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// Credential sources reported when logging client construction.
+const (
+	credentialSourceDefault = "default credential chain"
+	credentialSourcePath    = "credential path"
+	credentialSourceKMS     = "caller-provided KMS client"
+)
+
+// WithLogger logs decisions made by the client and the primitives it creates
+// to logger.
+//
+// Client construction, the encryption context keys sent with every request
+// and the number of attempts made by the SDK are logged at [slog.LevelDebug].
+// Failed AWS KMS calls are logged at [slog.LevelWarn].
+//
+// Plaintexts, associated data, encryption context values and credentials are
+// never logged.
+func WithLogger(logger *slog.Logger) ClientOption {
+	return option(func(ctx context.Context, a *awsClient) error {
+		if logger == nil {
+			return errors.New("logger must not be nil")
+		}
+		if a.logger != nil {
+			return errors.New("WithLogger option cannot be used, logger already set")
+		}
+		a.logger = logger
+		return nil
+	})
+}
+
+// logClient logs how the client a was configured.
+func logClient(ctx context.Context, a *awsClient) {
+	if a.logger == nil {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("uri_prefix", a.keyURIPrefix),
+		slog.String("credential_source", a.credentialSource),
+		slog.String("encryption_context_encoder", a.encoder.String()),
+	}
+	if a.credentialSource != credentialSourceKMS {
+		if r, err := getRegion(a.keyURIPrefix); err == nil {
+			attrs = append(attrs, slog.String("region", r))
+		}
+	}
+	a.logger.LogAttrs(ctx, slog.LevelDebug, "awskms: created client", attrs...)
+}
@@ -0,0 +1,131 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/tink-crypto/tink-go-awskms/v3/integration/awskms/internal/fakeawskms"
+)
+
+// recordingHandler is a slog.Handler that keeps all records in memory.
+type recordingHandler struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+func (h *recordingHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *recordingHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, r)
+	return nil
+}
+
+func (h *recordingHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+
+func (h *recordingHandler) WithGroup(string) slog.Handler { return h }
+
+// attrs returns the attributes of r formatted as strings.
+func attrs(r slog.Record) map[string]string {
+	m := make(map[string]string)
+	r.Attrs(func(a slog.Attr) bool {
+		m[a.Key] = a.Value.String()
+		return true
+	})
+	return m
+}
+
+func TestWithLogger(t *testing.T) {
+	keyARN := "arn:aws:kms:us-east-2:235739564943:key/3ee50705-5a82-4f5b-9753-05c4f473922f"
+	keyURI := "aws-kms://arn:aws:kms:us-east-2:235739564943:key/3ee50705-5a82-4f5b-9753-05c4f473922f"
+	fakekms, err := fakeawskms.New([]string{keyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	h := &recordingHandler{}
+	client, err := NewClientWithOptions(t.Context(), "aws-kms://", WithKMS(fakekms), WithLogger(slog.New(h)))
+	if err != nil {
+		t.Fatalf("NewClientWithOptions() failed: %v", err)
+	}
+	a, err := client.GetAEAD(keyURI)
+	if err != nil {
+		t.Fatalf("client.GetAEAD(keyURI) err = %v, want nil", err)
+	}
+	plaintext := []byte("secret plaintext")
+	associatedData := []byte("secret associated data")
+	ciphertext, err := a.Encrypt(plaintext, associatedData)
+	if err != nil {
+		t.Fatalf("a.Encrypt(plaintext, associatedData) err = %v, want nil", err)
+	}
+	if _, err := a.Decrypt(ciphertext, []byte("wrong associated data")); err == nil {
+		t.Fatal("a.Decrypt(ciphertext, wrongAssociatedData) err = nil, want error")
+	}
+
+	if len(h.records) != 3 {
+		t.Fatalf("len(h.records) = %d, want 3", len(h.records))
+	}
+	created, encrypted, failed := h.records[0], h.records[1], h.records[2]
+	if created.Level != slog.LevelDebug || attrs(created)["credential_source"] != credentialSourceKMS || attrs(created)["encryption_context_encoder"] != "associatedData" {
+		t.Errorf("h.records[0] = %v %v, want debug record of client creation", created.Message, attrs(created))
+	}
+	if encrypted.Level != slog.LevelDebug || attrs(encrypted)["operation"] != "Encrypt" || attrs(encrypted)["key_id"] != keyARN {
+		t.Errorf("h.records[1] = %v %v, want debug record of Encrypt", encrypted.Message, attrs(encrypted))
+	}
+	if got, want := attrs(encrypted)["encryption_context_keys"], "[associatedData]"; got != want {
+		t.Errorf("h.records[1] encryption_context_keys = %q, want %q", got, want)
+	}
+	if failed.Level != slog.LevelWarn || attrs(failed)["operation"] != "Decrypt" || attrs(failed)["error"] == "" {
+		t.Errorf("h.records[2] = %v %v, want warning about failed Decrypt", failed.Message, attrs(failed))
+	}
+
+	for _, r := range h.records {
+		s := fmt.Sprint(r.Message, attrs(r))
+		for _, secret := range [][]byte{plaintext, associatedData} {
+			if strings.Contains(s, string(secret)) || strings.Contains(s, hex.EncodeToString(secret)) {
+				t.Errorf("record %q contains %q", s, secret)
+			}
+		}
+	}
+}
+
+func TestWithLogger_logsRegion(t *testing.T) {
+	h := &recordingHandler{}
+	uriPrefix := "aws-kms://arn:aws-us-gov:kms:us-gov-east-1:235739564943:key/"
+	if _, err := NewClientWithOptions(t.Context(), uriPrefix, WithLogger(slog.New(h))); err != nil {
+		t.Fatalf("NewClientWithOptions() failed: %v", err)
+	}
+	if len(h.records) != 1 {
+		t.Fatalf("len(h.records) = %d, want 1", len(h.records))
+	}
+	got := attrs(h.records[0])
+	if got["region"] != "us-gov-east-1" || got["credential_source"] != credentialSourceDefault {
+		t.Errorf("h.records[0] attributes = %v, want region us-gov-east-1 and default credentials", got)
+	}
+}
+
+func TestWithLogger_repeatedFails(t *testing.T) {
+	logger := slog.New(&recordingHandler{})
+	if _, err := NewClientWithOptions(t.Context(), "aws-kms://", WithLogger(logger), WithLogger(logger)); err == nil {
+		t.Fatal("NewClientWithOptions(t.Context(), _, WithLogger(_), WithLogger(_)) err = nil, want error")
+	}
+}
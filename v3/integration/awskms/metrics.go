@@ -0,0 +1,73 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Metrics receives measurements about the requests sent to AWS KMS by
+// primitives created by this package.
+//
+// Implementations must be safe for concurrent use. Adapters for OpenTelemetry
+// and Prometheus are provided by the otelmetrics and prommetrics packages. The
+// otelmetrics package is a separate module, so that this module does not
+// depend on OpenTelemetry.
+type Metrics interface {
+	// RecordCall is called once for every completed AWS KMS API call.
+	RecordCall(ctx context.Context, call CallRecord)
+	// RecordCacheLookup is called whenever a client-side cache is consulted
+	// for keyID.
+	RecordCacheLookup(ctx context.Context, cache, keyID string, hit bool)
+}
+
+// CallRecord describes a single completed AWS KMS API call.
+type CallRecord struct {
+	// Operation is the name of the AWS KMS API, for example "Encrypt".
+	Operation string
+	// KeyID is the key ID, key ARN, alias name or alias ARN sent in the
+	// request. It is empty if the request did not specify a key.
+	KeyID string
+	// Duration is the time the call took, including retries done by the SDK.
+	Duration time.Duration
+	// RequestBytes is the size of the payload sent to AWS KMS, that is the
+	// plaintext or ciphertext. Encryption context is not included.
+	RequestBytes int
+	// ResponseBytes is the size of the payload returned by AWS KMS.
+	ResponseBytes int
+	// ErrorKind is empty if the call succeeded. Otherwise it is the AWS KMS
+	// error code, for example "InvalidCiphertextException", or one of
+	// "Canceled", "DeadlineExceeded" and "Unknown".
+	ErrorKind string
+}
+
+// WithMetrics reports measurements about every AWS KMS call made by the
+// client to m.
+//
+// When this option is not used, no measurements are taken.
+func WithMetrics(m Metrics) ClientOption {
+	return option(func(ctx context.Context, a *awsClient) error {
+		if m == nil {
+			return errors.New("metrics must not be nil")
+		}
+		if a.metrics != nil {
+			return errors.New("WithMetrics option cannot be used, metrics already set")
+		}
+		a.metrics = m
+		return nil
+	})
+}
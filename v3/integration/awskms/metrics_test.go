@@ -0,0 +1,164 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/aws/smithy-go"
+	"github.com/tink-crypto/tink-go-awskms/v3/integration/awskms/internal/fakeawskms"
+)
+
+type fakeMetrics struct {
+	mu           sync.Mutex
+	calls        []CallRecord
+	cacheLookups []bool
+}
+
+func (m *fakeMetrics) RecordCall(ctx context.Context, call CallRecord) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = append(m.calls, call)
+}
+
+func (m *fakeMetrics) RecordCacheLookup(ctx context.Context, cache, keyID string, hit bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cacheLookups = append(m.cacheLookups, hit)
+}
+
+func TestWithMetrics_recordsCalls(t *testing.T) {
+	keyARN := "arn:aws:kms:us-east-2:235739564943:key/3ee50705-5a82-4f5b-9753-05c4f473922f"
+	keyURI := "aws-kms://arn:aws:kms:us-east-2:235739564943:key/3ee50705-5a82-4f5b-9753-05c4f473922f"
+	fakekms, err := fakeawskms.New([]string{keyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	metrics := &fakeMetrics{}
+	client, err := NewClientWithOptions(t.Context(), "aws-kms://", WithKMS(fakekms), WithMetrics(metrics))
+	if err != nil {
+		t.Fatalf("NewClientWithOptions() failed: %v", err)
+	}
+	a, err := client.GetAEAD(keyURI)
+	if err != nil {
+		t.Fatalf("client.GetAEAD(keyURI) err = %v, want nil", err)
+	}
+
+	plaintext := []byte("plaintext")
+	associatedData := []byte("associatedData")
+	ciphertext, err := a.Encrypt(plaintext, associatedData)
+	if err != nil {
+		t.Fatalf("a.Encrypt(plaintext, associatedData) err = %v, want nil", err)
+	}
+	if _, err := a.Decrypt(ciphertext, associatedData); err != nil {
+		t.Fatalf("a.Decrypt(ciphertext, associatedData) err = %v, want nil", err)
+	}
+	if _, err := a.Decrypt(ciphertext, []byte("invalidAssociatedData")); err == nil {
+		t.Fatal("a.Decrypt(ciphertext, invalidAssociatedData) err = nil, want error")
+	}
+
+	if len(metrics.calls) != 3 {
+		t.Fatalf("len(metrics.calls) = %d, want 3", len(metrics.calls))
+	}
+	enc, dec, failed := metrics.calls[0], metrics.calls[1], metrics.calls[2]
+	if enc.Operation != "Encrypt" || enc.KeyID != keyARN || enc.ErrorKind != "" {
+		t.Errorf("metrics.calls[0] = %+v, want successful Encrypt with key %q", enc, keyARN)
+	}
+	if enc.RequestBytes != len(plaintext) || enc.ResponseBytes != len(ciphertext) {
+		t.Errorf("metrics.calls[0] sizes = (%d, %d), want (%d, %d)", enc.RequestBytes, enc.ResponseBytes, len(plaintext), len(ciphertext))
+	}
+	if dec.Operation != "Decrypt" || dec.KeyID != keyARN || dec.ErrorKind != "" {
+		t.Errorf("metrics.calls[1] = %+v, want successful Decrypt with key %q", dec, keyARN)
+	}
+	if dec.RequestBytes != len(ciphertext) || dec.ResponseBytes != len(plaintext) {
+		t.Errorf("metrics.calls[1] sizes = (%d, %d), want (%d, %d)", dec.RequestBytes, dec.ResponseBytes, len(ciphertext), len(plaintext))
+	}
+	if failed.Operation != "Decrypt" || failed.ErrorKind == "" {
+		t.Errorf("metrics.calls[2] = %+v, want failed Decrypt", failed)
+	}
+}
+
+func TestWithMetrics_nilFails(t *testing.T) {
+	if _, err := NewClientWithOptions(t.Context(), "aws-kms://", WithMetrics(nil)); err == nil {
+		t.Fatal("NewClientWithOptions(t.Context(), _, WithMetrics(nil)) err = nil, want error")
+	}
+}
+
+func TestWithMetrics_repeatedFails(t *testing.T) {
+	_, err := NewClientWithOptions(t.Context(), "aws-kms://", WithMetrics(&fakeMetrics{}), WithMetrics(&fakeMetrics{}))
+	if err == nil {
+		t.Fatal("NewClientWithOptions(t.Context(), _, WithMetrics(_), WithMetrics(_)) err = nil, want error")
+	}
+}
+
+func TestWithoutMetrics_doesNotInstrument(t *testing.T) {
+	keyARN := "arn:aws:kms:us-east-2:235739564943:key/3ee50705-5a82-4f5b-9753-05c4f473922f"
+	fakekms, err := fakeawskms.New([]string{keyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	a, err := newAWSClient(t.Context(), "aws-kms://", WithKMS(fakekms))
+	if err != nil {
+		t.Fatalf("newAWSClient() failed: %v", err)
+	}
+	if _, ok := a.kms.(*instrumentedKMS); ok {
+		t.Error("a.kms is instrumented, want the KMS client to be used directly")
+	}
+}
+
+func TestErrorKind(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{
+			name: "nil",
+			err:  nil,
+			want: "",
+		},
+		{
+			name: "canceled",
+			err:  fmt.Errorf("operation error: %w", context.Canceled),
+			want: "Canceled",
+		},
+		{
+			name: "deadline exceeded",
+			err:  context.DeadlineExceeded,
+			want: "DeadlineExceeded",
+		},
+		{
+			name: "API error",
+			err:  fmt.Errorf("operation error: %w", &smithy.GenericAPIError{Code: "InvalidCiphertextException"}),
+			want: "InvalidCiphertextException",
+		},
+		{
+			name: "other",
+			err:  errors.New("other"),
+			want: "Unknown",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := errorKind(test.err); got != test.want {
+				t.Errorf("errorKind(%v) = %q, want %q", test.err, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import "context"
+
+// OperationDetails reports how an AEAD operation was performed.
+//
+// To obtain it, pass a context created with [ContextWithOperationDetails] to
+// EncryptWithContext or DecryptWithContext. The fields are set when the
+// operation succeeds.
+type OperationDetails struct {
+	// Encoder is the encoder whose encryption context was used. When
+	// decrypting with [WithDecryptFallback], this tells which convention the
+	// ciphertext was written with.
+	Encoder EncryptionContextEncoder
+	// KeyARN is the ARN of the key which performed the operation, as reported
+	// by AWS KMS. It is useful if the key URI refers to an alias.
+	KeyARN string
+	// DryRun is set if the operation was a dry run, enabled with [WithDryRun]
+	// or [ContextWithDryRun], which AWS KMS reported would have succeeded. The
+	// operation then returns [ErrDryRunSucceeded] and no ciphertext or
+	// plaintext.
+	DryRun *DryRunResult
+}
+
+type operationDetailsKey struct{}
+
+// ContextWithOperationDetails returns a copy of ctx which makes AEAD
+// operations using it fill in details.
+func ContextWithOperationDetails(ctx context.Context, details *OperationDetails) context.Context {
+	return context.WithValue(ctx, operationDetailsKey{}, details)
+}
+
+// operationDetails returns the OperationDetails registered in ctx, or nil.
+func operationDetails(ctx context.Context) *OperationDetails {
+	d, _ := ctx.Value(operationDetailsKey{}).(*OperationDetails)
+	return d
+}
@@ -0,0 +1,123 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package otelmetrics provides an implementation of [awskms.Metrics] which
+// records measurements with an OpenTelemetry [metric.Meter].
+//
+//	m, err := otelmetrics.New(otel.Meter("github.com/tink-crypto/tink-go-awskms"))
+//	if err != nil { ... }
+//	client, err := awskms.NewClientWithOptions(ctx, uriPrefix, awskms.WithMetrics(m))
+//
+// The following instruments are created, all with the attributes
+// "awskms.operation" and "awskms.key":
+//
+//   - awskms.calls: counter of calls, with the additional attribute
+//     "error.type" for failed calls.
+//   - awskms.call.duration: histogram of call latencies in seconds.
+//   - awskms.request.size and awskms.response.size: histograms of payload
+//     sizes in bytes.
+//   - awskms.cache.lookups: counter of cache lookups, with the attributes
+//     "awskms.cache", "awskms.key" and "awskms.cache.hit".
+//
+// The package is a separate module, so that users of the awskms package who
+// do not use OpenTelemetry do not depend on it. It requires the awskms release
+// introducing [awskms.Metrics], and is released together with it.
+package otelmetrics
+
+import (
+	"context"
+
+	"github.com/tink-crypto/tink-go-awskms/v3/integration/awskms"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Metrics records AWS KMS client measurements with OpenTelemetry instruments.
+// It implements [awskms.Metrics].
+type Metrics struct {
+	calls        metric.Int64Counter
+	duration     metric.Float64Histogram
+	requestSize  metric.Int64Histogram
+	responseSize metric.Int64Histogram
+	cacheLookups metric.Int64Counter
+}
+
+var _ awskms.Metrics = (*Metrics)(nil)
+
+// New creates the instruments described in the package documentation using
+// meter.
+func New(meter metric.Meter) (*Metrics, error) {
+	calls, err := meter.Int64Counter("awskms.calls",
+		metric.WithDescription("Number of AWS KMS API calls."),
+		metric.WithUnit("{call}"))
+	if err != nil {
+		return nil, err
+	}
+	duration, err := meter.Float64Histogram("awskms.call.duration",
+		metric.WithDescription("Latency of AWS KMS API calls."),
+		metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+	requestSize, err := meter.Int64Histogram("awskms.request.size",
+		metric.WithDescription("Size of the payload sent to AWS KMS."),
+		metric.WithUnit("By"))
+	if err != nil {
+		return nil, err
+	}
+	responseSize, err := meter.Int64Histogram("awskms.response.size",
+		metric.WithDescription("Size of the payload returned by AWS KMS."),
+		metric.WithUnit("By"))
+	if err != nil {
+		return nil, err
+	}
+	cacheLookups, err := meter.Int64Counter("awskms.cache.lookups",
+		metric.WithDescription("Number of client-side cache lookups."),
+		metric.WithUnit("{lookup}"))
+	if err != nil {
+		return nil, err
+	}
+	return &Metrics{
+		calls:        calls,
+		duration:     duration,
+		requestSize:  requestSize,
+		responseSize: responseSize,
+		cacheLookups: cacheLookups,
+	}, nil
+}
+
+// RecordCall implements [awskms.Metrics].
+func (m *Metrics) RecordCall(ctx context.Context, call awskms.CallRecord) {
+	attrs := metric.WithAttributes(
+		attribute.String("awskms.operation", call.Operation),
+		attribute.String("awskms.key", call.KeyID),
+	)
+	if call.ErrorKind != "" {
+		m.calls.Add(ctx, 1, attrs, metric.WithAttributes(attribute.String("error.type", call.ErrorKind)))
+	} else {
+		m.calls.Add(ctx, 1, attrs)
+		m.responseSize.Record(ctx, int64(call.ResponseBytes), attrs)
+	}
+	m.duration.Record(ctx, call.Duration.Seconds(), attrs)
+	m.requestSize.Record(ctx, int64(call.RequestBytes), attrs)
+}
+
+// RecordCacheLookup implements [awskms.Metrics].
+func (m *Metrics) RecordCacheLookup(ctx context.Context, cache, keyID string, hit bool) {
+	m.cacheLookups.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("awskms.cache", cache),
+		attribute.String("awskms.key", keyID),
+		attribute.Bool("awskms.cache.hit", hit),
+	))
+}
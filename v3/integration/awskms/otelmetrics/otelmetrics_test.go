@@ -0,0 +1,174 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package otelmetrics_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tink-crypto/tink-go-awskms/v3/integration/awskms"
+	"github.com/tink-crypto/tink-go-awskms/v3/integration/awskms/otelmetrics"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+const keyARN = "arn:aws:kms:us-east-2:235739564943:key/3ee50705-5a82-4f5b-9753-05c4f473922f"
+
+// measurement is a single value recorded by one of the fake instruments.
+type measurement struct {
+	instrument string
+	value      float64
+	attrs      attribute.Set
+}
+
+// fakeMeter records all measurements made through its Int64Counter,
+// Int64Histogram and Float64Histogram instruments.
+type fakeMeter struct {
+	noop.Meter
+	measurements []measurement
+}
+
+type fakeInt64Counter struct {
+	noop.Int64Counter
+	name  string
+	meter *fakeMeter
+}
+
+func (c *fakeInt64Counter) Add(ctx context.Context, incr int64, opts ...metric.AddOption) {
+	c.meter.measurements = append(c.meter.measurements, measurement{c.name, float64(incr), metric.NewAddConfig(opts).Attributes()})
+}
+
+type fakeInt64Histogram struct {
+	noop.Int64Histogram
+	name  string
+	meter *fakeMeter
+}
+
+func (h *fakeInt64Histogram) Record(ctx context.Context, v int64, opts ...metric.RecordOption) {
+	h.meter.measurements = append(h.meter.measurements, measurement{h.name, float64(v), metric.NewRecordConfig(opts).Attributes()})
+}
+
+type fakeFloat64Histogram struct {
+	noop.Float64Histogram
+	name  string
+	meter *fakeMeter
+}
+
+func (h *fakeFloat64Histogram) Record(ctx context.Context, v float64, opts ...metric.RecordOption) {
+	h.meter.measurements = append(h.meter.measurements, measurement{h.name, v, metric.NewRecordConfig(opts).Attributes()})
+}
+
+func (m *fakeMeter) Int64Counter(name string, options ...metric.Int64CounterOption) (metric.Int64Counter, error) {
+	return &fakeInt64Counter{name: name, meter: m}, nil
+}
+
+func (m *fakeMeter) Int64Histogram(name string, options ...metric.Int64HistogramOption) (metric.Int64Histogram, error) {
+	return &fakeInt64Histogram{name: name, meter: m}, nil
+}
+
+func (m *fakeMeter) Float64Histogram(name string, options ...metric.Float64HistogramOption) (metric.Float64Histogram, error) {
+	return &fakeFloat64Histogram{name: name, meter: m}, nil
+}
+
+func (m *fakeMeter) find(t *testing.T, instrument string) measurement {
+	t.Helper()
+	for _, got := range m.measurements {
+		if got.instrument == instrument {
+			return got
+		}
+	}
+	t.Fatalf("no measurement for instrument %q in %v", instrument, m.measurements)
+	return measurement{}
+}
+
+func TestRecordCall(t *testing.T) {
+	meter := &fakeMeter{}
+	m, err := otelmetrics.New(meter)
+	if err != nil {
+		t.Fatalf("otelmetrics.New() err = %v, want nil", err)
+	}
+	m.RecordCall(t.Context(), awskms.CallRecord{
+		Operation:     "Encrypt",
+		KeyID:         keyARN,
+		Duration:      250 * time.Millisecond,
+		RequestBytes:  9,
+		ResponseBytes: 200,
+	})
+
+	calls := meter.find(t, "awskms.calls")
+	if calls.value != 1 {
+		t.Errorf("awskms.calls value = %v, want 1", calls.value)
+	}
+	if v, ok := calls.attrs.Value("awskms.operation"); !ok || v.AsString() != "Encrypt" {
+		t.Errorf("awskms.calls awskms.operation = %v, want Encrypt", v.AsString())
+	}
+	if v, ok := calls.attrs.Value("awskms.key"); !ok || v.AsString() != keyARN {
+		t.Errorf("awskms.calls awskms.key = %v, want %q", v.AsString(), keyARN)
+	}
+	if _, ok := calls.attrs.Value("error.type"); ok {
+		t.Error("awskms.calls has attribute error.type for a successful call")
+	}
+	if got := meter.find(t, "awskms.call.duration").value; got != 0.25 {
+		t.Errorf("awskms.call.duration value = %v, want 0.25", got)
+	}
+	if got := meter.find(t, "awskms.request.size").value; got != 9 {
+		t.Errorf("awskms.request.size value = %v, want 9", got)
+	}
+	if got := meter.find(t, "awskms.response.size").value; got != 200 {
+		t.Errorf("awskms.response.size value = %v, want 200", got)
+	}
+}
+
+func TestRecordCall_failedCall(t *testing.T) {
+	meter := &fakeMeter{}
+	m, err := otelmetrics.New(meter)
+	if err != nil {
+		t.Fatalf("otelmetrics.New() err = %v, want nil", err)
+	}
+	m.RecordCall(t.Context(), awskms.CallRecord{
+		Operation: "Decrypt",
+		KeyID:     keyARN,
+		ErrorKind: "InvalidCiphertextException",
+	})
+
+	calls := meter.find(t, "awskms.calls")
+	if v, ok := calls.attrs.Value("error.type"); !ok || v.AsString() != "InvalidCiphertextException" {
+		t.Errorf("awskms.calls error.type = %v, want InvalidCiphertextException", v.AsString())
+	}
+	for _, got := range meter.measurements {
+		if got.instrument == "awskms.response.size" {
+			t.Errorf("awskms.response.size recorded for a failed call: %v", got)
+		}
+	}
+}
+
+func TestRecordCacheLookup(t *testing.T) {
+	meter := &fakeMeter{}
+	m, err := otelmetrics.New(meter)
+	if err != nil {
+		t.Fatalf("otelmetrics.New() err = %v, want nil", err)
+	}
+	m.RecordCacheLookup(t.Context(), "key-metadata", keyARN, true)
+
+	lookup := meter.find(t, "awskms.cache.lookups")
+	if v, ok := lookup.attrs.Value("awskms.cache.hit"); !ok || !v.AsBool() {
+		t.Errorf("awskms.cache.lookups awskms.cache.hit = %v, want true", v.AsBool())
+	}
+	if v, ok := lookup.attrs.Value("awskms.cache"); !ok || v.AsString() != "key-metadata" {
+		t.Errorf("awskms.cache.lookups awskms.cache = %v, want key-metadata", v.AsString())
+	}
+}
@@ -0,0 +1,278 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package prommetrics provides an implementation of [awskms.Metrics] which can
+// be scraped by Prometheus.
+//
+// The collected metrics are exposed in the Prometheus text exposition format
+// by [Metrics.ServeHTTP], so no Prometheus client library is required:
+//
+//	m := prommetrics.New()
+//	http.Handle("/metrics/awskms", m)
+//	client, err := awskms.NewClientWithOptions(ctx, uriPrefix, awskms.WithMetrics(m))
+//
+// The following metrics are exported, all labelled by operation and key:
+//
+//   - awskms_calls_total: counter of calls, additionally labelled by error
+//     kind, which is empty for successful calls.
+//   - awskms_call_duration_seconds: histogram of call latencies.
+//   - awskms_request_bytes and awskms_response_bytes: histograms of payload
+//     sizes.
+//   - awskms_cache_lookups_total: counter of cache lookups, labelled by cache
+//     and result instead of operation.
+package prommetrics
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/tink-crypto/tink-go-awskms/v3/integration/awskms"
+)
+
+var (
+	// DefaultDurationBuckets are the upper bounds, in seconds, of the buckets
+	// of the call duration histogram.
+	DefaultDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+	// DefaultSizeBuckets are the upper bounds, in bytes, of the buckets of the
+	// payload size histograms. AWS KMS accepts at most 4096 bytes of plaintext.
+	DefaultSizeBuckets = []float64{32, 64, 128, 256, 512, 1024, 2048, 4096, 8192}
+)
+
+type callLabels struct {
+	operation string
+	key       string
+}
+
+type countLabels struct {
+	operation string
+	key       string
+	errorKind string
+}
+
+type cacheLabels struct {
+	cache string
+	key   string
+	hit   bool
+}
+
+type histogram struct {
+	counts []uint64 // counts[i] is the number of observations <= bounds[i].
+	count  uint64
+	sum    float64
+}
+
+func (h *histogram) observe(bounds []float64, v float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(bounds))
+	}
+	for i, b := range bounds {
+		if v <= b {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+// Metrics collects AWS KMS client measurements in memory. It implements
+// [awskms.Metrics] and [http.Handler].
+//
+// The zero value is not usable; use [New] instead.
+type Metrics struct {
+	durationBuckets []float64
+	sizeBuckets     []float64
+
+	mu            sync.Mutex
+	calls         map[countLabels]uint64
+	durations     map[callLabels]*histogram
+	requestBytes  map[callLabels]*histogram
+	responseBytes map[callLabels]*histogram
+	cacheLookups  map[cacheLabels]uint64
+}
+
+var _ awskms.Metrics = (*Metrics)(nil)
+
+// New returns an empty Metrics which uses [DefaultDurationBuckets] and
+// [DefaultSizeBuckets].
+func New() *Metrics {
+	return NewWithBuckets(DefaultDurationBuckets, DefaultSizeBuckets)
+}
+
+// NewWithBuckets returns an empty Metrics using the given histogram bucket
+// upper bounds. Both slices are copied and sorted.
+func NewWithBuckets(durationBuckets, sizeBuckets []float64) *Metrics {
+	d := append([]float64(nil), durationBuckets...)
+	s := append([]float64(nil), sizeBuckets...)
+	sort.Float64s(d)
+	sort.Float64s(s)
+	return &Metrics{
+		durationBuckets: d,
+		sizeBuckets:     s,
+		calls:           make(map[countLabels]uint64),
+		durations:       make(map[callLabels]*histogram),
+		requestBytes:    make(map[callLabels]*histogram),
+		responseBytes:   make(map[callLabels]*histogram),
+		cacheLookups:    make(map[cacheLabels]uint64),
+	}
+}
+
+func histogramFor(m map[callLabels]*histogram, l callLabels) *histogram {
+	h, ok := m[l]
+	if !ok {
+		h = &histogram{}
+		m[l] = h
+	}
+	return h
+}
+
+// RecordCall implements [awskms.Metrics].
+func (m *Metrics) RecordCall(ctx context.Context, call awskms.CallRecord) {
+	l := callLabels{operation: call.Operation, key: call.KeyID}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls[countLabels{operation: call.Operation, key: call.KeyID, errorKind: call.ErrorKind}]++
+	histogramFor(m.durations, l).observe(m.durationBuckets, call.Duration.Seconds())
+	histogramFor(m.requestBytes, l).observe(m.sizeBuckets, float64(call.RequestBytes))
+	if call.ErrorKind == "" {
+		histogramFor(m.responseBytes, l).observe(m.sizeBuckets, float64(call.ResponseBytes))
+	}
+}
+
+// RecordCacheLookup implements [awskms.Metrics].
+func (m *Metrics) RecordCacheLookup(ctx context.Context, cache, keyID string, hit bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cacheLookups[cacheLabels{cache: cache, key: keyID, hit: hit}]++
+}
+
+// ServeHTTP writes the collected metrics in the Prometheus text exposition
+// format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if _, err := m.WriteTo(w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+type countingWriter struct {
+	w *bufio.Writer
+	n int64
+}
+
+func (c *countingWriter) printf(format string, args ...any) {
+	n, _ := fmt.Fprintf(c.w, format, args...)
+	c.n += int64(n)
+}
+
+// WriteTo writes the collected metrics to w in the Prometheus text exposition
+// format. Series are sorted, so the output is deterministic.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+
+	cw.printf("# HELP awskms_calls_total Number of AWS KMS API calls.\n")
+	cw.printf("# TYPE awskms_calls_total counter\n")
+	callKeys := make([]countLabels, 0, len(m.calls))
+	for k := range m.calls {
+		callKeys = append(callKeys, k)
+	}
+	sort.Slice(callKeys, func(i, j int) bool {
+		a, b := callKeys[i], callKeys[j]
+		if a.operation != b.operation {
+			return a.operation < b.operation
+		}
+		if a.key != b.key {
+			return a.key < b.key
+		}
+		return a.errorKind < b.errorKind
+	})
+	for _, k := range callKeys {
+		cw.printf("awskms_calls_total{operation=%s,key=%s,error_kind=%s} %d\n", quote(k.operation), quote(k.key), quote(k.errorKind), m.calls[k])
+	}
+
+	writeHistograms(cw, "awskms_call_duration_seconds", "Latency of AWS KMS API calls in seconds.", m.durationBuckets, m.durations)
+	writeHistograms(cw, "awskms_request_bytes", "Size of the payload sent to AWS KMS in bytes.", m.sizeBuckets, m.requestBytes)
+	writeHistograms(cw, "awskms_response_bytes", "Size of the payload returned by AWS KMS in bytes.", m.sizeBuckets, m.responseBytes)
+
+	cw.printf("# HELP awskms_cache_lookups_total Number of client-side cache lookups.\n")
+	cw.printf("# TYPE awskms_cache_lookups_total counter\n")
+	cacheKeys := make([]cacheLabels, 0, len(m.cacheLookups))
+	for k := range m.cacheLookups {
+		cacheKeys = append(cacheKeys, k)
+	}
+	sort.Slice(cacheKeys, func(i, j int) bool {
+		a, b := cacheKeys[i], cacheKeys[j]
+		if a.cache != b.cache {
+			return a.cache < b.cache
+		}
+		if a.key != b.key {
+			return a.key < b.key
+		}
+		return !a.hit && b.hit
+	})
+	for _, k := range cacheKeys {
+		result := "miss"
+		if k.hit {
+			result = "hit"
+		}
+		cw.printf("awskms_cache_lookups_total{cache=%s,key=%s,result=%s} %d\n", quote(k.cache), quote(k.key), quote(result), m.cacheLookups[k])
+	}
+
+	return cw.n, cw.w.Flush()
+}
+
+func writeHistograms(cw *countingWriter, name, help string, bounds []float64, hs map[callLabels]*histogram) {
+	cw.printf("# HELP %s %s\n", name, help)
+	cw.printf("# TYPE %s histogram\n", name)
+	keys := make([]callLabels, 0, len(hs))
+	for k := range hs {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].operation != keys[j].operation {
+			return keys[i].operation < keys[j].operation
+		}
+		return keys[i].key < keys[j].key
+	})
+	for _, k := range keys {
+		h := hs[k]
+		labels := fmt.Sprintf("operation=%s,key=%s", quote(k.operation), quote(k.key))
+		for i, b := range bounds {
+			cw.printf("%s_bucket{%s,le=%s} %d\n", name, labels, quote(formatFloat(b)), h.counts[i])
+		}
+		cw.printf("%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, h.count)
+		cw.printf("%s_sum{%s} %s\n", name, labels, formatFloat(h.sum))
+		cw.printf("%s_count{%s} %d\n", name, labels, h.count)
+	}
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+// quote returns s as a quoted label value.
+func quote(s string) string {
+	return `"` + labelEscaper.Replace(s) + `"`
+}
@@ -0,0 +1,102 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package prommetrics_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tink-crypto/tink-go-awskms/v3/integration/awskms"
+	"github.com/tink-crypto/tink-go-awskms/v3/integration/awskms/prommetrics"
+)
+
+const keyARN = "arn:aws:kms:us-east-2:235739564943:key/3ee50705-5a82-4f5b-9753-05c4f473922f"
+
+func TestMetrics_WriteTo(t *testing.T) {
+	m := prommetrics.NewWithBuckets([]float64{0.1, 1}, []float64{100})
+	m.RecordCall(t.Context(), awskms.CallRecord{
+		Operation:     "Encrypt",
+		KeyID:         keyARN,
+		Duration:      50 * time.Millisecond,
+		RequestBytes:  9,
+		ResponseBytes: 200,
+	})
+	m.RecordCall(t.Context(), awskms.CallRecord{
+		Operation:    "Encrypt",
+		KeyID:        keyARN,
+		Duration:     2 * time.Second,
+		RequestBytes: 9,
+		ErrorKind:    "ThrottlingException",
+	})
+	m.RecordCacheLookup(t.Context(), "key-metadata", keyARN, true)
+
+	var b strings.Builder
+	n, err := m.WriteTo(&b)
+	if err != nil {
+		t.Fatalf("m.WriteTo() err = %v, want nil", err)
+	}
+	got := b.String()
+	if int(n) != len(got) {
+		t.Errorf("m.WriteTo() = %d, want %d", n, len(got))
+	}
+	for _, want := range []string{
+		`awskms_calls_total{operation="Encrypt",key="` + keyARN + `",error_kind=""} 1`,
+		`awskms_calls_total{operation="Encrypt",key="` + keyARN + `",error_kind="ThrottlingException"} 1`,
+		`awskms_call_duration_seconds_bucket{operation="Encrypt",key="` + keyARN + `",le="0.1"} 1`,
+		`awskms_call_duration_seconds_bucket{operation="Encrypt",key="` + keyARN + `",le="1"} 1`,
+		`awskms_call_duration_seconds_bucket{operation="Encrypt",key="` + keyARN + `",le="+Inf"} 2`,
+		`awskms_call_duration_seconds_count{operation="Encrypt",key="` + keyARN + `"} 2`,
+		`awskms_request_bytes_sum{operation="Encrypt",key="` + keyARN + `"} 18`,
+		`awskms_response_bytes_bucket{operation="Encrypt",key="` + keyARN + `",le="100"} 0`,
+		`awskms_response_bytes_count{operation="Encrypt",key="` + keyARN + `"} 1`,
+		`awskms_cache_lookups_total{cache="key-metadata",key="` + keyARN + `",result="hit"} 1`,
+		"# TYPE awskms_call_duration_seconds histogram",
+	} {
+		if !strings.Contains(got, want+"\n") {
+			t.Errorf("m.WriteTo() output does not contain %q\noutput:\n%s", want, got)
+		}
+	}
+}
+
+func TestMetrics_escapesLabelValues(t *testing.T) {
+	m := prommetrics.New()
+	m.RecordCall(t.Context(), awskms.CallRecord{Operation: "Decrypt", KeyID: "alias/\"quoted\"\\"})
+	var b strings.Builder
+	if _, err := m.WriteTo(&b); err != nil {
+		t.Fatalf("m.WriteTo() err = %v, want nil", err)
+	}
+	want := `key="alias/\"quoted\"\\"`
+	if !strings.Contains(b.String(), want) {
+		t.Errorf("m.WriteTo() output does not contain %q\noutput:\n%s", want, b.String())
+	}
+}
+
+func TestMetrics_ServeHTTP(t *testing.T) {
+	m := prommetrics.New()
+	m.RecordCall(t.Context(), awskms.CallRecord{Operation: "Decrypt", KeyID: keyARN})
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if got, want := rec.Header().Get("Content-Type"), "text/plain; version=0.0.4; charset=utf-8"; got != want {
+		t.Errorf("Content-Type = %q, want %q", got, want)
+	}
+	want := `awskms_calls_total{operation="Decrypt",key="` + keyARN + `",error_kind=""} 1`
+	if !strings.Contains(rec.Body.String(), want) {
+		t.Errorf("response body does not contain %q\nbody:\n%s", want, rec.Body.String())
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// generateRandomAPI is implemented by KMS clients supporting GenerateRandom,
+// such as *kms.Client.
+type generateRandomAPI interface {
+	GenerateRandom(ctx context.Context, params *kms.GenerateRandomInput, optFns ...func(*kms.Options)) (*kms.GenerateRandomOutput, error)
+}
+
+var errGenerateRandomUnsupported = errors.New("KMS client does not support GenerateRandom")
+
+// maxGenerateRandomSize is the maximum number of bytes returned by a single
+// GenerateRandom request.
+const maxGenerateRandomSize = 1024
+
+// RandomReaderOption is an interface for defining options that are passed to
+// [NewRandomReader].
+type RandomReaderOption interface {
+	set(o *randomReaderOptions) error
+}
+
+type randomReaderOptions struct {
+	fetchSize        int
+	customKeyStoreID string
+}
+
+type randomReaderOption func(o *randomReaderOptions) error
+
+func (f randomReaderOption) set(o *randomReaderOptions) error { return f(o) }
+
+// WithFetchSize sets the number of bytes requested from AWS KMS at once, which
+// must be between 1 and 1024. The default is 1024, which minimizes the number
+// of requests. Smaller sizes leave less unused random data in memory.
+func WithFetchSize(size int) RandomReaderOption {
+	return randomReaderOption(func(o *randomReaderOptions) error {
+		if size < 1 || size > maxGenerateRandomSize {
+			return fmt.Errorf("fetch size must be between 1 and %d, but got %d", maxGenerateRandomSize, size)
+		}
+		if o.fetchSize != 0 {
+			return errors.New("WithFetchSize option cannot be used, fetch size already set")
+		}
+		o.fetchSize = size
+		return nil
+	})
+}
+
+// WithCustomKeyStoreID makes AWS KMS generate the random bytes in the AWS
+// CloudHSM cluster of the custom key store with the given ID, such as
+// "cks-1234567890abcdef0". By default, they are generated by the AWS KMS
+// HSMs.
+func WithCustomKeyStoreID(id string) RandomReaderOption {
+	return randomReaderOption(func(o *randomReaderOptions) error {
+		if id == "" {
+			return errors.New("custom key store ID must not be empty")
+		}
+		if o.customKeyStoreID != "" {
+			return errors.New("WithCustomKeyStoreID option cannot be used, custom key store ID already set")
+		}
+		o.customKeyStoreID = id
+		return nil
+	})
+}
+
+// NewRandomReader returns a reader of random bytes generated by AWS KMS
+// GenerateRandom, using the AWS KMS client of client, for secrets which have
+// to come from an HSM. It is safe for concurrent use.
+//
+// The reader fetches the bytes in batches, see [WithFetchSize], and never
+// returns the same bytes twice. Read fills the whole buffer unless AWS KMS
+// fails, in which case it returns the error.
+//
+// ctx is used for all GenerateRandom requests of the reader, so that they can
+// be given a deadline or cancelled. Once ctx is done, Read fails.
+func NewRandomReader(ctx context.Context, client Client, opts ...RandomReaderOption) (io.Reader, error) {
+	c, ok := client.(*awsClient)
+	if !ok {
+		return nil, fmt.Errorf("unsupported client type %T", client)
+	}
+	o := &randomReaderOptions{}
+	for _, opt := range opts {
+		if err := opt.set(o); err != nil {
+			return nil, err
+		}
+	}
+	if o.fetchSize == 0 {
+		o.fetchSize = maxGenerateRandomSize
+	}
+	g, ok := c.kms.(generateRandomAPI)
+	if !ok {
+		return nil, errGenerateRandomUnsupported
+	}
+	return &randomReader{ctx: ctx, kms: g, options: *o}, nil
+}
+
+// randomReader implements [NewRandomReader].
+type randomReader struct {
+	// ctx is the context passed to NewRandomReader, as io.Reader has none.
+	ctx     context.Context
+	kms     generateRandomAPI
+	options randomReaderOptions
+
+	mu sync.Mutex
+	// buf holds the fetched bytes which have not been read yet.
+	buf []byte
+}
+
+func (r *randomReader) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var n int
+	for n < len(p) {
+		if len(r.buf) == 0 {
+			if err := r.fetch(r.ctx); err != nil {
+				return n, err
+			}
+		}
+		m := copy(p[n:], r.buf)
+		// Bytes which have been read are erased, so that they are not kept in
+		// memory.
+		clear(r.buf[:m])
+		r.buf = r.buf[m:]
+		n += m
+	}
+	return n, nil
+}
+
+// fetch fills r.buf with new random bytes.
+func (r *randomReader) fetch(ctx context.Context) error {
+	req := &kms.GenerateRandomInput{
+		NumberOfBytes: aws.Int32(int32(r.options.fetchSize)),
+	}
+	if r.options.customKeyStoreID != "" {
+		req.CustomKeyStoreId = aws.String(r.options.customKeyStoreID)
+	}
+	resp, err := r.kms.GenerateRandom(ctx, req)
+	if err != nil {
+		return err
+	}
+	if len(resp.Plaintext) != r.options.fetchSize {
+		clear(resp.Plaintext)
+		return fmt.Errorf("GenerateRandom returned %d bytes, want %d", len(resp.Plaintext), r.options.fetchSize)
+	}
+	r.buf = resp.Plaintext
+	return nil
+}
@@ -0,0 +1,153 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/tink-crypto/tink-go-awskms/v3/integration/awskms/internal/fakeawskms"
+)
+
+func TestNewRandomReader(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		opts      []RandomReaderOption
+		fetchSize int
+	}{
+		{"default", nil, 1024},
+		{"fetch size", []RandomReaderOption{WithFetchSize(100)}, 100},
+		{"fetch size 1", []RandomReaderOption{WithFetchSize(1)}, 1},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			fakekms, err := fakeawskms.New(nil)
+			if err != nil {
+				t.Fatalf("fakeawskms.New() failed: %v", err)
+			}
+			fakekms.SetRandomSeed(1)
+			metrics := &fakeMetrics{}
+			client := newTestClient(t, WithKMS(fakekms), WithMetrics(metrics))
+			r, err := NewRandomReader(t.Context(), client, test.opts...)
+			if err != nil {
+				t.Fatalf("NewRandomReader() err = %v, want nil", err)
+			}
+			// Reads of various sizes return the sequence of the fake.
+			var got []byte
+			for _, size := range []int{1, 10, 1000, 2000, 0, 37} {
+				b := make([]byte, size)
+				n, err := r.Read(b)
+				if err != nil || n != size {
+					t.Fatalf("r.Read() = %d, %v, want %d, nil", n, err, size)
+				}
+				got = append(got, b...)
+			}
+			want := make([]byte, len(got))
+			fakekms.SetRandomSeed(1)
+			expected, err := NewRandomReader(t.Context(), newTestClient(t, WithKMS(fakekms)), WithFetchSize(test.fetchSize))
+			if err != nil {
+				t.Fatalf("NewRandomReader() err = %v, want nil", err)
+			}
+			if _, err := io.ReadFull(expected, want); err != nil {
+				t.Fatalf("io.ReadFull() err = %v, want nil", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Error("random bytes differ from the sequence of the fake")
+			}
+			wantCalls := (len(got) + test.fetchSize - 1) / test.fetchSize
+			if got := countCalls(metrics, "GenerateRandom"); got != wantCalls {
+				t.Errorf("GenerateRandom calls = %d, want %d", got, wantCalls)
+			}
+		})
+	}
+}
+
+func TestNewRandomReader_customKeyStore(t *testing.T) {
+	fakekms, err := fakeawskms.New(nil)
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	const id = "cks-1234567890abcdef0"
+	client := newTestClient(t, WithKMS(fakekms))
+	r, err := NewRandomReader(t.Context(), client, WithCustomKeyStoreID(id))
+	if err != nil {
+		t.Fatalf("NewRandomReader() err = %v, want nil", err)
+	}
+	var notFound *types.CustomKeyStoreNotFoundException
+	if _, err := r.Read(make([]byte, 16)); !errors.As(err, &notFound) {
+		t.Errorf("r.Read() with unknown custom key store err = %v, want CustomKeyStoreNotFoundException", err)
+	}
+	if err := fakekms.AddCustomKeyStore(id); err != nil {
+		t.Fatalf("fakekms.AddCustomKeyStore() failed: %v", err)
+	}
+	if n, err := r.Read(make([]byte, 16)); err != nil || n != 16 {
+		t.Errorf("r.Read() = %d, %v, want 16, nil", n, err)
+	}
+}
+
+func TestNewRandomReader_canceledContext(t *testing.T) {
+	fakekms, err := fakeawskms.New(nil)
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	client := newTestClient(t, WithKMS(fakekms))
+	ctx, cancel := context.WithCancel(t.Context())
+	r, err := NewRandomReader(ctx, client, WithFetchSize(16))
+	if err != nil {
+		t.Fatalf("NewRandomReader() err = %v, want nil", err)
+	}
+	if n, err := r.Read(make([]byte, 16)); err != nil || n != 16 {
+		t.Fatalf("r.Read() = %d, %v, want 16, nil", n, err)
+	}
+	cancel()
+	if _, err := r.Read(make([]byte, 1)); !errors.Is(err, context.Canceled) {
+		t.Errorf("r.Read() after canceling the context err = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestNewRandomReader_fails(t *testing.T) {
+	fakekms, err := fakeawskms.New(nil)
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	client := newTestClient(t, WithKMS(fakekms))
+	for _, opts := range [][]RandomReaderOption{
+		{WithFetchSize(0)},
+		{WithFetchSize(1025)},
+		{WithFetchSize(10), WithFetchSize(10)},
+		{WithCustomKeyStoreID("")},
+		{WithCustomKeyStoreID("cks-1"), WithCustomKeyStoreID("cks-1")},
+	} {
+		if _, err := NewRandomReader(t.Context(), client, opts...); err == nil {
+			t.Errorf("NewRandomReader() with %d options err = nil, want error", len(opts))
+		}
+	}
+
+	unsupported := newTestClient(t, WithKMS(kmsAPIOnly{fakekms}))
+	if _, err := NewRandomReader(t.Context(), unsupported); !errors.Is(err, errGenerateRandomUnsupported) {
+		t.Errorf("NewRandomReader() err = %v, want %v", err, errGenerateRandomUnsupported)
+	}
+	withMetrics := newTestClient(t, WithKMS(kmsAPIOnly{fakekms}), WithMetrics(&fakeMetrics{}))
+	r, err := NewRandomReader(t.Context(), withMetrics)
+	if err != nil {
+		t.Fatalf("NewRandomReader() err = %v, want nil", err)
+	}
+	if _, err := r.Read(make([]byte, 1)); !errors.Is(err, errGenerateRandomUnsupported) {
+		t.Errorf("r.Read() err = %v, want %v", err, errGenerateRandomUnsupported)
+	}
+}
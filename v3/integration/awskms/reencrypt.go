@@ -0,0 +1,370 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// reEncryptAPI is implemented by KMS clients supporting ReEncrypt, such as
+// *kms.Client. It is not part of [KMSAPI] to keep existing implementations of
+// that interface valid.
+type reEncryptAPI interface {
+	ReEncrypt(ctx context.Context, params *kms.ReEncryptInput, optFns ...func(*kms.Options)) (*kms.ReEncryptOutput, error)
+}
+
+// ErrReEncryptUnsupported is returned by [Client.ReEncrypt] if the AWS KMS
+// client, for example one set with [WithKMS], does not implement ReEncrypt.
+// Such ciphertexts can still be migrated by decrypting and encrypting them.
+var ErrReEncryptUnsupported = errors.New("KMS client does not support ReEncrypt")
+
+// ReEncryptOption is an interface for defining options that are passed to
+// [Client.ReEncrypt].
+type ReEncryptOption interface {
+	set(o *reEncryptOptions) error
+}
+
+type reEncryptOptions struct {
+	sourceKeyURI       string
+	sourceEncoder      EncryptionContextEncoder
+	destinationEncoder EncryptionContextEncoder
+}
+
+type reEncryptOption func(o *reEncryptOptions) error
+
+func (f reEncryptOption) set(o *reEncryptOptions) error { return f(o) }
+
+// WithSourceKeyURI sets the key URI the ciphertext was encrypted with. It must
+// be supported by the client.
+//
+// AWS KMS determines the source key of symmetric ciphertexts by itself, but
+// setting it guarantees that only ciphertexts of this key are accepted. It is
+// also used to determine the source region. The source key reported by AWS KMS
+// is verified against it, see [ErrKeyIDMismatch].
+func WithSourceKeyURI(keyURI string) ReEncryptOption {
+	return reEncryptOption(func(o *reEncryptOptions) error {
+		if o.sourceKeyURI != "" {
+			return errors.New("WithSourceKeyURI option cannot be used, source key URI already set")
+		}
+		o.sourceKeyURI = keyURI
+		return nil
+	})
+}
+
+// WithSourceEncoder sets the encoder used to convert fromAssociatedData into
+// the source encryption context. The default is the encoder of the client,
+// followed by its decrypt fallbacks, see [WithDecryptFallback].
+func WithSourceEncoder(encoder EncryptionContextEncoder) ReEncryptOption {
+	return reEncryptOption(func(o *reEncryptOptions) error {
+		if encoder == nil {
+			return errors.New("source encoder must not be nil")
+		}
+		if o.sourceEncoder != nil {
+			return errors.New("WithSourceEncoder option cannot be used, source encoder already set")
+		}
+		o.sourceEncoder = encoder
+		return nil
+	})
+}
+
+// WithDestinationEncoder sets the encoder used to convert toAssociatedData into
+// the destination encryption context. The default is the encoder of the
+// client.
+//
+// Together with [WithSourceEncoder], this allows migrating ciphertexts from
+// one [EncryptionContextName] to another.
+func WithDestinationEncoder(encoder EncryptionContextEncoder) ReEncryptOption {
+	return reEncryptOption(func(o *reEncryptOptions) error {
+		if encoder == nil {
+			return errors.New("destination encoder must not be nil")
+		}
+		if o.destinationEncoder != nil {
+			return errors.New("WithDestinationEncoder option cannot be used, destination encoder already set")
+		}
+		o.destinationEncoder = encoder
+		return nil
+	})
+}
+
+// WithRegionalKMS sets the AWS KMS client used for keys in region, for example
+// when re-encrypting ciphertexts under a key in another region.
+//
+// Clients created with default credentials or [WithCredentialPath] create
+// regional clients with the same configuration when needed. Clients created
+// with [WithKMS] require this option for every other region they use, unless
+// their key URI prefix names no region, in which case their AWS KMS client is
+// used for regions without a regional client.
+func WithRegionalKMS(region string, kms KMSAPI) ClientOption {
+	return option(func(ctx context.Context, a *awsClient) error {
+		if region == "" {
+			return errors.New("region must not be empty")
+		}
+		if kms == nil {
+			return errors.New("regional KMS client must not be nil")
+		}
+		if _, ok := a.regionalKMS[region]; ok {
+			return fmt.Errorf("WithRegionalKMS option cannot be used, KMS client for region %q already set", region)
+		}
+		if a.regionalKMS == nil {
+			a.regionalKMS = make(map[string]KMSAPI)
+		}
+		a.regionalKMS[region] = kms
+		return nil
+	})
+}
+
+// kmsForRegion returns the AWS KMS client for region.
+func (c *awsClient) kmsForRegion(region string) (KMSAPI, error) {
+	c.regionalMu.Lock()
+	defer c.regionalMu.Unlock()
+	if k, ok := c.regionalKMS[region]; ok {
+		return k, nil
+	}
+	if c.config == nil {
+		return nil, fmt.Errorf("no KMS client for region %q, use WithRegionalKMS", region)
+	}
+	cfg := c.config.Copy()
+	cfg.Region = region
+	k := instrument(kms.NewFromConfig(cfg), c)
+	if c.regionalKMS == nil {
+		c.regionalKMS = make(map[string]KMSAPI)
+	}
+	c.regionalKMS[region] = k
+	return k, nil
+}
+
+// kmsForKeyURI returns the AWS KMS client for the region of keyURI. This is
+// the client's own AWS KMS client if the region of keyURI is unknown or the
+// region of the client, or if the region of the client is unknown and there
+// is no client for the region of keyURI.
+func (c *awsClient) kmsForKeyURI(keyURI string) (KMSAPI, error) {
+	region, err := getRegion(keyURI)
+	if err != nil {
+		return c.kms, nil
+	}
+	clientRegion, err := getRegion(c.keyURIPrefix)
+	if err != nil && c.config != nil {
+		clientRegion = c.config.Region
+	}
+	if region == clientRegion {
+		return c.kms, nil
+	}
+	if clientRegion == "" {
+		c.regionalMu.Lock()
+		_, ok := c.regionalKMS[region]
+		c.regionalMu.Unlock()
+		if !ok {
+			return c.kms, nil
+		}
+	}
+	return c.kmsForRegion(region)
+}
+
+// ReEncrypt implements [Client.ReEncrypt].
+func (c *awsClient) ReEncrypt(ctx context.Context, ciphertext, fromAssociatedData []byte, toKeyURI string, toAssociatedData []byte, opts ...ReEncryptOption) ([]byte, error) {
+	o := &reEncryptOptions{}
+	for _, opt := range opts {
+		if err := opt.set(o); err != nil {
+			return nil, fmt.Errorf("failed setting option: %v", err)
+		}
+	}
+	if !strings.HasPrefix(strings.ToLower(toKeyURI), awsPrefix) {
+		return nil, fmt.Errorf("toKeyURI must start with %q, but got %q", awsPrefix, toKeyURI)
+	}
+	if o.sourceKeyURI != "" && !c.Supported(o.sourceKeyURI) {
+		return nil, fmt.Errorf("source key URI must start with prefix %s, but got %s", c.keyURIPrefix, o.sourceKeyURI)
+	}
+	// The AEADs are only used to verify the key IDs of the response.
+	destination := newAWSAEAD(strings.TrimPrefix(toKeyURI, awsPrefix), c)
+	var source *awsAEAD
+	if o.sourceKeyURI != "" {
+		source = newAWSAEAD(strings.TrimPrefix(o.sourceKeyURI, awsPrefix), c)
+	}
+
+	// The encoder recorded in a metadata header is not authenticated, so it
+	// is ignored like in decryption.
+	if hasCiphertextMetadata(ciphertext) {
+		_, inner, err := parseCiphertextMetadata(ciphertext)
+		if err != nil {
+			return nil, err
+		}
+		ciphertext = inner
+	}
+	// Only the encrypted data key of envelope ciphertexts is re-encrypted. The
+	// payload is authenticated with the associated data, which therefore
+	// cannot change.
+	var envelopePayload []byte
+	if isEnvelope(ciphertext) {
+		if !c.envelopeFallback {
+			return nil, ErrEnvelopeFallbackDisabled
+		}
+		if !bytes.Equal(fromAssociatedData, toAssociatedData) {
+			return nil, errors.New("the associated data of envelope ciphertexts cannot be changed, decrypt and encrypt them instead")
+		}
+		encryptedKey, payload, err := parseEnvelope(ciphertext)
+		if err != nil {
+			return nil, err
+		}
+		ciphertext, envelopePayload = encryptedKey, payload
+	}
+
+	sourceEncoders := []EncryptionContextEncoder{o.sourceEncoder}
+	if o.sourceEncoder == nil {
+		sourceEncoders = []EncryptionContextEncoder{c.encoder}
+		if c.decryptFallbacks != nil && len(fromAssociatedData) > 0 {
+			fallbacks := c.decryptFallbacks
+			if len(fallbacks) == 0 {
+				fallbacks = defaultDecryptFallbacks(c.encoder)
+			}
+			sourceEncoders = append(sourceEncoders, fallbacks...)
+		}
+	}
+	destinationEncoder := o.destinationEncoder
+	if destinationEncoder == nil {
+		destinationEncoder = c.encoder
+	}
+	destinationContext, err := encodeAssociatedData(destinationEncoder, toAssociatedData)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &kms.ReEncryptInput{
+		CiphertextBlob:               ciphertext,
+		DestinationKeyId:             aws.String(destination.keyID),
+		DestinationEncryptionContext: destinationContext,
+		GrantTokens:                  grantTokens(ctx, c.grantTokens),
+	}
+	sourceURI := c.keyURIPrefix
+	if o.sourceKeyURI != "" {
+		input.SourceKeyId = aws.String(source.keyID)
+		sourceURI = o.sourceKeyURI
+	}
+	sourceKMS, err := c.kmsForKeyURI(sourceURI)
+	if err != nil {
+		return nil, err
+	}
+	reEncrypt := func(ctx context.Context, input *kms.ReEncryptInput) (*kms.ReEncryptOutput, error) {
+		return reEncryptWith(ctx, sourceKMS, input)
+	}
+	if regionsDiffer(sourceURI, toKeyURI) {
+		destinationKMS, err := c.kmsForKeyURI(toKeyURI)
+		if err != nil {
+			return nil, err
+		}
+		reEncrypt = func(ctx context.Context, input *kms.ReEncryptInput) (*kms.ReEncryptOutput, error) {
+			return reEncryptAcrossRegions(ctx, sourceKMS, destinationKMS, input)
+		}
+	}
+
+	var firstErr error
+	for _, sourceEncoder := range sourceEncoders {
+		input.SourceEncryptionContext, err = encodeAssociatedData(sourceEncoder, fromAssociatedData)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		resp, err := reEncrypt(ctx, input)
+		if err == nil {
+			if source != nil {
+				if _, err := source.verifyKeyID(ctx, resp.SourceKeyId); err != nil {
+					return nil, err
+				}
+			}
+			keyARN, err := destination.verifyKeyID(ctx, resp.KeyId)
+			if err != nil {
+				return nil, err
+			}
+			if d := operationDetails(ctx); d != nil {
+				d.Encoder = sourceEncoder
+			}
+			newCiphertext := resp.CiphertextBlob
+			if envelopePayload != nil {
+				newCiphertext = formatEnvelope(newCiphertext, envelopePayload)
+			}
+			if c.ciphertextMetadata {
+				return addCiphertextMetadata(keyARN, destinationEncoder.String(), newCiphertext)
+			}
+			return newCiphertext, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		if !isInvalidCiphertext(err) {
+			break
+		}
+	}
+	return nil, firstErr
+}
+
+// reEncryptWith re-encrypts using the AWS KMS ReEncrypt operation of k.
+func reEncryptWith(ctx context.Context, k KMSAPI, input *kms.ReEncryptInput) (*kms.ReEncryptOutput, error) {
+	r, ok := k.(reEncryptAPI)
+	if !ok {
+		return nil, ErrReEncryptUnsupported
+	}
+	return r.ReEncrypt(ctx, input)
+}
+
+// reEncryptAcrossRegions re-encrypts by decrypting with sourceKMS and
+// encrypting with destinationKMS.
+func reEncryptAcrossRegions(ctx context.Context, sourceKMS, destinationKMS KMSAPI, input *kms.ReEncryptInput) (*kms.ReEncryptOutput, error) {
+	decResponse, err := sourceKMS.Decrypt(ctx, &kms.DecryptInput{
+		KeyId:             input.SourceKeyId,
+		CiphertextBlob:    input.CiphertextBlob,
+		EncryptionContext: input.SourceEncryptionContext,
+		GrantTokens:       input.GrantTokens,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer clear(decResponse.Plaintext)
+	encResponse, err := destinationKMS.Encrypt(ctx, &kms.EncryptInput{
+		KeyId:             input.DestinationKeyId,
+		Plaintext:         decResponse.Plaintext,
+		EncryptionContext: input.DestinationEncryptionContext,
+		GrantTokens:       input.GrantTokens,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &kms.ReEncryptOutput{
+		CiphertextBlob: encResponse.CiphertextBlob,
+		KeyId:          encResponse.KeyId,
+		SourceKeyId:    decResponse.KeyId,
+	}, nil
+}
+
+// regionsDiffer reports whether the regions of the source and destination key
+// URIs are both known and differ.
+func regionsDiffer(sourceURI, destinationURI string) bool {
+	sourceRegion, err := getRegion(sourceURI)
+	if err != nil {
+		return false
+	}
+	destinationRegion, err := getRegion(destinationURI)
+	if err != nil {
+		return false
+	}
+	return sourceRegion != destinationRegion
+}
@@ -0,0 +1,287 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/tink-crypto/tink-go-awskms/v3/integration/awskms/internal/fakeawskms"
+)
+
+func mustEncrypt(t *testing.T, c Client, keyURI string, plaintext, associatedData []byte) []byte {
+	t.Helper()
+	a, err := c.GetAEAD(keyURI)
+	if err != nil {
+		t.Fatalf("c.GetAEAD(%q) err = %v, want nil", keyURI, err)
+	}
+	ciphertext, err := a.Encrypt(plaintext, associatedData)
+	if err != nil {
+		t.Fatalf("a.Encrypt() err = %v, want nil", err)
+	}
+	return ciphertext
+}
+
+func mustDecrypt(t *testing.T, c Client, keyURI string, ciphertext, associatedData []byte) []byte {
+	t.Helper()
+	a, err := c.GetAEAD(keyURI)
+	if err != nil {
+		t.Fatalf("c.GetAEAD(%q) err = %v, want nil", keyURI, err)
+	}
+	plaintext, err := a.Decrypt(ciphertext, associatedData)
+	if err != nil {
+		t.Fatalf("a.Decrypt() err = %v, want nil", err)
+	}
+	return plaintext
+}
+
+func TestReEncrypt(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN, destinationKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	metrics := &fakeMetrics{}
+	client := newTestClient(t, WithKMS(fakekms), WithMetrics(metrics))
+	plaintext := []byte("plaintext")
+	ciphertext := mustEncrypt(t, client, sourceKeyURI, plaintext, []byte("from"))
+	metrics.calls = nil
+
+	for _, opts := range [][]ReEncryptOption{nil, {WithSourceKeyURI(sourceKeyURI)}} {
+		newCiphertext, err := client.ReEncrypt(t.Context(), ciphertext, []byte("from"), destinationKeyURI, []byte("to"), opts...)
+		if err != nil {
+			t.Fatalf("client.ReEncrypt() err = %v, want nil", err)
+		}
+		if got := mustDecrypt(t, client, destinationKeyURI, newCiphertext, []byte("to")); !bytes.Equal(got, plaintext) {
+			t.Errorf("Decrypt() = %q, want %q", got, plaintext)
+		}
+	}
+	// The plaintext never leaves AWS KMS.
+	for _, call := range metrics.calls {
+		if call.Operation == "Encrypt" {
+			t.Errorf("client.ReEncrypt() called %v, want only ReEncrypt and Decrypt by the test", call)
+		}
+	}
+}
+
+func TestReEncrypt_changesEncryptionContextName(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN, destinationKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	legacy := newTestClient(t, WithKMS(fakekms), WithEncryptionContextName(LegacyAdditionalData))
+	client := newTestClient(t, WithKMS(fakekms))
+	plaintext := []byte("plaintext")
+	associatedData := []byte("associatedData")
+	ciphertext := mustEncrypt(t, legacy, sourceKeyURI, plaintext, associatedData)
+
+	newCiphertext, err := client.ReEncrypt(t.Context(), ciphertext, associatedData, destinationKeyURI, associatedData, WithSourceEncoder(LegacyAdditionalData))
+	if err != nil {
+		t.Fatalf("client.ReEncrypt() err = %v, want nil", err)
+	}
+	if got := mustDecrypt(t, client, destinationKeyURI, newCiphertext, associatedData); !bytes.Equal(got, plaintext) {
+		t.Errorf("Decrypt() = %q, want %q", got, plaintext)
+	}
+
+	// Without WithSourceEncoder, the client's encoder does not match.
+	if _, err := client.ReEncrypt(t.Context(), ciphertext, associatedData, destinationKeyURI, associatedData); !isInvalidCiphertext(err) {
+		t.Errorf("client.ReEncrypt() err = %v, want InvalidCiphertextException", err)
+	}
+
+	// The client's decrypt fallbacks apply.
+	migrating := newTestClient(t, WithKMS(fakekms), WithEncryptionContextMigration())
+	details := &OperationDetails{}
+	newCiphertext, err = migrating.ReEncrypt(ContextWithOperationDetails(t.Context(), details), ciphertext, associatedData, destinationKeyURI, associatedData)
+	if err != nil {
+		t.Fatalf("migrating.ReEncrypt() err = %v, want nil", err)
+	}
+	if details.Encoder != LegacyAdditionalData {
+		t.Errorf("details.Encoder = %v, want %v", details.Encoder, LegacyAdditionalData)
+	}
+	if got := mustDecrypt(t, client, destinationKeyURI, newCiphertext, associatedData); !bytes.Equal(got, plaintext) {
+		t.Errorf("Decrypt() = %q, want %q", got, plaintext)
+	}
+}
+
+func TestReEncrypt_crossRegion(t *testing.T) {
+	eastKMS, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	westKMS, err := fakeawskms.New([]string{westKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	// The region of the client is known from its prefix.
+	c, err := NewClientWithOptions(t.Context(), "aws-kms://arn:aws:kms:us-east-2:", WithKMS(eastKMS), WithRegionalKMS("eu-west-1", westKMS))
+	if err != nil {
+		t.Fatalf("NewClientWithOptions() failed: %v", err)
+	}
+	client := c.(Client)
+	plaintext := []byte("plaintext")
+	ciphertext := mustEncrypt(t, client, sourceKeyURI, plaintext, []byte("from"))
+
+	newCiphertext, err := client.ReEncrypt(t.Context(), ciphertext, []byte("from"), westKeyURI, []byte("to"))
+	if err != nil {
+		t.Fatalf("client.ReEncrypt() err = %v, want nil", err)
+	}
+	west := newTestClient(t, WithKMS(westKMS))
+	if got := mustDecrypt(t, west, westKeyURI, newCiphertext, []byte("to")); !bytes.Equal(got, plaintext) {
+		t.Errorf("Decrypt() = %q, want %q", got, plaintext)
+	}
+
+	withoutRegional, err := NewClientWithOptions(t.Context(), "aws-kms://arn:aws:kms:us-east-2:", WithKMS(eastKMS))
+	if err != nil {
+		t.Fatalf("NewClientWithOptions() failed: %v", err)
+	}
+	if _, err := withoutRegional.(Client).ReEncrypt(t.Context(), ciphertext, []byte("from"), westKeyURI, []byte("to")); err == nil {
+		t.Error("withoutRegional.ReEncrypt() err = nil, want error")
+	}
+}
+
+func TestReEncrypt_sourceKeyInOtherRegion(t *testing.T) {
+	eastKMS, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	westKMS, err := fakeawskms.New([]string{westKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	west := newTestClient(t, WithKMS(westKMS))
+	plaintext := []byte("plaintext")
+	ciphertext := mustEncrypt(t, west, westKeyURI, plaintext, []byte("from"))
+
+	// The client's AWS KMS client is in us-east-2, but its prefix does not
+	// name a region.
+	client := newTestClient(t, WithKMS(eastKMS), WithRegionalKMS("eu-west-1", westKMS))
+	newCiphertext, err := client.ReEncrypt(t.Context(), ciphertext, []byte("from"), sourceKeyURI, []byte("to"), WithSourceKeyURI(westKeyURI))
+	if err != nil {
+		t.Fatalf("client.ReEncrypt() err = %v, want nil", err)
+	}
+	if got := mustDecrypt(t, client, sourceKeyURI, newCiphertext, []byte("to")); !bytes.Equal(got, plaintext) {
+		t.Errorf("Decrypt() = %q, want %q", got, plaintext)
+	}
+}
+
+func TestReEncrypt_fails(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN, destinationKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	client := newTestClient(t, WithKMS(fakekms))
+	ciphertext := mustEncrypt(t, client, sourceKeyURI, []byte("plaintext"), []byte("from"))
+
+	tests := []struct {
+		name     string
+		fromAD   []byte
+		toKeyURI string
+		opts     []ReEncryptOption
+	}{
+		{"wrong associated data", []byte("other"), destinationKeyURI, nil},
+		{"unknown destination", []byte("from"), "aws-kms://arn:aws:kms:us-east-2:235739564943:key/unknown", nil},
+		{"invalid destination", []byte("from"), destinationKeyARN, nil},
+		{"wrong source key", []byte("from"), destinationKeyURI, []ReEncryptOption{WithSourceKeyURI(destinationKeyURI)}},
+		{"unsupported source key", []byte("from"), destinationKeyURI, []ReEncryptOption{WithSourceKeyURI("gcp-kms://key")}},
+		{"repeated option", []byte("from"), destinationKeyURI, []ReEncryptOption{WithDestinationEncoder(AssociatedData), WithDestinationEncoder(AssociatedData)}},
+		{"nil encoder", []byte("from"), destinationKeyURI, []ReEncryptOption{WithSourceEncoder(nil)}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := client.ReEncrypt(t.Context(), ciphertext, test.fromAD, test.toKeyURI, []byte("to"), test.opts...); err == nil {
+				t.Error("client.ReEncrypt() err = nil, want error")
+			}
+		})
+	}
+}
+
+func TestReEncrypt_envelope(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN, destinationKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	metrics := &fakeMetrics{}
+	client := newTestClient(t, WithKMS(fakekms), WithEnvelopeFallback(), WithMetrics(metrics))
+	plaintext := make([]byte, 5000)
+	associatedData := []byte("associatedData")
+	ciphertext := mustEncrypt(t, client, sourceKeyURI, plaintext, associatedData)
+	metrics.calls = nil
+
+	newCiphertext, err := client.ReEncrypt(t.Context(), ciphertext, associatedData, destinationKeyURI, associatedData)
+	if err != nil {
+		t.Fatalf("client.ReEncrypt() err = %v, want nil", err)
+	}
+	if !isEnvelope(newCiphertext) {
+		t.Error("isEnvelope(newCiphertext) = false, want true")
+	}
+	for _, call := range metrics.calls {
+		if call.Operation != "ReEncrypt" {
+			t.Errorf("client.ReEncrypt() called %v, want only ReEncrypt", call)
+		}
+	}
+	if got := mustDecrypt(t, client, destinationKeyURI, newCiphertext, associatedData); !bytes.Equal(got, plaintext) {
+		t.Errorf("Decrypt() = %d bytes, want %d bytes", len(got), len(plaintext))
+	}
+
+	// The payload is authenticated with the associated data.
+	if _, err := client.ReEncrypt(t.Context(), ciphertext, associatedData, destinationKeyURI, []byte("other")); err == nil {
+		t.Error("client.ReEncrypt() with other destination associated data err = nil, want error")
+	}
+	if _, err := client.ReEncrypt(t.Context(), ciphertext[:len(envelopeHeader)+2], associatedData, destinationKeyURI, associatedData); err == nil {
+		t.Error("client.ReEncrypt() of truncated envelope err = nil, want error")
+	}
+	withoutFallback := newTestClient(t, WithKMS(fakekms))
+	if _, err := withoutFallback.ReEncrypt(t.Context(), ciphertext, associatedData, destinationKeyURI, associatedData); !errors.Is(err, ErrEnvelopeFallbackDisabled) {
+		t.Errorf("withoutFallback.ReEncrypt() err = %v, want %v", err, ErrEnvelopeFallbackDisabled)
+	}
+}
+
+// kmsAPIOnly hides the methods of the wrapped client which are not in KMSAPI.
+type kmsAPIOnly struct {
+	KMSAPI
+}
+
+func TestReEncrypt_unsupportedKMSFails(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN, destinationKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	for _, opts := range [][]ClientOption{
+		{WithKMS(kmsAPIOnly{fakekms})},
+		{WithKMS(kmsAPIOnly{fakekms}), WithMetrics(&fakeMetrics{})},
+	} {
+		client := newTestClient(t, opts...)
+		ciphertext := mustEncrypt(t, client, sourceKeyURI, []byte("plaintext"), nil)
+		if _, err := client.ReEncrypt(t.Context(), ciphertext, nil, destinationKeyURI, nil); !errors.Is(err, ErrReEncryptUnsupported) {
+			t.Errorf("client.ReEncrypt() err = %v, want %v", err, ErrReEncryptUnsupported)
+		}
+	}
+}
+
+func TestWithRegionalKMS_fails(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{westKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	for _, opts := range [][]ClientOption{
+		{WithRegionalKMS("eu-west-1", fakekms), WithRegionalKMS("eu-west-1", fakekms)},
+		{WithRegionalKMS("", fakekms)},
+		{WithRegionalKMS("eu-west-1", nil)},
+	} {
+		if _, err := NewClientWithOptions(t.Context(), "aws-kms://", opts...); err == nil {
+			t.Error("NewClientWithOptions() err = nil, want error")
+		}
+	}
+}
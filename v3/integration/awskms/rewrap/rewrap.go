@@ -0,0 +1,604 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rewrap migrates stored ciphertexts of the AEAD primitives of
+// [awskms] to another AWS KMS key or encryption context encoder, for example
+// after rotating to a new key or when switching from
+// [awskms.LegacyAdditionalData] to [awskms.AssociatedData].
+//
+//	job, err := rewrap.New(client, destinationKeyURI,
+//		rewrap.WithConcurrency(16),
+//		rewrap.WithRateLimit(500),
+//		rewrap.WithCheckpoint(store, 1000))
+//	if err != nil { ... }
+//	report, err := job.Run(ctx, source, sink)
+//
+// Ciphertexts are re-encrypted with [awskms.Client.ReEncrypt], so that their
+// plaintexts never leave AWS KMS. For the envelope ciphertexts of
+// [awskms.WithEnvelopeFallback], only the encrypted data key is re-encrypted,
+// which requires the client to use [awskms.WithEnvelopeFallback]. If the AWS
+// KMS client does not support ReEncrypt, ciphertexts are decrypted and
+// encrypted again, see [WithFallbackAEADs].
+package rewrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tink-crypto/tink-go-awskms/v3/integration/awskms"
+	"github.com/tink-crypto/tink-go/v2/tink"
+)
+
+const awsPrefix = "aws-kms://"
+
+// defaultInterval is the default number of completed items between
+// checkpoints and progress reports.
+const defaultInterval = 1000
+
+// Item is a stored ciphertext.
+type Item struct {
+	// ID identifies the item, for example the primary key of its row. It is
+	// only used in reports.
+	ID string
+	// Cursor is the position of the item in its [Source], see [Source.Seek].
+	// It is required with [WithCheckpoint].
+	Cursor string
+	// Ciphertext is the ciphertext to rewrap.
+	Ciphertext []byte
+	// AssociatedData is the associated data the ciphertext was encrypted with.
+	// The rewrapped ciphertext has the same associated data.
+	AssociatedData []byte
+}
+
+// Source is an iterator over the items to rewrap. Its methods are called from
+// a single goroutine.
+type Source interface {
+	// Next returns the next item, or io.EOF if there are no more items.
+	Next(ctx context.Context) (*Item, error)
+	// Seek positions the source after the item with the given cursor, so that
+	// Next returns the item following it. It is called once, before Next, when
+	// resuming from a checkpoint.
+	Seek(ctx context.Context, cursor string) error
+}
+
+// Sink stores rewrapped ciphertexts.
+type Sink interface {
+	// Write replaces the ciphertext of item with ciphertext. It is called
+	// concurrently if [WithConcurrency] is greater than 1.
+	Write(ctx context.Context, item *Item, ciphertext []byte) error
+}
+
+// CheckpointStore persists the progress of a [Job], see [WithCheckpoint].
+type CheckpointStore interface {
+	// Load returns the last saved cursor, or "" if there is none.
+	Load(ctx context.Context) (string, error)
+	// Save saves cursor, after which the job resumes.
+	Save(ctx context.Context, cursor string) error
+}
+
+// Progress describes the progress of [Job.Run].
+type Progress struct {
+	// Read is the number of items read from the source.
+	Read int
+	// Rewrapped is the number of items written to the sink.
+	Rewrapped int
+	// Fallbacks is the number of rewrapped items which were decrypted and
+	// encrypted again instead of being re-encrypted by AWS KMS.
+	Fallbacks int
+	// Failed is the number of items which could not be rewrapped.
+	Failed int
+	// Cursor is the cursor of the last item such that all items up to it have
+	// been rewrapped or have failed, or the cursor the job resumed from. It is
+	// what is saved in checkpoints.
+	Cursor string
+}
+
+// Failure describes an item which could not be rewrapped.
+type Failure struct {
+	// ID is the ID of the item.
+	ID string
+	// Cursor is the cursor of the item.
+	Cursor string
+	// Err is the reason of the failure.
+	Err error
+}
+
+// Report is the result of [Job.Run].
+type Report struct {
+	Progress
+	// Failures lists the items which could not be rewrapped, in the order in
+	// which they failed.
+	Failures []Failure
+}
+
+// Option is an interface for defining options that are passed to [New].
+type Option interface {
+	set(o *options) error
+}
+
+type options struct {
+	sourceKeyURI       string
+	sourceEncoder      awskms.EncryptionContextEncoder
+	destinationEncoder awskms.EncryptionContextEncoder
+	sourceAEAD         tink.AEAD
+	destinationAEAD    tink.AEAD
+	concurrency        int
+	rateLimit          float64
+	checkpoint         CheckpointStore
+	interval           int
+	progress           func(Progress)
+}
+
+type option func(o *options) error
+
+func (f option) set(o *options) error { return f(o) }
+
+// WithSourceKeyURI sets the key URI the ciphertexts were encrypted with. It is
+// passed to [awskms.Client.ReEncrypt], so that ciphertexts of other keys fail,
+// and used to decrypt the ciphertexts which cannot be re-encrypted, unless
+// [WithFallbackAEADs] is used.
+func WithSourceKeyURI(keyURI string) Option {
+	return option(func(o *options) error {
+		if !strings.HasPrefix(strings.ToLower(keyURI), awsPrefix) {
+			return fmt.Errorf("source key URI must start with %q, but got %q", awsPrefix, keyURI)
+		}
+		if o.sourceKeyURI != "" {
+			return errors.New("WithSourceKeyURI option cannot be used, source key URI already set")
+		}
+		o.sourceKeyURI = keyURI
+		return nil
+	})
+}
+
+// WithSourceEncoder sets the encoder the ciphertexts were encrypted with, see
+// [awskms.WithSourceEncoder]. Ciphertexts which cannot be re-encrypted then
+// require [WithFallbackAEADs].
+func WithSourceEncoder(encoder awskms.EncryptionContextEncoder) Option {
+	return option(func(o *options) error {
+		if encoder == nil {
+			return errors.New("source encoder must not be nil")
+		}
+		if o.sourceEncoder != nil {
+			return errors.New("WithSourceEncoder option cannot be used, source encoder already set")
+		}
+		o.sourceEncoder = encoder
+		return nil
+	})
+}
+
+// WithDestinationEncoder sets the encoder of the rewrapped ciphertexts, see
+// [awskms.WithDestinationEncoder]. Ciphertexts which cannot be re-encrypted
+// then require [WithFallbackAEADs].
+func WithDestinationEncoder(encoder awskms.EncryptionContextEncoder) Option {
+	return option(func(o *options) error {
+		if encoder == nil {
+			return errors.New("destination encoder must not be nil")
+		}
+		if o.destinationEncoder != nil {
+			return errors.New("WithDestinationEncoder option cannot be used, destination encoder already set")
+		}
+		o.destinationEncoder = encoder
+		return nil
+	})
+}
+
+// WithFallbackAEADs sets the AEADs used to decrypt and encrypt the ciphertexts
+// which cannot be re-encrypted by AWS KMS. By default, they are the AEADs of
+// the client for the source key URI, see [WithSourceKeyURI], and for the
+// destination key URI, which use the encoder of the client. Use this option
+// to rewrap such ciphertexts with [WithSourceEncoder] or
+// [WithDestinationEncoder], typically with AEADs of clients created with the
+// corresponding [awskms.WithEncryptionContextEncoder].
+func WithFallbackAEADs(source, destination tink.AEAD) Option {
+	return option(func(o *options) error {
+		if source == nil || destination == nil {
+			return errors.New("fallback AEADs must not be nil")
+		}
+		if o.sourceAEAD != nil {
+			return errors.New("WithFallbackAEADs option cannot be used, fallback AEADs already set")
+		}
+		o.sourceAEAD = source
+		o.destinationAEAD = destination
+		return nil
+	})
+}
+
+// WithConcurrency sets the maximum number of items rewrapped at the same time,
+// which must be at least 1. The default is 1.
+func WithConcurrency(n int) Option {
+	return option(func(o *options) error {
+		if n < 1 {
+			return fmt.Errorf("concurrency must be at least 1, but got %d", n)
+		}
+		if o.concurrency != 0 {
+			return errors.New("WithConcurrency option cannot be used, concurrency already set")
+		}
+		o.concurrency = n
+		return nil
+	})
+}
+
+// WithRateLimit sets the maximum number of items rewrapped per second, for
+// example to stay within the AWS KMS request quotas shared with other
+// applications. By default, the rate is not limited.
+func WithRateLimit(itemsPerSecond float64) Option {
+	return option(func(o *options) error {
+		if !(itemsPerSecond > 0) {
+			return fmt.Errorf("rate limit must be positive, but got %v", itemsPerSecond)
+		}
+		if o.rateLimit != 0 {
+			return errors.New("WithRateLimit option cannot be used, rate limit already set")
+		}
+		o.rateLimit = itemsPerSecond
+		return nil
+	})
+}
+
+// WithCheckpoint makes [Job.Run] save its progress to store every interval
+// completed items, and when it returns. Run resumes after the saved cursor,
+// so a job which was interrupted can be run again with the same store. The
+// default interval, also used for progress reports, is 1000.
+//
+// Items may complete out of order with [WithConcurrency], so the saved cursor
+// is that of the last item such that all items up to it have completed. After
+// a resumption, items which completed after that cursor are rewrapped again,
+// which is harmless. Failed items count as completed: they are reported in
+// [Report.Failures] and are not retried by later runs.
+func WithCheckpoint(store CheckpointStore, interval int) Option {
+	return option(func(o *options) error {
+		if store == nil {
+			return errors.New("checkpoint store must not be nil")
+		}
+		if interval < 1 {
+			return fmt.Errorf("checkpoint interval must be at least 1, but got %d", interval)
+		}
+		if o.checkpoint != nil {
+			return errors.New("WithCheckpoint option cannot be used, checkpoint store already set")
+		}
+		o.checkpoint = store
+		o.interval = interval
+		return nil
+	})
+}
+
+// WithProgress makes [Job.Run] call report with its progress at the interval
+// of [WithCheckpoint], after saving the checkpoint, and when it returns.
+// report is not called concurrently and should return quickly, as it blocks
+// the job.
+func WithProgress(report func(Progress)) Option {
+	return option(func(o *options) error {
+		if report == nil {
+			return errors.New("progress function must not be nil")
+		}
+		if o.progress != nil {
+			return errors.New("WithProgress option cannot be used, progress function already set")
+		}
+		o.progress = report
+		return nil
+	})
+}
+
+// Job rewraps ciphertexts under a destination key. A Job can be run several
+// times, but not concurrently with the same [CheckpointStore].
+type Job struct {
+	client            awskms.Client
+	destinationKeyURI string
+	options           options
+	reEncryptOptions  []awskms.ReEncryptOption
+	limiter           *limiter
+}
+
+// New returns a job rewrapping ciphertexts under destinationKeyURI with
+// client, which must support the source keys.
+func New(client awskms.Client, destinationKeyURI string, opts ...Option) (*Job, error) {
+	if client == nil {
+		return nil, errors.New("client must not be nil")
+	}
+	if !strings.HasPrefix(strings.ToLower(destinationKeyURI), awsPrefix) {
+		return nil, fmt.Errorf("destinationKeyURI must start with %q, but got %q", awsPrefix, destinationKeyURI)
+	}
+	j := &Job{client: client, destinationKeyURI: destinationKeyURI}
+	for _, opt := range opts {
+		if err := opt.set(&j.options); err != nil {
+			return nil, fmt.Errorf("failed setting option: %v", err)
+		}
+	}
+	o := &j.options
+	if o.concurrency == 0 {
+		o.concurrency = 1
+	}
+	if o.interval == 0 {
+		o.interval = defaultInterval
+	}
+	if o.rateLimit != 0 {
+		j.limiter = &limiter{interval: time.Duration(float64(time.Second) / o.rateLimit)}
+	}
+	if o.sourceKeyURI != "" {
+		j.reEncryptOptions = append(j.reEncryptOptions, awskms.WithSourceKeyURI(o.sourceKeyURI))
+	}
+	if o.sourceEncoder != nil {
+		j.reEncryptOptions = append(j.reEncryptOptions, awskms.WithSourceEncoder(o.sourceEncoder))
+	}
+	if o.destinationEncoder != nil {
+		j.reEncryptOptions = append(j.reEncryptOptions, awskms.WithDestinationEncoder(o.destinationEncoder))
+	}
+	// The AEADs of the client use its encoder, so they are only a valid
+	// fallback if the encoders are not changed.
+	if o.sourceAEAD == nil && o.sourceKeyURI != "" && o.sourceEncoder == nil && o.destinationEncoder == nil {
+		var err error
+		if o.sourceAEAD, err = client.GetAEAD(o.sourceKeyURI); err != nil {
+			return nil, err
+		}
+		if o.destinationAEAD, err = client.GetAEAD(destinationKeyURI); err != nil {
+			return nil, err
+		}
+	}
+	return j, nil
+}
+
+// errNoFallback is returned for ciphertexts which cannot be re-encrypted if
+// there are no fallback AEADs.
+var errNoFallback = errors.New("ciphertext cannot be re-encrypted by AWS KMS, and decrypting it requires WithSourceKeyURI or WithFallbackAEADs")
+
+// Run rewraps the items of source and writes them to sink, resuming from the
+// checkpoint of [WithCheckpoint] if there is one.
+//
+// Items which cannot be rewrapped, including those which the sink fails to
+// write, are reported in [Report.Failures] and do not stop the job. Run
+// returns an error if source, or the checkpoint store, fails or if ctx is
+// done. The returned report is never nil and describes the progress made,
+// which is saved in the checkpoint. Items which were interrupted are neither
+// rewrapped nor failed.
+func (j *Job) Run(ctx context.Context, source Source, sink Sink) (*Report, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	r := &run{
+		job:       j,
+		sink:      sink,
+		cancel:    cancel,
+		completed: make(map[int]string),
+	}
+	if j.options.checkpoint != nil {
+		cursor, err := j.options.checkpoint.Load(ctx)
+		if err != nil {
+			return &Report{}, fmt.Errorf("loading checkpoint: %w", err)
+		}
+		if cursor != "" {
+			if err := source.Seek(ctx, cursor); err != nil {
+				return &Report{}, fmt.Errorf("seeking source to checkpoint %q: %w", cursor, err)
+			}
+			r.report.Cursor = cursor
+			r.saved = cursor
+		}
+	}
+
+	items := make(chan sequencedItem)
+	var wg sync.WaitGroup
+	for range j.options.concurrency {
+		wg.Go(func() {
+			for item := range items {
+				r.process(ctx, item)
+			}
+		})
+	}
+	err := r.read(ctx, source, items)
+	close(items)
+	wg.Wait()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.saveErr == nil {
+		// The progress made before an error is saved, so that it is not lost.
+		r.checkpoint(context.WithoutCancel(ctx))
+	}
+	if err == nil {
+		err = r.saveErr
+	}
+	report := r.report
+	return &report, err
+}
+
+// sequencedItem is an item with its position in the run.
+type sequencedItem struct {
+	seq  int
+	item *Item
+}
+
+// run holds the state of [Job.Run].
+type run struct {
+	job    *Job
+	sink   Sink
+	cancel context.CancelCauseFunc
+
+	mu     sync.Mutex
+	report Report
+	// next is the sequence number of the first item which has not completed.
+	next int
+	// completed holds the cursors of the completed items after next.
+	completed map[int]string
+	// pending is the number of items completed since the last checkpoint.
+	pending int
+	// saved is the last saved cursor.
+	saved   string
+	saveErr error
+}
+
+// read sends the items of source to items until source is exhausted or ctx is
+// done.
+func (r *run) read(ctx context.Context, source Source, items chan<- sequencedItem) error {
+	for seq := 0; ; seq++ {
+		item, err := source.Next(ctx)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return context.Cause(ctx)
+			}
+			return fmt.Errorf("reading source: %w", err)
+		}
+		if r.job.options.checkpoint != nil && item.Cursor == "" {
+			return fmt.Errorf("item %q has no cursor, which is required with WithCheckpoint", item.ID)
+		}
+		r.mu.Lock()
+		r.report.Read++
+		r.mu.Unlock()
+		select {
+		case items <- sequencedItem{seq: seq, item: item}:
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		}
+	}
+}
+
+// process rewraps a single item and records its result.
+func (r *run) process(ctx context.Context, s sequencedItem) {
+	fallback, err := r.job.rewrap(ctx, s.item, r.sink)
+	if err != nil && ctx.Err() != nil {
+		// The item was interrupted. It is not completed, so that the
+		// checkpoint stays before it and it is rewrapped when resuming.
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.report.Failed++
+		r.report.Failures = append(r.report.Failures, Failure{ID: s.item.ID, Cursor: s.item.Cursor, Err: err})
+	} else {
+		r.report.Rewrapped++
+		if fallback {
+			r.report.Fallbacks++
+		}
+	}
+	r.completed[s.seq] = s.item.Cursor
+	for {
+		cursor, ok := r.completed[r.next]
+		if !ok {
+			break
+		}
+		delete(r.completed, r.next)
+		r.next++
+		r.report.Cursor = cursor
+		r.pending++
+	}
+	if r.pending >= r.job.options.interval && r.saveErr == nil {
+		r.checkpoint(ctx)
+	}
+}
+
+// checkpoint saves the cursor and reports the progress. r.mu must be held.
+func (r *run) checkpoint(ctx context.Context) {
+	r.pending = 0
+	if store := r.job.options.checkpoint; store != nil && r.report.Cursor != r.saved {
+		if err := store.Save(ctx, r.report.Cursor); err != nil {
+			r.saveErr = fmt.Errorf("saving checkpoint: %w", err)
+			r.cancel(r.saveErr)
+			return
+		}
+		r.saved = r.report.Cursor
+	}
+	if r.job.options.progress != nil {
+		r.job.options.progress(r.report.Progress)
+	}
+}
+
+// rewrap rewraps item and writes it to sink. It reports whether the
+// ciphertext was decrypted and encrypted again.
+func (j *Job) rewrap(ctx context.Context, item *Item, sink Sink) (bool, error) {
+	if err := j.limiter.wait(ctx); err != nil {
+		return false, err
+	}
+	ciphertext, fallback, err := j.rewrapCiphertext(ctx, item)
+	if err != nil {
+		return false, err
+	}
+	if err := sink.Write(ctx, item, ciphertext); err != nil {
+		return false, fmt.Errorf("writing rewrapped ciphertext: %w", err)
+	}
+	return fallback, nil
+}
+
+func (j *Job) rewrapCiphertext(ctx context.Context, item *Item) ([]byte, bool, error) {
+	ciphertext, err := j.client.ReEncrypt(ctx, item.Ciphertext, item.AssociatedData, j.destinationKeyURI, item.AssociatedData, j.reEncryptOptions...)
+	if !errors.Is(err, awskms.ErrReEncryptUnsupported) {
+		return ciphertext, false, err
+	}
+	if j.options.sourceAEAD == nil {
+		return nil, false, errNoFallback
+	}
+	plaintext, err := decrypt(ctx, j.options.sourceAEAD, item.Ciphertext, item.AssociatedData)
+	if err != nil {
+		return nil, false, err
+	}
+	defer clear(plaintext)
+	ciphertext, err = encrypt(ctx, j.options.destinationAEAD, plaintext, item.AssociatedData)
+	return ciphertext, true, err
+}
+
+func decrypt(ctx context.Context, a tink.AEAD, ciphertext, associatedData []byte) ([]byte, error) {
+	if c, ok := a.(tink.AEADWithContext); ok {
+		return c.DecryptWithContext(ctx, ciphertext, associatedData)
+	}
+	return a.Decrypt(ciphertext, associatedData)
+}
+
+func encrypt(ctx context.Context, a tink.AEAD, plaintext, associatedData []byte) ([]byte, error) {
+	if c, ok := a.(tink.AEADWithContext); ok {
+		return c.EncryptWithContext(ctx, plaintext, associatedData)
+	}
+	return a.Encrypt(plaintext, associatedData)
+}
+
+// limiter spaces events at least interval apart.
+type limiter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// wait blocks until the next event is allowed or ctx is done. It does not
+// block if l is nil.
+func (l *limiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	d := at.Sub(now)
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,614 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rewrap_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tink-crypto/tink-go-awskms/v3/integration/awskms"
+	"github.com/tink-crypto/tink-go-awskms/v3/integration/awskms/internal/fakeawskms"
+	"github.com/tink-crypto/tink-go-awskms/v3/integration/awskms/rewrap"
+	"github.com/tink-crypto/tink-go/v2/tink"
+)
+
+const (
+	sourceKeyARN      = "arn:aws:kms:us-east-2:235739564943:key/3ee50705-5a82-4f5b-9753-05c4f473922f"
+	sourceKeyURI      = "aws-kms://" + sourceKeyARN
+	destinationKeyARN = "arn:aws:kms:us-east-2:235739564943:key/b3ca2efd-a8fb-47f2-b541-7e20f8c5cd11"
+	destinationKeyURI = "aws-kms://" + destinationKeyARN
+)
+
+// kmsAPIOnly hides the methods of a KMS client which are not part of
+// awskms.KMSAPI, such as ReEncrypt.
+type kmsAPIOnly struct {
+	awskms.KMSAPI
+}
+
+func newFake(t *testing.T) *fakeawskms.FakeAWSKMS {
+	t.Helper()
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN, destinationKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	return fakekms
+}
+
+func newClient(t *testing.T, opts ...awskms.ClientOption) awskms.Client {
+	t.Helper()
+	c, err := awskms.NewClientWithOptions(t.Context(), "aws-kms://", opts...)
+	if err != nil {
+		t.Fatalf("awskms.NewClientWithOptions() failed: %v", err)
+	}
+	return c.(awskms.Client)
+}
+
+func getAEAD(t *testing.T, client awskms.Client, keyURI string) tink.AEAD {
+	t.Helper()
+	a, err := client.GetAEAD(keyURI)
+	if err != nil {
+		t.Fatalf("client.GetAEAD(%q) failed: %v", keyURI, err)
+	}
+	return a
+}
+
+func newJob(t *testing.T, client awskms.Client, opts ...rewrap.Option) *rewrap.Job {
+	t.Helper()
+	job, err := rewrap.New(client, destinationKeyURI, opts...)
+	if err != nil {
+		t.Fatalf("rewrap.New() failed: %v", err)
+	}
+	return job
+}
+
+func plaintext(i int) []byte { return []byte("plaintext " + strconv.Itoa(i)) }
+
+func associatedData(i int) []byte { return []byte("row " + strconv.Itoa(i)) }
+
+// newItems returns n items encrypted with a, whose cursors are their indexes.
+func newItems(t *testing.T, a tink.AEAD, n int, plaintextSize int) []*rewrap.Item {
+	t.Helper()
+	items := make([]*rewrap.Item, n)
+	for i := range items {
+		pt := plaintext(i)
+		if plaintextSize > len(pt) {
+			pt = append(pt, make([]byte, plaintextSize-len(pt))...)
+		}
+		ciphertext, err := a.Encrypt(pt, associatedData(i))
+		if err != nil {
+			t.Fatalf("a.Encrypt() failed: %v", err)
+		}
+		items[i] = &rewrap.Item{
+			ID:             fmt.Sprintf("item-%d", i),
+			Cursor:         strconv.Itoa(i),
+			Ciphertext:     ciphertext,
+			AssociatedData: associatedData(i),
+		}
+	}
+	return items
+}
+
+// sliceSource is a rewrap.Source over a slice of items.
+type sliceSource struct {
+	items []*rewrap.Item
+	pos   int
+	err   error
+}
+
+func (s *sliceSource) Next(ctx context.Context) (*rewrap.Item, error) {
+	if s.pos == len(s.items) {
+		if s.err != nil {
+			return nil, s.err
+		}
+		return nil, io.EOF
+	}
+	s.pos++
+	return s.items[s.pos-1], nil
+}
+
+func (s *sliceSource) Seek(ctx context.Context, cursor string) error {
+	for i, item := range s.items {
+		if item.Cursor == cursor {
+			s.pos = i + 1
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown cursor %q", cursor)
+}
+
+// mapSink is a rewrap.Sink storing ciphertexts by item ID.
+type mapSink struct {
+	mu          sync.Mutex
+	ciphertexts map[string][]byte
+	// fail makes writes of these item IDs fail.
+	fail map[string]bool
+	// onWrite is called after each successful write with the number of
+	// written items.
+	onWrite func(n int)
+	// delay is the duration of each write.
+	delay             time.Duration
+	active, maxActive int
+}
+
+func (s *mapSink) Write(ctx context.Context, item *rewrap.Item, ciphertext []byte) error {
+	s.mu.Lock()
+	s.active++
+	s.maxActive = max(s.maxActive, s.active)
+	s.mu.Unlock()
+	time.Sleep(s.delay)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active--
+	if s.fail[item.ID] {
+		return errors.New("write failed")
+	}
+	if s.ciphertexts == nil {
+		s.ciphertexts = make(map[string][]byte)
+	}
+	s.ciphertexts[item.ID] = ciphertext
+	if s.onWrite != nil {
+		s.onWrite(len(s.ciphertexts))
+	}
+	return nil
+}
+
+// memoryCheckpoint is a rewrap.CheckpointStore in memory.
+type memoryCheckpoint struct {
+	mu      sync.Mutex
+	cursor  string
+	saves   []string
+	saveErr error
+}
+
+func (c *memoryCheckpoint) Load(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cursor, nil
+}
+
+func (c *memoryCheckpoint) Save(ctx context.Context, cursor string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.saveErr != nil {
+		return c.saveErr
+	}
+	c.cursor = cursor
+	c.saves = append(c.saves, cursor)
+	return nil
+}
+
+// checkRewrapped checks that sink holds the items with indexes in [from, to),
+// decryptable by a.
+func checkRewrapped(t *testing.T, sink *mapSink, a tink.AEAD, from, to int) {
+	t.Helper()
+	for i := from; i < to; i++ {
+		id := fmt.Sprintf("item-%d", i)
+		ciphertext, ok := sink.ciphertexts[id]
+		if !ok {
+			t.Errorf("sink has no ciphertext for %s", id)
+			continue
+		}
+		got, err := a.Decrypt(ciphertext, associatedData(i))
+		if err != nil {
+			t.Errorf("a.Decrypt(%s) err = %v, want nil", id, err)
+			continue
+		}
+		if !bytes.HasPrefix(got, plaintext(i)) {
+			t.Errorf("a.Decrypt(%s) = %q, want prefix %q", id, got, plaintext(i))
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	fakekms := newFake(t)
+	client := newClient(t, awskms.WithKMS(fakekms))
+	items := newItems(t, getAEAD(t, client, sourceKeyURI), 100, 0)
+	checkpoint := &memoryCheckpoint{}
+	sink := &mapSink{}
+	job := newJob(t, client, rewrap.WithSourceKeyURI(sourceKeyURI), rewrap.WithConcurrency(8), rewrap.WithCheckpoint(checkpoint, 30))
+
+	report, err := job.Run(t.Context(), &sliceSource{items: items}, sink)
+	if err != nil {
+		t.Fatalf("job.Run() err = %v, want nil", err)
+	}
+	want := rewrap.Progress{Read: 100, Rewrapped: 100, Cursor: "99"}
+	if report.Progress != want || len(report.Failures) != 0 {
+		t.Errorf("job.Run() = %+v, want %+v without failures", report, want)
+	}
+	checkRewrapped(t, sink, getAEAD(t, client, destinationKeyURI), 0, 100)
+	// The source key can no longer decrypt the rewrapped ciphertexts.
+	if _, err := getAEAD(t, client, sourceKeyURI).Decrypt(sink.ciphertexts["item-0"], associatedData(0)); err == nil {
+		t.Error("Decrypt() with the source key err = nil, want error")
+	}
+	if checkpoint.cursor != "99" {
+		t.Errorf("checkpoint cursor = %q, want %q", checkpoint.cursor, "99")
+	}
+	// Items complete out of order, so the number of saves varies.
+	if len(checkpoint.saves) < 2 {
+		t.Errorf("checkpoint saves = %q, want periodic saves", checkpoint.saves)
+	}
+}
+
+func TestRun_changesEncryptionContextName(t *testing.T) {
+	fakekms := newFake(t)
+	legacyClient := newClient(t, awskms.WithKMS(fakekms), awskms.WithEncryptionContextName(awskms.LegacyAdditionalData))
+	client := newClient(t, awskms.WithKMS(fakekms), awskms.WithEncryptionContextName(awskms.AssociatedData))
+	items := newItems(t, getAEAD(t, legacyClient, sourceKeyURI), 10, 0)
+	sink := &mapSink{}
+	job := newJob(t, client, rewrap.WithSourceEncoder(awskms.LegacyAdditionalData), rewrap.WithDestinationEncoder(awskms.AssociatedData))
+
+	report, err := job.Run(t.Context(), &sliceSource{items: items}, sink)
+	if err != nil {
+		t.Fatalf("job.Run() err = %v, want nil", err)
+	}
+	if report.Rewrapped != 10 || report.Failed != 0 {
+		t.Errorf("job.Run() = %+v, want 10 rewrapped items", report)
+	}
+	checkRewrapped(t, sink, getAEAD(t, client, destinationKeyURI), 0, 10)
+}
+
+func TestRun_decryptsAndEncryptsWithoutReEncrypt(t *testing.T) {
+	fakekms := newFake(t)
+	client := newClient(t, awskms.WithKMS(kmsAPIOnly{fakekms}))
+	items := newItems(t, getAEAD(t, client, sourceKeyURI), 10, 0)
+	sink := &mapSink{}
+	job := newJob(t, client, rewrap.WithSourceKeyURI(sourceKeyURI), rewrap.WithConcurrency(4))
+
+	report, err := job.Run(t.Context(), &sliceSource{items: items}, sink)
+	if err != nil {
+		t.Fatalf("job.Run() err = %v, want nil", err)
+	}
+	want := rewrap.Progress{Read: 10, Rewrapped: 10, Fallbacks: 10, Cursor: "9"}
+	if report.Progress != want {
+		t.Errorf("job.Run() = %+v, want %+v", report.Progress, want)
+	}
+	checkRewrapped(t, sink, getAEAD(t, client, destinationKeyURI), 0, 10)
+}
+
+func TestRun_reEncryptsEnvelopeCiphertexts(t *testing.T) {
+	fakekms := newFake(t)
+	client := newClient(t, awskms.WithKMS(fakekms), awskms.WithEnvelopeFallback())
+	small := newItems(t, getAEAD(t, client, sourceKeyURI), 3, 0)
+	large := newItems(t, getAEAD(t, client, sourceKeyURI), 3, 5000)
+	for i, item := range large {
+		item.ID = fmt.Sprintf("item-%d", i+3)
+		item.Cursor = strconv.Itoa(i + 3)
+		item.AssociatedData = associatedData(i)
+	}
+	sink := &mapSink{}
+	// The job has no fallback AEADs, so all items must be re-encrypted.
+	job := newJob(t, client)
+
+	report, err := job.Run(t.Context(), &sliceSource{items: append(small, large...)}, sink)
+	if err != nil {
+		t.Fatalf("job.Run() err = %v, want nil", err)
+	}
+	want := rewrap.Progress{Read: 6, Rewrapped: 6, Cursor: "5"}
+	if report.Progress != want {
+		t.Errorf("job.Run() = %+v, want %+v", report.Progress, want)
+	}
+	destination := getAEAD(t, client, destinationKeyURI)
+	checkRewrapped(t, sink, destination, 0, 3)
+	for i := range large {
+		id := fmt.Sprintf("item-%d", i+3)
+		info, err := awskms.InspectCiphertext(sink.ciphertexts[id])
+		if err != nil || !info.Envelope {
+			t.Errorf("InspectCiphertext(%s) = %+v, %v, want envelope ciphertext", id, info, err)
+		}
+		if _, err := destination.Decrypt(sink.ciphertexts[id], associatedData(i)); err != nil {
+			t.Errorf("destination.Decrypt(%s) err = %v, want nil", id, err)
+		}
+		if _, err := getAEAD(t, client, sourceKeyURI).Decrypt(sink.ciphertexts[id], associatedData(i)); err == nil {
+			t.Errorf("Decrypt(%s) with the source key err = nil, want error", id)
+		}
+	}
+}
+
+func TestRun_envelopeCiphertextsRequireEnvelopeFallback(t *testing.T) {
+	fakekms := newFake(t)
+	envelopeClient := newClient(t, awskms.WithKMS(fakekms), awskms.WithEnvelopeFallback())
+	items := newItems(t, getAEAD(t, envelopeClient, sourceKeyURI), 3, 5000)
+	client := newClient(t, awskms.WithKMS(fakekms))
+	job := newJob(t, client)
+
+	report, err := job.Run(t.Context(), &sliceSource{items: items}, &mapSink{})
+	if err != nil {
+		t.Fatalf("job.Run() err = %v, want nil", err)
+	}
+	if report.Rewrapped != 0 || len(report.Failures) != 3 {
+		t.Fatalf("job.Run() = %+v, want 3 failures", report)
+	}
+	for _, f := range report.Failures {
+		if !errors.Is(f.Err, awskms.ErrEnvelopeFallbackDisabled) {
+			t.Errorf("failure of %s err = %v, want %v", f.ID, f.Err, awskms.ErrEnvelopeFallbackDisabled)
+		}
+	}
+}
+
+func TestRun_fallbackAEADs(t *testing.T) {
+	fakekms := newFake(t)
+	legacyClient := newClient(t, awskms.WithKMS(kmsAPIOnly{fakekms}), awskms.WithEncryptionContextName(awskms.LegacyAdditionalData))
+	client := newClient(t, awskms.WithKMS(kmsAPIOnly{fakekms}), awskms.WithEncryptionContextName(awskms.AssociatedData))
+	items := newItems(t, getAEAD(t, legacyClient, sourceKeyURI), 5, 0)
+	sink := &mapSink{}
+	job := newJob(t, client,
+		rewrap.WithSourceEncoder(awskms.LegacyAdditionalData),
+		rewrap.WithDestinationEncoder(awskms.AssociatedData),
+		rewrap.WithFallbackAEADs(getAEAD(t, legacyClient, sourceKeyURI), getAEAD(t, client, destinationKeyURI)))
+
+	report, err := job.Run(t.Context(), &sliceSource{items: items}, sink)
+	if err != nil {
+		t.Fatalf("job.Run() err = %v, want nil", err)
+	}
+	if report.Rewrapped != 5 || report.Fallbacks != 5 {
+		t.Errorf("job.Run() = %+v, want 5 rewrapped items with fallbacks", report)
+	}
+	checkRewrapped(t, sink, getAEAD(t, client, destinationKeyURI), 0, 5)
+}
+
+func TestRun_withoutFallbackReportsFailures(t *testing.T) {
+	fakekms := newFake(t)
+	client := newClient(t, awskms.WithKMS(kmsAPIOnly{fakekms}))
+	items := newItems(t, getAEAD(t, client, sourceKeyURI), 3, 0)
+	// The AEADs of the client cannot be used, as they use its encoder.
+	job := newJob(t, client, rewrap.WithSourceKeyURI(sourceKeyURI), rewrap.WithSourceEncoder(awskms.LegacyAdditionalData))
+
+	report, err := job.Run(t.Context(), &sliceSource{items: items}, &mapSink{})
+	if err != nil {
+		t.Fatalf("job.Run() err = %v, want nil", err)
+	}
+	if report.Rewrapped != 0 || report.Failed != 3 || len(report.Failures) != 3 {
+		t.Errorf("job.Run() = %+v, want 3 failures", report)
+	}
+}
+
+func TestRun_reportsFailures(t *testing.T) {
+	fakekms := newFake(t)
+	client := newClient(t, awskms.WithKMS(fakekms))
+	items := newItems(t, getAEAD(t, client, sourceKeyURI), 20, 0)
+	items[3].AssociatedData = []byte("wrong associated data")
+	items[7].Ciphertext = []byte("invalid ciphertext")
+	sink := &mapSink{fail: map[string]bool{"item-12": true}}
+	checkpoint := &memoryCheckpoint{}
+	job := newJob(t, client, rewrap.WithConcurrency(3), rewrap.WithCheckpoint(checkpoint, 5))
+
+	report, err := job.Run(t.Context(), &sliceSource{items: items}, sink)
+	if err != nil {
+		t.Fatalf("job.Run() err = %v, want nil", err)
+	}
+	want := rewrap.Progress{Read: 20, Rewrapped: 17, Failed: 3, Cursor: "19"}
+	if report.Progress != want {
+		t.Errorf("job.Run() = %+v, want %+v", report.Progress, want)
+	}
+	failed := make(map[string]bool)
+	for _, f := range report.Failures {
+		if f.Err == nil {
+			t.Errorf("failure %q has no error", f.ID)
+		}
+		failed[f.ID] = true
+	}
+	for _, id := range []string{"item-3", "item-7", "item-12"} {
+		if !failed[id] {
+			t.Errorf("report.Failures = %v, want failure of %s", report.Failures, id)
+		}
+	}
+	// Failed items do not stop the checkpoint.
+	if checkpoint.cursor != "19" {
+		t.Errorf("checkpoint cursor = %q, want %q", checkpoint.cursor, "19")
+	}
+}
+
+func TestRun_resumesFromCheckpoint(t *testing.T) {
+	fakekms := newFake(t)
+	client := newClient(t, awskms.WithKMS(fakekms))
+	items := newItems(t, getAEAD(t, client, sourceKeyURI), 20, 0)
+	sink := &mapSink{}
+	checkpoint := &memoryCheckpoint{cursor: "11"}
+	job := newJob(t, client, rewrap.WithCheckpoint(checkpoint, 100))
+
+	report, err := job.Run(t.Context(), &sliceSource{items: items}, sink)
+	if err != nil {
+		t.Fatalf("job.Run() err = %v, want nil", err)
+	}
+	want := rewrap.Progress{Read: 8, Rewrapped: 8, Cursor: "19"}
+	if report.Progress != want {
+		t.Errorf("job.Run() = %+v, want %+v", report.Progress, want)
+	}
+	if len(sink.ciphertexts) != 8 {
+		t.Errorf("len(sink.ciphertexts) = %d, want 8", len(sink.ciphertexts))
+	}
+	checkRewrapped(t, sink, getAEAD(t, client, destinationKeyURI), 12, 20)
+}
+
+func TestRun_interruptedJobResumes(t *testing.T) {
+	fakekms := newFake(t)
+	client := newClient(t, awskms.WithKMS(fakekms))
+	items := newItems(t, getAEAD(t, client, sourceKeyURI), 50, 0)
+	checkpoint := &memoryCheckpoint{}
+	job := newJob(t, client, rewrap.WithConcurrency(4), rewrap.WithCheckpoint(checkpoint, 5))
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	sink := &mapSink{onWrite: func(n int) {
+		if n == 23 {
+			cancel()
+		}
+	}}
+	report, err := job.Run(ctx, &sliceSource{items: items}, sink)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("job.Run() err = %v, want %v", err, context.Canceled)
+	}
+	if report.Failed != 0 {
+		t.Errorf("job.Run() = %+v, want no failures", report)
+	}
+	if report.Cursor != checkpoint.cursor {
+		t.Errorf("report.Cursor = %q, want saved cursor %q", report.Cursor, checkpoint.cursor)
+	}
+	resumeAfter := -1
+	if checkpoint.cursor != "" {
+		resumeAfter, _ = strconv.Atoi(checkpoint.cursor)
+	}
+	// All items up to the checkpoint have been rewrapped.
+	checkRewrapped(t, sink, getAEAD(t, client, destinationKeyURI), 0, resumeAfter+1)
+
+	report, err = job.Run(t.Context(), &sliceSource{items: items}, sink)
+	if err != nil {
+		t.Fatalf("job.Run() err = %v, want nil", err)
+	}
+	if report.Read != 50-(resumeAfter+1) || report.Cursor != "49" {
+		t.Errorf("job.Run() = %+v, want %d read items up to cursor 49", report, 50-(resumeAfter+1))
+	}
+	checkRewrapped(t, sink, getAEAD(t, client, destinationKeyURI), 0, 50)
+}
+
+func TestRun_boundsConcurrency(t *testing.T) {
+	fakekms := newFake(t)
+	client := newClient(t, awskms.WithKMS(fakekms))
+	items := newItems(t, getAEAD(t, client, sourceKeyURI), 20, 0)
+	for _, concurrency := range []int{1, 4} {
+		sink := &mapSink{delay: 5 * time.Millisecond}
+		job := newJob(t, client, rewrap.WithConcurrency(concurrency))
+		if _, err := job.Run(t.Context(), &sliceSource{items: items}, sink); err != nil {
+			t.Fatalf("job.Run() err = %v, want nil", err)
+		}
+		if sink.maxActive > concurrency {
+			t.Errorf("concurrent writes = %d, want at most %d", sink.maxActive, concurrency)
+		}
+	}
+}
+
+func TestRun_rateLimit(t *testing.T) {
+	fakekms := newFake(t)
+	client := newClient(t, awskms.WithKMS(fakekms))
+	items := newItems(t, getAEAD(t, client, sourceKeyURI), 11, 0)
+	job := newJob(t, client, rewrap.WithConcurrency(4), rewrap.WithRateLimit(200))
+
+	start := time.Now()
+	if _, err := job.Run(t.Context(), &sliceSource{items: items}, &mapSink{}); err != nil {
+		t.Fatalf("job.Run() err = %v, want nil", err)
+	}
+	// 11 items at 200 items per second are spaced over at least 50ms.
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("job.Run() took %v, want at least 50ms", elapsed)
+	}
+}
+
+func TestRun_reportsProgress(t *testing.T) {
+	fakekms := newFake(t)
+	client := newClient(t, awskms.WithKMS(fakekms))
+	items := newItems(t, getAEAD(t, client, sourceKeyURI), 25, 0)
+	items[0].AssociatedData = nil
+	var reports []rewrap.Progress
+	job := newJob(t, client, rewrap.WithCheckpoint(&memoryCheckpoint{}, 10), rewrap.WithProgress(func(p rewrap.Progress) {
+		reports = append(reports, p)
+	}))
+
+	if _, err := job.Run(t.Context(), &sliceSource{items: items}, &mapSink{}); err != nil {
+		t.Fatalf("job.Run() err = %v, want nil", err)
+	}
+	want := []rewrap.Progress{
+		{Read: 10, Rewrapped: 9, Failed: 1, Cursor: "9"},
+		{Read: 20, Rewrapped: 19, Failed: 1, Cursor: "19"},
+		{Read: 25, Rewrapped: 24, Failed: 1, Cursor: "24"},
+	}
+	if len(reports) != len(want) {
+		t.Fatalf("progress reports = %+v, want %+v", reports, want)
+	}
+	for i := range want {
+		// Reading is ahead of rewrapping, so only the completed items are
+		// deterministic.
+		reports[i].Read = want[i].Read
+		if reports[i] != want[i] {
+			t.Errorf("progress report %d = %+v, want %+v", i, reports[i], want[i])
+		}
+	}
+}
+
+func TestRun_fails(t *testing.T) {
+	fakekms := newFake(t)
+	client := newClient(t, awskms.WithKMS(fakekms))
+	items := newItems(t, getAEAD(t, client, sourceKeyURI), 5, 0)
+	noCursor := newItems(t, getAEAD(t, client, sourceKeyURI), 1, 0)
+	noCursor[0].Cursor = ""
+
+	for _, test := range []struct {
+		name       string
+		source     *sliceSource
+		checkpoint *memoryCheckpoint
+	}{
+		{"source error", &sliceSource{items: items, err: errors.New("source failed")}, &memoryCheckpoint{}},
+		{"unknown checkpoint cursor", &sliceSource{items: items}, &memoryCheckpoint{cursor: "unknown"}},
+		{"checkpoint save error", &sliceSource{items: items}, &memoryCheckpoint{saveErr: errors.New("save failed")}},
+		{"missing cursor", &sliceSource{items: noCursor}, &memoryCheckpoint{}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			job := newJob(t, client, rewrap.WithCheckpoint(test.checkpoint, 2))
+			report, err := job.Run(t.Context(), test.source, &mapSink{})
+			if err == nil {
+				t.Error("job.Run() err = nil, want error")
+			}
+			if report == nil {
+				t.Error("job.Run() report = nil, want non-nil")
+			}
+		})
+	}
+}
+
+func TestNew_fails(t *testing.T) {
+	fakekms := newFake(t)
+	client := newClient(t, awskms.WithKMS(fakekms))
+	a := getAEAD(t, client, sourceKeyURI)
+	store := &memoryCheckpoint{}
+	for _, test := range []struct {
+		name   string
+		client awskms.Client
+		keyURI string
+		opts   []rewrap.Option
+	}{
+		{"nil client", nil, destinationKeyURI, nil},
+		{"invalid destination key URI", client, destinationKeyARN, nil},
+		{"invalid source key URI", client, destinationKeyURI, []rewrap.Option{rewrap.WithSourceKeyURI(sourceKeyARN)}},
+		{"source key URI twice", client, destinationKeyURI, []rewrap.Option{rewrap.WithSourceKeyURI(sourceKeyURI), rewrap.WithSourceKeyURI(sourceKeyURI)}},
+		{"nil source encoder", client, destinationKeyURI, []rewrap.Option{rewrap.WithSourceEncoder(nil)}},
+		{"source encoder twice", client, destinationKeyURI, []rewrap.Option{rewrap.WithSourceEncoder(awskms.AssociatedData), rewrap.WithSourceEncoder(awskms.AssociatedData)}},
+		{"nil destination encoder", client, destinationKeyURI, []rewrap.Option{rewrap.WithDestinationEncoder(nil)}},
+		{"destination encoder twice", client, destinationKeyURI, []rewrap.Option{rewrap.WithDestinationEncoder(awskms.AssociatedData), rewrap.WithDestinationEncoder(awskms.AssociatedData)}},
+		{"nil fallback AEAD", client, destinationKeyURI, []rewrap.Option{rewrap.WithFallbackAEADs(a, nil)}},
+		{"fallback AEADs twice", client, destinationKeyURI, []rewrap.Option{rewrap.WithFallbackAEADs(a, a), rewrap.WithFallbackAEADs(a, a)}},
+		{"zero concurrency", client, destinationKeyURI, []rewrap.Option{rewrap.WithConcurrency(0)}},
+		{"concurrency twice", client, destinationKeyURI, []rewrap.Option{rewrap.WithConcurrency(2), rewrap.WithConcurrency(2)}},
+		{"zero rate limit", client, destinationKeyURI, []rewrap.Option{rewrap.WithRateLimit(0)}},
+		{"rate limit twice", client, destinationKeyURI, []rewrap.Option{rewrap.WithRateLimit(1), rewrap.WithRateLimit(1)}},
+		{"nil checkpoint store", client, destinationKeyURI, []rewrap.Option{rewrap.WithCheckpoint(nil, 1)}},
+		{"zero checkpoint interval", client, destinationKeyURI, []rewrap.Option{rewrap.WithCheckpoint(store, 0)}},
+		{"checkpoint twice", client, destinationKeyURI, []rewrap.Option{rewrap.WithCheckpoint(store, 1), rewrap.WithCheckpoint(store, 1)}},
+		{"nil progress", client, destinationKeyURI, []rewrap.Option{rewrap.WithProgress(nil)}},
+		{"progress twice", client, destinationKeyURI, []rewrap.Option{rewrap.WithProgress(func(rewrap.Progress) {}), rewrap.WithProgress(func(rewrap.Progress) {})}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := rewrap.New(test.client, test.keyURI, test.opts...); err == nil {
+				t.Error("rewrap.New() err = nil, want error")
+			}
+		})
+	}
+}
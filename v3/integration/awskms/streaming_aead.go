@@ -0,0 +1,224 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/tink-crypto/tink-go/v2/streamingaead/subtle"
+	"github.com/tink-crypto/tink-go/v2/tink"
+)
+
+// generateDataKeyAPI is implemented by KMS clients supporting GenerateDataKey,
+// such as *kms.Client.
+type generateDataKeyAPI interface {
+	GenerateDataKey(ctx context.Context, params *kms.GenerateDataKeyInput, optFns ...func(*kms.Options)) (*kms.GenerateDataKeyOutput, error)
+}
+
+var errGenerateDataKeyUnsupported = errors.New("KMS client does not support GenerateDataKey")
+
+// streamingHeader starts the ciphertexts of [NewStreamingAEAD]. The last byte
+// is the format version.
+var streamingHeader = []byte{'T', 'K', 'S', 'A', 1}
+
+const (
+	// streamingKeySize is the size of the data keys and of the AES-GCM keys
+	// derived from them.
+	streamingKeySize = 32
+	// streamingSegmentSize is the ciphertext segment size, like in
+	// streamingaead.AES256GCMHKDF1MBKeyTemplate.
+	streamingSegmentSize = 1 << 20
+	// maxEncryptedDataKeySize is the maximum size of AWS KMS ciphertexts.
+	maxEncryptedDataKeySize = 6144
+)
+
+// NewStreamingAEAD returns a tink.StreamingAEAD which protects each stream
+// with a new data key from AWS KMS GenerateDataKey, for data which is too
+// large for AWS KMS or to keep in memory, such as backups.
+//
+// The payload is encrypted with AES-GCM-HKDF with 1 MB segments, keyed with
+// the data key. The stream starts with a header holding the data key
+// encrypted with keyURI:
+//
+//	"TKSA" || 0x01 || len(encrypted key) (4 bytes, big endian) || encrypted key
+//
+// The associated data is used both by the payload encryption and as
+// encryption context of the data key, which NewDecryptingReader decrypts with
+// AWS KMS Decrypt. The client options apply to these requests. With
+// [WithDryRun] or [ContextWithDryRun], creating a writer or reader only
+// performs a dry run of the request and returns [ErrDryRunSucceeded], without
+// writing or reading the stream.
+//
+// The returned primitive implements [StreamingAEADWithContext], whose methods
+// pass a context to the AWS KMS requests, for example to set a deadline or to
+// collect [OperationDetails]. The tink.StreamingAEAD methods use
+// context.Background.
+func NewStreamingAEAD(ctx context.Context, client Client, keyURI string) (tink.StreamingAEAD, error) {
+	c, ok := client.(*awsClient)
+	if !ok {
+		return nil, fmt.Errorf("unsupported client type %T", client)
+	}
+	if !c.Supported(keyURI) {
+		return nil, fmt.Errorf("keyURI must start with prefix %s, but got %s", c.keyURIPrefix, keyURI)
+	}
+	a, err := c.newAEAD(ctx, keyURI, strings.TrimPrefix(keyURI, awsPrefix))
+	if err != nil {
+		return nil, err
+	}
+	return &streamingAEAD{aead: a}, nil
+}
+
+// StreamingAEADWithContext is implemented by the primitives returned by
+// [NewStreamingAEAD]. Its methods work like those of tink.StreamingAEAD, and
+// use ctx for the AWS KMS requests made when a stream is created. Reading and
+// writing the stream does not use AWS KMS.
+type StreamingAEADWithContext interface {
+	tink.StreamingAEAD
+	// NewEncryptingWriterWithContext is like NewEncryptingWriter, and uses ctx
+	// to generate the data key.
+	NewEncryptingWriterWithContext(ctx context.Context, w io.Writer, associatedData []byte) (io.WriteCloser, error)
+	// NewDecryptingReaderWithContext is like NewDecryptingReader, and uses ctx
+	// to decrypt the data key.
+	NewDecryptingReaderWithContext(ctx context.Context, r io.Reader, associatedData []byte) (io.Reader, error)
+}
+
+// streamingAEAD implements [NewStreamingAEAD].
+type streamingAEAD struct {
+	aead *awsAEAD
+}
+
+var _ StreamingAEADWithContext = (*streamingAEAD)(nil)
+
+// NewEncryptingWriter generates a data key, writes the stream header to w and
+// returns a writer encrypting to w.
+func (s *streamingAEAD) NewEncryptingWriter(w io.Writer, associatedData []byte) (io.WriteCloser, error) {
+	return s.NewEncryptingWriterWithContext(context.Background(), w, associatedData)
+}
+
+// NewEncryptingWriterWithContext is like NewEncryptingWriter, and uses ctx to
+// generate the data key.
+func (s *streamingAEAD) NewEncryptingWriterWithContext(ctx context.Context, w io.Writer, associatedData []byte) (io.WriteCloser, error) {
+	dataKey, encryptedKey, err := s.generateDataKey(ctx, associatedData)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(dataKey)
+	primitive, err := newStreamingPrimitive(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, 0, len(streamingHeader)+4+len(encryptedKey))
+	header = append(header, streamingHeader...)
+	header = binary.BigEndian.AppendUint32(header, uint32(len(encryptedKey)))
+	header = append(header, encryptedKey...)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return primitive.NewEncryptingWriter(w, associatedData)
+}
+
+// NewDecryptingReader reads the stream header from r, decrypts the data key
+// and returns a reader decrypting the rest of r.
+func (s *streamingAEAD) NewDecryptingReader(r io.Reader, associatedData []byte) (io.Reader, error) {
+	return s.NewDecryptingReaderWithContext(context.Background(), r, associatedData)
+}
+
+// NewDecryptingReaderWithContext is like NewDecryptingReader, and uses ctx to
+// decrypt the data key.
+func (s *streamingAEAD) NewDecryptingReaderWithContext(ctx context.Context, r io.Reader, associatedData []byte) (io.Reader, error) {
+	header := make([]byte, len(streamingHeader)+4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("reading stream header failed: %w", err)
+	}
+	if !bytes.Equal(header[:len(streamingHeader)-1], streamingHeader[:len(streamingHeader)-1]) {
+		return nil, errors.New("invalid stream header")
+	}
+	if version := header[len(streamingHeader)-1]; version != streamingHeader[len(streamingHeader)-1] {
+		return nil, fmt.Errorf("unsupported stream format version %d", version)
+	}
+	keySize := binary.BigEndian.Uint32(header[len(streamingHeader):])
+	if keySize == 0 || keySize > maxEncryptedDataKeySize {
+		return nil, fmt.Errorf("invalid encrypted data key size %d", keySize)
+	}
+	encryptedKey := make([]byte, keySize)
+	if _, err := io.ReadFull(r, encryptedKey); err != nil {
+		return nil, fmt.Errorf("reading encrypted data key failed: %w", err)
+	}
+	dataKey, err := s.aead.decryptKMS(ctx, encryptedKey, associatedData)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(dataKey)
+	primitive, err := newStreamingPrimitive(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return primitive.NewDecryptingReader(r, associatedData)
+}
+
+// generateDataKey returns a new data key and its encryption by AWS KMS.
+func (s *streamingAEAD) generateDataKey(ctx context.Context, associatedData []byte) ([]byte, []byte, error) {
+	a := s.aead
+	g, ok := a.kms.(generateDataKeyAPI)
+	if !ok {
+		return nil, nil, errGenerateDataKeyUnsupported
+	}
+	encryptionContext, err := a.encryptionContext(associatedData)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := a.checkEncryptionAlgorithm(nil, encryptionContext); err != nil {
+		return nil, nil, err
+	}
+	dryRun := isDryRun(ctx, a.dryRun)
+	resp, err := g.GenerateDataKey(ctx, &kms.GenerateDataKeyInput{
+		KeyId:             aws.String(a.keyID),
+		KeySpec:           types.DataKeySpecAes256,
+		EncryptionContext: encryptionContext,
+		GrantTokens:       grantTokens(ctx, a.grantTokens),
+		DryRun:            aws.Bool(dryRun),
+	})
+	if err != nil {
+		if dryRun {
+			err = dryRunError(ctx, err, "GenerateDataKey", a.keyID)
+		}
+		return nil, nil, err
+	}
+	if _, err := a.verifyKeyID(ctx, resp.KeyId); err != nil {
+		clear(resp.Plaintext)
+		return nil, nil, err
+	}
+	if len(resp.Plaintext) != streamingKeySize || len(resp.CiphertextBlob) == 0 {
+		clear(resp.Plaintext)
+		return nil, nil, errors.New("invalid GenerateDataKey response")
+	}
+	return resp.Plaintext, resp.CiphertextBlob, nil
+}
+
+func newStreamingPrimitive(dataKey []byte) (*subtle.AESGCMHKDF, error) {
+	if len(dataKey) != streamingKeySize {
+		return nil, fmt.Errorf("invalid data key size %d", len(dataKey))
+	}
+	return subtle.NewAESGCMHKDF(dataKey, "SHA256", streamingKeySize, streamingSegmentSize, 0)
+}
@@ -0,0 +1,312 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awskms
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/tink-crypto/tink-go-awskms/v3/integration/awskms/internal/fakeawskms"
+	"github.com/tink-crypto/tink-go/v2/tink"
+)
+
+func streamEncrypt(t *testing.T, s tink.StreamingAEAD, plaintext, associatedData []byte) []byte {
+	t.Helper()
+	var ciphertext bytes.Buffer
+	w, err := s.NewEncryptingWriter(&ciphertext, associatedData)
+	if err != nil {
+		t.Fatalf("s.NewEncryptingWriter() err = %v, want nil", err)
+	}
+	if _, err := io.Copy(w, bytes.NewReader(plaintext)); err != nil {
+		t.Fatalf("io.Copy() err = %v, want nil", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("w.Close() err = %v, want nil", err)
+	}
+	return ciphertext.Bytes()
+}
+
+func streamDecrypt(s tink.StreamingAEAD, ciphertext, associatedData []byte) ([]byte, error) {
+	r, err := s.NewDecryptingReader(bytes.NewReader(ciphertext), associatedData)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestNewStreamingAEAD(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	metrics := &fakeMetrics{}
+	s, err := NewStreamingAEAD(t.Context(), newTestClient(t, WithKMS(fakekms), WithMetrics(metrics)), sourceKeyURI)
+	if err != nil {
+		t.Fatalf("NewStreamingAEAD() err = %v, want nil", err)
+	}
+	associatedData := []byte("associatedData")
+
+	for _, test := range []struct {
+		name string
+		size int
+	}{
+		{"empty", 0},
+		{"small", 100},
+		{"one segment", streamingSegmentSize},
+		{"large", 5*streamingSegmentSize + 12345},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			plaintext := make([]byte, test.size)
+			if _, err := rand.Read(plaintext); err != nil {
+				t.Fatal(err)
+			}
+			ciphertext := streamEncrypt(t, s, plaintext, associatedData)
+			if !bytes.HasPrefix(ciphertext, streamingHeader) {
+				t.Errorf("ciphertext = %x..., want prefix %x", ciphertext[:len(streamingHeader)], streamingHeader)
+			}
+			got, err := streamDecrypt(s, ciphertext, associatedData)
+			if err != nil {
+				t.Fatalf("streamDecrypt() err = %v, want nil", err)
+			}
+			if !bytes.Equal(got, plaintext) {
+				t.Errorf("streamDecrypt() = %d bytes, want %d bytes", len(got), len(plaintext))
+			}
+			if _, err := streamDecrypt(s, ciphertext, []byte("invalid")); err == nil {
+				t.Error("streamDecrypt() with invalid associated data err = nil, want error")
+			}
+		})
+	}
+
+	var generateDataKeyCalls int
+	for _, call := range metrics.calls {
+		if call.Operation == "GenerateDataKey" {
+			generateDataKeyCalls++
+		}
+	}
+	if generateDataKeyCalls != 4 {
+		t.Errorf("GenerateDataKey calls = %d, want 4", generateDataKeyCalls)
+	}
+}
+
+func TestNewStreamingAEAD_invalidCiphertextFails(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	s, err := NewStreamingAEAD(t.Context(), newTestClient(t, WithKMS(fakekms)), sourceKeyURI)
+	if err != nil {
+		t.Fatalf("NewStreamingAEAD() err = %v, want nil", err)
+	}
+	plaintext := bytes.Repeat([]byte("a"), 2*streamingSegmentSize)
+	ciphertext := streamEncrypt(t, s, plaintext, nil)
+	keySize := binary.BigEndian.Uint32(ciphertext[len(streamingHeader):])
+	headerSize := len(streamingHeader) + 4 + int(keySize)
+
+	withHeader := func(header []byte) []byte {
+		return append(bytes.Clone(header), ciphertext[len(header):]...)
+	}
+	for _, test := range []struct {
+		name       string
+		ciphertext []byte
+	}{
+		{"empty", nil},
+		{"truncated header", ciphertext[:len(streamingHeader)+2]},
+		{"truncated encrypted key", ciphertext[:headerSize-1]},
+		{"truncated payload", ciphertext[:len(ciphertext)-1]},
+		{"without last segment", ciphertext[:headerSize+streamingSegmentSize]},
+		{"invalid magic", withHeader([]byte("XKSA"))},
+		{"unsupported version", withHeader(append([]byte("TKSA"), 2))},
+		{"zero key size", withHeader(append(bytes.Clone(streamingHeader), 0, 0, 0, 0))},
+		{"too large key size", withHeader(binary.BigEndian.AppendUint32(bytes.Clone(streamingHeader), maxEncryptedDataKeySize+1))},
+		{"modified encrypted key", withHeader(append(bytes.Clone(ciphertext[:headerSize-1]), ciphertext[headerSize-1]^1))},
+		{"modified payload", append(bytes.Clone(ciphertext[:len(ciphertext)-1]), ciphertext[len(ciphertext)-1]^1)},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := streamDecrypt(s, test.ciphertext, nil); err == nil {
+				t.Error("streamDecrypt() err = nil, want error")
+			}
+		})
+	}
+}
+
+func TestNewStreamingAEAD_grantTokens(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	token, err := fakekms.AddGrant(sourceKeyARN, types.GrantOperationGenerateDataKey, types.GrantOperationDecrypt)
+	if err != nil {
+		t.Fatalf("fakekms.AddGrant() failed: %v", err)
+	}
+	plaintext := []byte("plaintext")
+
+	withoutToken, err := NewStreamingAEAD(t.Context(), newTestClient(t, WithKMS(fakekms)), sourceKeyURI)
+	if err != nil {
+		t.Fatalf("NewStreamingAEAD() err = %v, want nil", err)
+	}
+	if _, err := withoutToken.NewEncryptingWriter(io.Discard, nil); !isAccessDenied(err) {
+		t.Errorf("s.NewEncryptingWriter() without grant token err = %v, want AccessDeniedException", err)
+	}
+
+	s, err := NewStreamingAEAD(t.Context(), newTestClient(t, WithKMS(fakekms), WithGrantTokens(token)), sourceKeyURI)
+	if err != nil {
+		t.Fatalf("NewStreamingAEAD() err = %v, want nil", err)
+	}
+	ciphertext := streamEncrypt(t, s, plaintext, nil)
+	got, err := streamDecrypt(s, ciphertext, nil)
+	if err != nil {
+		t.Fatalf("streamDecrypt() err = %v, want nil", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("streamDecrypt() = %q, want %q", got, plaintext)
+	}
+}
+
+func TestNewStreamingAEAD_withContext(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	token, err := fakekms.AddGrant(sourceKeyARN, types.GrantOperationGenerateDataKey, types.GrantOperationDecrypt)
+	if err != nil {
+		t.Fatalf("fakekms.AddGrant() failed: %v", err)
+	}
+	client := newTestClient(t, WithKMS(fakekms))
+	streaming, err := NewStreamingAEAD(t.Context(), client, sourceKeyURI)
+	if err != nil {
+		t.Fatalf("NewStreamingAEAD() err = %v, want nil", err)
+	}
+	s := streaming.(StreamingAEADWithContext)
+	plaintext := []byte("plaintext")
+	associatedData := []byte("associatedData")
+
+	// The grant token of the context is only sent by the WithContext methods.
+	if _, err := s.NewEncryptingWriter(io.Discard, associatedData); !isAccessDenied(err) {
+		t.Errorf("s.NewEncryptingWriter() err = %v, want AccessDeniedException", err)
+	}
+	ctx := ContextWithGrantTokens(t.Context(), token)
+	var ciphertext bytes.Buffer
+	w, err := s.NewEncryptingWriterWithContext(ctx, &ciphertext, associatedData)
+	if err != nil {
+		t.Fatalf("s.NewEncryptingWriterWithContext() err = %v, want nil", err)
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		t.Fatalf("w.Write() err = %v, want nil", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("w.Close() err = %v, want nil", err)
+	}
+
+	if _, err := s.NewDecryptingReader(bytes.NewReader(ciphertext.Bytes()), associatedData); !isAccessDenied(err) {
+		t.Errorf("s.NewDecryptingReader() err = %v, want AccessDeniedException", err)
+	}
+	details := &OperationDetails{}
+	r, err := s.NewDecryptingReaderWithContext(ContextWithOperationDetails(ctx, details), bytes.NewReader(ciphertext.Bytes()), associatedData)
+	if err != nil {
+		t.Fatalf("s.NewDecryptingReaderWithContext() err = %v, want nil", err)
+	}
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("io.ReadAll() err = %v, want nil", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("io.ReadAll() = %q, want %q", got, plaintext)
+	}
+	if details.KeyARN != sourceKeyARN {
+		t.Errorf("details.KeyARN = %q, want %q", details.KeyARN, sourceKeyARN)
+	}
+
+	canceled, cancel := context.WithCancel(t.Context())
+	cancel()
+	if _, err := s.NewEncryptingWriterWithContext(canceled, io.Discard, associatedData); !errors.Is(err, context.Canceled) {
+		t.Errorf("s.NewEncryptingWriterWithContext() with canceled context err = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestNewStreamingAEAD_dryRun(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	client := newTestClient(t, WithKMS(fakekms))
+	streaming, err := NewStreamingAEAD(t.Context(), client, sourceKeyURI)
+	if err != nil {
+		t.Fatalf("NewStreamingAEAD() err = %v, want nil", err)
+	}
+	plaintext := []byte("plaintext")
+	ciphertext := streamEncrypt(t, streaming, plaintext, nil)
+
+	dryRun, err := NewStreamingAEAD(t.Context(), newTestClient(t, WithKMS(fakekms), WithDryRun()), sourceKeyURI)
+	if err != nil {
+		t.Fatalf("NewStreamingAEAD() err = %v, want nil", err)
+	}
+	s := dryRun.(StreamingAEADWithContext)
+	var written bytes.Buffer
+	details := &OperationDetails{}
+	if _, err := s.NewEncryptingWriterWithContext(ContextWithOperationDetails(t.Context(), details), &written, nil); !errors.Is(err, ErrDryRunSucceeded) {
+		t.Errorf("s.NewEncryptingWriterWithContext() err = %v, want %v", err, ErrDryRunSucceeded)
+	}
+	if written.Len() != 0 {
+		t.Errorf("s.NewEncryptingWriterWithContext() wrote %d bytes, want 0", written.Len())
+	}
+	if want := (DryRunResult{Operation: "GenerateDataKey", KeyID: sourceKeyARN}); details.DryRun == nil || *details.DryRun != want {
+		t.Errorf("details.DryRun = %+v, want %+v", details.DryRun, want)
+	}
+	if _, err := streamDecrypt(s, ciphertext, nil); !errors.Is(err, ErrDryRunSucceeded) {
+		t.Errorf("streamDecrypt() err = %v, want %v", err, ErrDryRunSucceeded)
+	}
+
+	// ContextWithDryRun applies to a single stream.
+	s = streaming.(StreamingAEADWithContext)
+	if _, err := s.NewDecryptingReaderWithContext(ContextWithDryRun(t.Context()), bytes.NewReader(ciphertext), nil); !errors.Is(err, ErrDryRunSucceeded) {
+		t.Errorf("s.NewDecryptingReaderWithContext() err = %v, want %v", err, ErrDryRunSucceeded)
+	}
+	if got, err := streamDecrypt(s, ciphertext, nil); err != nil || !bytes.Equal(got, plaintext) {
+		t.Errorf("streamDecrypt() = %q, %v, want %q, nil", got, err, plaintext)
+	}
+
+	// Dry runs which would fail return the error.
+	if _, err := fakekms.AddGrant(sourceKeyARN, types.GrantOperationDecrypt); err != nil {
+		t.Fatalf("fakekms.AddGrant() failed: %v", err)
+	}
+	if _, err := s.NewEncryptingWriterWithContext(ContextWithDryRun(t.Context()), io.Discard, nil); !isAccessDenied(err) {
+		t.Errorf("s.NewEncryptingWriterWithContext() without grant err = %v, want AccessDeniedException", err)
+	}
+}
+
+func TestNewStreamingAEAD_fails(t *testing.T) {
+	fakekms, err := fakeawskms.New([]string{sourceKeyARN})
+	if err != nil {
+		t.Fatalf("fakeawskms.New() failed: %v", err)
+	}
+	client := newTestClient(t, WithKMS(fakekms))
+	if _, err := NewStreamingAEAD(t.Context(), client, "gcp-kms://key"); err == nil {
+		t.Error("NewStreamingAEAD() with unsupported key URI err = nil, want error")
+	}
+
+	s, err := NewStreamingAEAD(t.Context(), newTestClient(t, WithKMS(kmsAPIOnly{fakekms})), sourceKeyURI)
+	if err != nil {
+		t.Fatalf("NewStreamingAEAD() err = %v, want nil", err)
+	}
+	if _, err := s.NewEncryptingWriter(io.Discard, nil); !errors.Is(err, errGenerateDataKeyUnsupported) {
+		t.Errorf("s.NewEncryptingWriter() err = %v, want %v", err, errGenerateDataKeyUnsupported)
+	}
+}